@@ -2,11 +2,13 @@ package api
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/controllers"
 	"github.com/nyudlts/go-medialog/database"
 	"github.com/nyudlts/go-medialog/models"
 )
@@ -27,7 +29,7 @@ import (
 func CreateAccessionV0(c *gin.Context) {
 	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -65,9 +67,9 @@ func CreateAccessionV0(c *gin.Context) {
 
 }
 
-// GetAccessionsV0 returns all accessions.
+// GetAccessionsV0 returns the accessions the user can view.
 // @Summary      List accessions
-// @Description  Returns a list of all accessions.
+// @Description  Returns a list of the accessions in repositories the user can view.
 // @Tags         accessions
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -79,7 +81,7 @@ func GetAccessionsV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -89,7 +91,7 @@ func GetAccessionsV0(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, accessions)
+	c.JSON(http.StatusOK, controllers.GetPermissions(c).FilterAccessions(accessions))
 }
 
 // GetAccessionV0 returns an accession by ID.
@@ -108,7 +110,7 @@ func GetAccessionV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
 		return
 	}
 
@@ -136,37 +138,56 @@ func GetAccessionV0(c *gin.Context) {
 
 // DeleteAccessionV0 deletes an accession by ID.
 // @Summary      Delete accession
-// @Description  Deletes an accession by its ID.
+// @Description  Moves an accession to the trash by its ID. The delete is refused with 409 and a summary of the dependents while the accession still has entries, unless an admin sets cascade=true to move them to the trash too.
 // @Tags         accessions
 // @Produce      json
 // @Security     ApiKeyAuth
-// @Param        id   path      int  true  "Accession ID"
+// @Param        id       path   int   true   "Accession ID"
+// @Param        cascade  query  bool  false  "Also delete the accession's entries (admin only)"
 // @Success      200  {string}  string
 // @Failure      400  {string}  string
-// @Failure      401  {string}  string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  DeleteConflict
 // @Failure      500  {string}  string
 // @Router       /accessions/{id} [delete]
 func DeleteAccessionV0(c *gin.Context) {
-	_, err := checkToken(c)
+	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
 	accessionIDParam := c.Param("id")
 	accessionID, err := strconv.Atoi(accessionIDParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		c.JSON(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := database.DeleteAccession(uint(accessionID)); err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+	cascade, err := isCascadeDelete(c, token)
+	if err != nil {
+		c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, fmt.Sprintf("Resource %d deleted", accessionID))
+	if !cascade {
+		if err := database.DeleteAccession(uint(accessionID)); err != nil {
+			deleteError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, fmt.Sprintf("Accession %d deleted", accessionID))
+		return
+	}
 
+	summary, err := database.DeleteAccessionCascade(uint(accessionID))
+	if err != nil {
+		deleteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fmt.Sprintf("Accession %d deleted with %s", accessionID, summary.String()))
 }
 
 // GetAccessionEntriesV0 returns entries for an accession.
@@ -187,7 +208,7 @@ func DeleteAccessionV0(c *gin.Context) {
 func GetAccessionEntriesV0(c *gin.Context) {
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -274,7 +295,7 @@ func GetAccessionSummaryV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -305,3 +326,88 @@ func GetAccessionSummaryV0(c *gin.Context) {
 	c.JSON(http.StatusOK, summaryAccession)
 
 }
+
+// ImportAccessionEntriesV0 validates, and optionally commits, a csv of entries for an accession.
+// @Summary      Import entries from csv
+// @Description  Parses a csv of entries for an accession and returns a per-row validation report. The first row names the columns, which match the entry json fields (mediatype, stock_size_num, stock_unit, box_number, label_text, manufacturer, ...). Nothing is saved unless commit=true, in which case every row is inserted in one transaction with sequential media IDs, or none are if any row is invalid. The csv may be sent as the request body or as a multipart file named "file".
+// @Tags         accessions
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id      path      int   true   "Accession ID"
+// @Param        commit  query     bool  false  "Insert the entries instead of a dry run"
+// @Success      200  {object}  controllers.EntryImportReport
+// @Failure      400  {object}  controllers.EntryImportReport
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {string}  string
+// @Router       /accessions/{id}/import [post]
+func ImportAccessionEntriesV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
+		return
+	}
+
+	accessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accession, err := database.FindAccession(uint(accessionID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var csvBytes []byte
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		defer file.Close()
+		csvBytes, err = io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		csvBytes, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	report, err := controllers.ParseEntryImport(csvBytes, accession, int(userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if c.Query("commit") != "true" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	if report.HasErrors() {
+		c.JSON(http.StatusBadRequest, report)
+		return
+	}
+
+	if err := controllers.CommitEntryImport(&report, accession.ResourceID); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
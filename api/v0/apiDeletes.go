@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+// DeleteConflict is returned when a delete is refused because the object still has children
+type DeleteConflict struct {
+	Error      string                 `json:"error"`
+	Dependents database.DeleteSummary `json:"dependents"`
+}
+
+// isCascadeDelete reports whether the request asked to delete the children too, only admins may cascade
+func isCascadeDelete(c *gin.Context, token string) (bool, error) {
+	if c.Query("cascade") != "true" {
+		return false, nil
+	}
+
+	admin, err := isAdminToken(token)
+	if err != nil {
+		return false, err
+	}
+
+	if !admin {
+		return false, fmt.Errorf("cascading deletes require an admin user")
+	}
+
+	return true, nil
+}
+
+func deleteError(c *gin.Context, err error) {
+	var dependentsError *database.DependentsError
+	if errors.As(err, &dependentsError) {
+		c.JSON(http.StatusConflict, DeleteConflict{Error: err.Error(), Dependents: dependentsError.Summary})
+		return
+	}
+	c.JSON(controllers.DeleteErrorStatus(err), map[string]string{"error": err.Error()})
+}
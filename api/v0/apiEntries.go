@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,7 +18,7 @@ import (
 
 // CreateEntryV0 creates a new media entry.
 // @Summary      Create entry
-// @Description  Creates a new media entry within an accession.
+// @Description  Creates a new media entry within an accession. An entry without a media_id is given the next free one in its resource, a media_id already used in the resource is a conflict.
 // @Tags         entries
 // @Accept       json
 // @Produce      json
@@ -26,12 +27,13 @@ import (
 // @Success      200    {object}  models.Entry
 // @Failure      400    {string}  string
 // @Failure      401    {string}  string
+// @Failure      409    {string}  string
 // @Failure      500    {string}  string
 // @Router       /entries [post]
 func CreateEntryV0(c *gin.Context) {
 	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
 		return
 	}
 
@@ -47,6 +49,11 @@ func CreateEntryV0(c *gin.Context) {
 		return
 	}
 
+	if err := controllers.ValidateTechnicalMetadata(entry); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	entry.CreatedBy = int(userID)
 	entry.UpdatedBy = int(userID)
 	entry.CreatedAt = time.Now()
@@ -74,8 +81,12 @@ func CreateEntryV0(c *gin.Context) {
 	}
 	entry.Repository = repository
 
-	if err = database.InsertEntry(&entry); err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+	insertEntry := database.InsertEntry
+	if entry.MediaID == 0 {
+		insertEntry = database.InsertEntryWithNextMediaID
+	}
+	if err = insertEntry(&entry); err != nil {
+		c.JSON(entryWriteStatus(err), err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, entry)
@@ -83,7 +94,7 @@ func CreateEntryV0(c *gin.Context) {
 
 // DeleteEntryV0 deletes an entry by UUID.
 // @Summary      Delete entry
-// @Description  Deletes a media entry by its UUID.
+// @Description  Moves a media entry to the trash by its UUID, an admin can restore or purge it from the trash.
 // @Tags         entries
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -91,12 +102,13 @@ func CreateEntryV0(c *gin.Context) {
 // @Success      200  {string}  string
 // @Failure      400  {string}  string
 // @Failure      401  {string}  string
+// @Failure      404  {object}  map[string]string
 // @Failure      500  {string}  string
 // @Router       /entries/{id} [delete]
 func DeleteEntryV0(c *gin.Context) {
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
 		return
 	}
 
@@ -108,7 +120,7 @@ func DeleteEntryV0(c *gin.Context) {
 	}
 
 	if err := database.DeleteEntry(entryUUID); err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		deleteError(c, err)
 		return
 	}
 
@@ -129,7 +141,7 @@ func DeleteEntryV0(c *gin.Context) {
 func GetEntryV0(c *gin.Context) {
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
 		return
 	}
 
@@ -150,9 +162,48 @@ func GetEntryV0(c *gin.Context) {
 	c.JSON(http.StatusOK, entry)
 }
 
-// GetEntriesV0 returns all entries.
+// GetEntryHistoryV0 returns the change history of an entry.
+// @Summary      Get entry history
+// @Description  Returns the recorded revisions of an entry, newest first. Each revision lists the changed fields with their previous and new values.
+// @Tags         entries
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Entry UUID"
+// @Success      200  {array}   models.EntryRevision
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/history [get]
+func GetEntryHistoryV0(c *gin.Context) {
+	_, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	uId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := database.FindEntry(uId); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	revisions, err := database.FindEntryRevisions(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetEntriesV0 returns the entries the user can view.
 // @Summary      List entries
-// @Description  Returns paginated entries across all accessions. Use all_ids=true to return only UUIDs.
+// @Description  Returns paginated entries across all accessions in repositories the user can view. Use all_ids=true to return only UUIDs.
 // @Tags         entries
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -167,10 +218,13 @@ func GetEntriesV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
+	//only list entries in repositories the user can view
+	repositoryIDs := controllers.GetPermissions(c).ViewableRepositories()
+
 	allIDsParam := c.Query("all_ids")
 
 	var allIds bool
@@ -184,7 +238,7 @@ func GetEntriesV0(c *gin.Context) {
 	}
 
 	if allIds {
-		ids, err := database.GetEntryIDs()
+		ids, err := database.GetEntryIDs(repositoryIDs)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, err.Error())
 			return
@@ -220,7 +274,7 @@ func GetEntriesV0(c *gin.Context) {
 
 			pagination := database.Pagination{Offset: page, Limit: pageSize}
 			fmt.Println(pagination)
-			entries, err = database.FindEntriesPaginated(pagination)
+			entries, err = database.FindEntriesPaginated(pagination, repositoryIDs)
 			if err != nil {
 				c.JSON(http.StatusBadRequest, err.Error())
 				return
@@ -230,7 +284,7 @@ func GetEntriesV0(c *gin.Context) {
 		}
 
 		results := EntryResultSet{}
-		results.Total = database.GetCountOfEntriesInDB()
+		results.Total = database.GetCountOfEntriesInRepositories(repositoryIDs)
 		r := int(results.Total / int64(pageSize))
 		m := int(results.Total % int64(pageSize))
 		var t int
@@ -263,7 +317,7 @@ func GetEntriesV0(c *gin.Context) {
 func UpdateEntryLocationV0(c *gin.Context) {
 	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
 		return
 	}
 
@@ -287,7 +341,7 @@ func UpdateEntryLocationV0(c *gin.Context) {
 
 	storageLocation := controllers.GetStorageLocation(location)
 
-	if storageLocation == "No Match" {
+	if !controllers.IsActiveTerm(controllers.VocabularyStorageLocations, location) {
 		c.JSON(http.StatusBadRequest, fmt.Sprintf("`%s` is not a valid location", location))
 		return
 	}
@@ -317,9 +371,9 @@ func UpdateEntryLocationV0(c *gin.Context) {
 
 }
 
-// UpdateEntryV0 updates all fields of an entry.
+// UpdateEntryV0 updates the editable fields of an entry.
 // @Summary      Update entry
-// @Description  Replaces all updatable fields of an entry by UUID.
+// @Description  Replaces the editable fields of an entry by UUID. An id in the body must match the one in the path. The media id, repository, resource, accession and creation fields are left unchanged, use the move endpoints to reparent an entry.
 // @Tags         entries
 // @Accept       json
 // @Produce      json
@@ -329,14 +383,20 @@ func UpdateEntryLocationV0(c *gin.Context) {
 // @Success      200  {string}  string
 // @Failure      400  {string}  string
 // @Failure      401  {string}  string
+// @Failure      403  {string}  string
+// @Failure      409  {string}  string
 // @Failure      500  {string}  string
 // @Router       /entries/{id}/update [post]
 func UpdateEntryV0(c *gin.Context) {
-	id := c.Param("id")
-
 	tkn, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -346,9 +406,26 @@ func UpdateEntryV0(c *gin.Context) {
 		return
 	}
 
-	entry := models.Entry{}
-	if err := json.Unmarshal(body, &entry); err != nil {
+	editedEntry := models.Entry{}
+	if err := json.Unmarshal(body, &editedEntry); err != nil {
 		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if editedEntry.ID != uuid.Nil && editedEntry.ID != id {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("entry id %s in the body does not match %s", editedEntry.ID, id))
+		return
+	}
+
+	if err := controllers.ValidateTechnicalMetadata(editedEntry); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entry, err := database.FindEntry(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
 	}
 
 	userID, err := database.FindUserIDByToken(tkn)
@@ -357,13 +434,71 @@ func UpdateEntryV0(c *gin.Context) {
 		return
 	}
 
+	entry.UpdateEntry(editedEntry)
 	entry.UpdatedBy = int(userID)
 	entry.UpdatedAt = time.Now()
 
 	if err := database.UpdateEntry(&entry); err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		c.JSON(entryWriteStatus(err), err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, fmt.Sprintf("entry %s updated", id))
 }
+
+// SearchEntriesV0 searches entries with field-qualified terms, filters and facets.
+// @Summary      Search entries
+// @Description  Searches entries and returns one page of results with facet counts per mediatype and status for the whole result set. The query matches free text anywhere in an entry and accepts field-qualified terms such as label_text:letters, box_number:3 or manufacturer:"Sony"; quote a value to search for a phrase. Dates use YYYY-MM-DD and pages start at 0. Only entries in repositories the user can view are searched.
+// @Tags         entries
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        query          query     string  false  "Search query"
+// @Param        repository_id  query     int     false  "Repository ID"
+// @Param        resource_id    query     int     false  "Resource ID"
+// @Param        accession_id   query     int     false  "Accession ID"
+// @Param        mediatype      query     string  false  "Mediatype key"
+// @Param        status         query     string  false  "Entry status key"
+// @Param        location       query     string  false  "Storage location key"
+// @Param        created_from   query     string  false  "Created on or after"
+// @Param        created_to     query     string  false  "Created on or before"
+// @Param        updated_from   query     string  false  "Updated on or after"
+// @Param        updated_to     query     string  false  "Updated on or before"
+// @Param        sort           query     string  false  "updated_at desc, updated_at, created_at desc, created_at, media_id or media_id desc; defaults to relevance when the query has free text, otherwise updated_at desc"
+// @Param        page           query     int     false  "Page number, starting at 0"
+// @Param        limit          query     int     false  "Results per page (default 10)"
+// @Success      200  {object}  database.EntrySearchResult
+// @Failure      400  {string}  string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {string}  string
+// @Router       /search/entries [get]
+func SearchEntriesV0(c *gin.Context) {
+	_, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
+		return
+	}
+
+	search, pagination, err := controllers.ParseEntrySearch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	search.RepositoryIDs = controllers.GetPermissions(c).ViewableRepositories()
+
+	result, err := database.SearchEntryPage(search, pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// entryWriteStatus maps an error saving an entry to a response code, a media id already used in the resource is a conflict
+func entryWriteStatus(err error) int {
+	var takenError *database.MediaIDTakenError
+	if errors.As(err, &takenError) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
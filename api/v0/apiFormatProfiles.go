@@ -0,0 +1,109 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+// GetEntryFormatProfileV0 returns the format profile of an entry.
+// @Summary      Get entry format profile
+// @Description  Returns the file count, content size, filesystems and PRONOM format breakdown read from the DFXML, Siegfried and DROID reports attached to an entry.
+// @Tags         entries
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Entry UUID"
+// @Success      200  {object}  models.FormatProfile
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      404  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/format_profile [get]
+func GetEntryFormatProfileV0(c *gin.Context) {
+	if _, err := checkToken(c); err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	profile, err := database.FindFormatProfileByEntryID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if profile.ID == 0 {
+		c.JSON(http.StatusNotFound, "no reports have been attached to the entry")
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// AttachFormatReportV0 parses a DFXML, Siegfried or DROID report into an entry's format profile.
+// @Summary      Attach a format report
+// @Description  Parses a fiwalk DFXML file, a Siegfried json or csv report, or a DROID csv export and merges it into the entry's format profile. File count and content size come from the latest report, filesystems from DFXML and the PRONOM format breakdown from Siegfried or DROID. The report may be sent as the request body or as a multipart file named "report".
+// @Tags         entries
+// @Accept       application/xml
+// @Accept       application/json
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Entry UUID"
+// @Success      200  {object}  models.FormatProfile
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/format_profile [post]
+func AttachFormatReportV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var report io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		fileHeader, err := c.FormFile("report")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		defer file.Close()
+		report = file
+	}
+
+	profile, err := controllers.AttachFormatReport(id, report, int(userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/fixity"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// ImageFilesRequest names a directory on the server to register image files from
+type ImageFilesRequest struct {
+	Path string `json:"path"`
+}
+
+// EntryImageFiles are an entry's registered image files with their latest fixity events
+type EntryImageFiles struct {
+	ImageFiles   []models.ImageFile   `json:"image_files"`
+	FixityEvents []models.FixityEvent `json:"fixity_events"`
+}
+
+// GetEntryImageFilesV0 returns the image files registered for an entry.
+// @Summary      Get entry image files
+// @Description  Returns the image files registered for an entry, with their checksums and status, and the latest fixity events.
+// @Tags         entries
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Entry UUID"
+// @Success      200  {object}  EntryImageFiles
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/image_files [get]
+func GetEntryImageFilesV0(c *gin.Context) {
+	if _, err := checkToken(c); err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	imageFiles, err := database.FindImageFilesByEntryID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	fixityEvents, err := database.FindFixityEventsByEntryID(id, 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, EntryImageFiles{ImageFiles: imageFiles, FixityEvents: fixityEvents})
+}
+
+// RegisterEntryImageFilesV0 registers the files in a server directory as an entry's image files.
+// @Summary      Register entry image files
+// @Description  Hashes every file below a directory on the server and registers it for the entry with its size, MD5 and SHA-256. The directory must be inside one of the configured image_roots. Registering a path again replaces its checksums.
+// @Tags         entries
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id     path  string             true  "Entry UUID"
+// @Param        files  body  ImageFilesRequest  true  "Directory to register"
+// @Success      200  {array}   models.ImageFile
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      403  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/image_files [post]
+func RegisterEntryImageFilesV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	request := ImageFilesRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !fixity.InRoots(request.Path) {
+		c.JSON(http.StatusForbidden, fmt.Sprintf("%s is not inside a configured image root", request.Path))
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	imageFiles, err := controllers.RegisterImageFiles(id, request.Path, int(userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, imageFiles)
+}
+
+// FixityReportV0 lists the image files that failed their last fixity check.
+// @Summary      Fixity report
+// @Description  Returns every registered image file that was missing or changed at its last fixity check, with the collection code and media ID of its entry, for the repositories the user can view.
+// @Tags         reports
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   database.ImageFileProblem
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /reports/fixity [get]
+func FixityReportV0(c *gin.Context) {
+	if _, err := checkToken(c); err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	problems, err := database.FindImageFileProblems(controllers.GetPermissions(c).ViewableRepositories())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, problems)
+}
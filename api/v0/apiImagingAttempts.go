@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// GetEntryImagingAttemptsV0 returns the imaging attempts of an entry.
+// @Summary      Get entry imaging attempts
+// @Description  Returns the imaging attempts recorded for an entry, latest first.
+// @Tags         entries
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Entry UUID"
+// @Success      200  {array}   models.ImagingAttempt
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/imaging_attempts [get]
+func GetEntryImagingAttemptsV0(c *gin.Context) {
+	if _, err := checkToken(c); err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	attempts, err := database.FindImagingAttemptsByEntryID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// CreateImagingAttemptV0 records an imaging attempt for an entry.
+// @Summary      Record an imaging attempt
+// @Description  Records an attempt at imaging an entry. imaging_success is image_success_yes or image_success_no, interface and imaging_software are vocabulary keys, and attempted_at defaults to now. The entry's imaging_success, interface, imaging_software and imaged_by are then set from its latest successful attempt.
+// @Tags         entries
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id       path  string                 true  "Entry UUID"
+// @Param        attempt  body  models.ImagingAttempt  true  "Imaging attempt"
+// @Success      201  {object}  models.ImagingAttempt
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/imaging_attempts [post]
+func CreateImagingAttemptV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	attempt := models.ImagingAttempt{}
+	if err := c.ShouldBindJSON(&attempt); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	attempt, err = controllers.RecordImagingAttempt(id, attempt, int(userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, attempt)
+}
+
+// DeleteImagingAttemptV0 deletes an imaging attempt recorded in error.
+// @Summary      Delete an imaging attempt
+// @Description  Deletes an imaging attempt, the entry's imaging fields are derived again from the attempts left.
+// @Tags         entries
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id          path  string  true  "Entry UUID"
+// @Param        attempt_id  path  int     true  "Imaging attempt ID"
+// @Success      200  {string}  string
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/imaging_attempts/{attempt_id} [delete]
+func DeleteImagingAttemptV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	attemptID, err := strconv.Atoi(c.Param("attempt_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := database.DeleteImagingAttempt(id, uint(attemptID), int(userID)); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, "imaging attempt deleted")
+}
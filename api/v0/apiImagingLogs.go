@@ -0,0 +1,69 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+// ReadImagingLogV0 proposes values for an entry from an FTK Imager or KryoFlux log.
+// @Summary      Read an imaging log
+// @Description  Parses an FTK Imager .txt report or a KryoFlux DiskTool Console log and returns the values it proposes for the entry's imaging_software, image_format, image_filename, imaging_success and interface, mapped to vocabulary keys, with the hashes and image size added to imaging_note. Nothing is saved, review the values and send them to /entries/{id}/update. The log may be sent as the request body or as a multipart file named "log".
+// @Tags         entries
+// @Accept       text/plain
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Entry UUID"
+// @Success      200  {object}  controllers.ImagingLogProposal
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/imaging_log [post]
+func ReadImagingLogV0(c *gin.Context) {
+	if _, err := checkToken(c); err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	entry, err := database.FindEntry(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//only multipart requests are parsed as a form, so a plain body is not consumed as urlencoded fields
+	var log io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		fileHeader, err := c.FormFile("log")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+		defer file.Close()
+		log = file
+	}
+
+	proposal, err := controllers.ReadImagingLog(entry, log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, proposal)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+// MoveRequest is the body of a bulk move
+type MoveRequest struct {
+	EntryIDs    []uuid.UUID `json:"entry_ids"`
+	AccessionID uint        `json:"accession_id"`
+}
+
+// MoveEntryV0 moves an entry into another accession.
+// @Summary      Move entry
+// @Description  Re-files an entry into another accession, updating its repository, resource and accession. The entry keeps its media ID if it is free in the target resource, otherwise it gets the next media ID there. The move is recorded and returned.
+// @Tags         entries
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id            path   string  true  "Entry UUID"
+// @Param        accession_id  query  int     true  "Target accession ID"
+// @Success      200  {object}  models.EntryMove
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/{id}/move [post]
+func MoveEntryV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "provided id is not a valid uuid")
+		return
+	}
+
+	accessionID, err := strconv.Atoi(c.Query("accession_id"))
+	if err != nil || accessionID < 1 {
+		c.JSON(http.StatusBadRequest, "no valid accession_id provided")
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	moves, err := database.MoveEntries([]uuid.UUID{uid}, uint(accessionID), int(userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, moves[0])
+}
+
+// MoveEntriesV0 moves a selection of entries into another accession.
+// @Summary      Move entries
+// @Description  Re-files several entries into an accession in one transaction, numbering them like a single move. Nothing is moved if any entry fails.
+// @Tags         entries
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        move  body  MoveRequest  true  "Entries and target accession"
+// @Success      200  {array}   models.EntryMove
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /entries/move [post]
+func MoveEntriesV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	request := MoveRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(request.EntryIDs) == 0 || request.AccessionID == 0 {
+		c.JSON(http.StatusBadRequest, "entry_ids and accession_id are required")
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	moves, err := database.MoveEntries(request.EntryIDs, request.AccessionID, int(userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, moves)
+}
@@ -21,7 +21,7 @@ type SummaryAndTotals struct {
 
 // SummaryDateRange returns a media summary for a date range.
 // @Summary      Date range summary
-// @Description  Returns total counts and sizes of media ingested within a date range. Dates must be in YYYYMMDD format.
+// @Description  Returns total counts and sizes of media ingested within a date range. Dates must be in YYYYMMDD format. A summary of all repositories needs the viewer role in every repository.
 // @Tags         reports
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -36,7 +36,7 @@ type SummaryAndTotals struct {
 // @Router       /reports/range [get]
 func SummaryDateRange(c *gin.Context) {
 	if _, err := checkToken(c); err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
 		return
 	}
 
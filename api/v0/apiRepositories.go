@@ -8,13 +8,14 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/controllers"
 	"github.com/nyudlts/go-medialog/database"
 	"github.com/nyudlts/go-medialog/models"
 )
 
-// GetRepositoriesV0 returns all repositories.
+// GetRepositoriesV0 returns the repositories the user can view.
 // @Summary      List repositories
-// @Description  Returns a list of all repositories.
+// @Description  Returns a list of the repositories the user holds a role in, or of all repositories for a global role.
 // @Tags         repositories
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -26,7 +27,7 @@ func GetRepositoriesV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -36,7 +37,7 @@ func GetRepositoriesV0(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, repositories)
+	c.JSON(http.StatusOK, controllers.GetPermissions(c).FilterRepositories(repositories))
 }
 
 // GetRepositoryV0 returns a repository by ID.
@@ -55,7 +56,7 @@ func GetRepositoryV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -89,7 +90,7 @@ func GetRepositoryV0(c *gin.Context) {
 func CreateRepositoryV0(c *gin.Context) {
 	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -121,20 +122,24 @@ func CreateRepositoryV0(c *gin.Context) {
 
 // DeleteRepositoryV0 deletes a repository by ID.
 // @Summary      Delete repository
-// @Description  Deletes a repository by its ID.
+// @Description  Moves a repository to the trash by its ID. The delete is refused with 409 and a summary of the dependents while the repository still has resources, accessions or entries, unless an admin sets cascade=true to move them to the trash too.
 // @Tags         repositories
 // @Produce      json
 // @Security     ApiKeyAuth
-// @Param        id   path      int  true  "Repository ID"
+// @Param        id       path   int   true   "Repository ID"
+// @Param        cascade  query  bool  false  "Also delete the repository's resources, accessions and entries (admin only)"
 // @Success      200  {string}  string
 // @Failure      400  {string}  string
 // @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  DeleteConflict
 // @Failure      500  {string}  string
 // @Router       /repositories/{id} [delete]
 func DeleteRepositoryV0(c *gin.Context) {
-	_, err := checkToken(c)
+	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -145,13 +150,28 @@ func DeleteRepositoryV0(c *gin.Context) {
 		return
 	}
 
-	if err := database.DeleteRepository(uint(repositoryID)); err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+	cascade, err := isCascadeDelete(c, token)
+	if err != nil {
+		c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, fmt.Sprintf("Repository %d deleted", repositoryID))
+	if !cascade {
+		if err := database.DeleteRepository(uint(repositoryID)); err != nil {
+			deleteError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, fmt.Sprintf("Repository %d deleted", repositoryID))
+		return
+	}
+
+	summary, err := database.DeleteRepositoryCascade(uint(repositoryID))
+	if err != nil {
+		deleteError(c, err)
+		return
+	}
 
+	c.JSON(http.StatusOK, fmt.Sprintf("Repository %d deleted with %s", repositoryID, summary.String()))
 }
 
 // GetRepositoryEntriesV0 returns entries for a repository.
@@ -172,7 +192,7 @@ func DeleteRepositoryV0(c *gin.Context) {
 func GetRepositoryEntriesV0(c *gin.Context) {
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -257,7 +277,7 @@ func GetRepositoryEntriesV0(c *gin.Context) {
 func GetRepositorySummaryV0(c *gin.Context) {
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/controllers"
 	"github.com/nyudlts/go-medialog/database"
 	"github.com/nyudlts/go-medialog/models"
 )
@@ -27,7 +28,7 @@ import (
 func CreateResourceV0(c *gin.Context) {
 	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -66,42 +67,61 @@ func CreateResourceV0(c *gin.Context) {
 
 // DeleteResourceV0 deletes a resource by ID.
 // @Summary      Delete resource
-// @Description  Deletes a resource by its ID.
+// @Description  Moves a resource to the trash by its ID. The delete is refused with 409 and a summary of the dependents while the resource still has accessions or entries, unless an admin sets cascade=true to move them to the trash too.
 // @Tags         resources
 // @Produce      json
 // @Security     ApiKeyAuth
-// @Param        id   path      int  true  "Resource ID"
+// @Param        id       path   int   true   "Resource ID"
+// @Param        cascade  query  bool  false  "Also delete the resource's accessions and entries (admin only)"
 // @Success      200  {string}  string
 // @Failure      400  {string}  string
 // @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  DeleteConflict
 // @Failure      500  {string}  string
 // @Router       /resources/{id} [delete]
 func DeleteResourceV0(c *gin.Context) {
-	_, err := checkToken(c)
+	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
 	resourceIDParam := c.Param("id")
 	resourceID, err := strconv.Atoi(resourceIDParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		c.JSON(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := database.DeleteResource(uint(resourceID)); err != nil {
-		c.JSON(http.StatusInternalServerError, err)
+	cascade, err := isCascadeDelete(c, token)
+	if err != nil {
+		c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if !cascade {
+		if err := database.DeleteResource(uint(resourceID)); err != nil {
+			deleteError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, fmt.Sprintf("Resource %d deleted", resourceID))
 		return
 	}
 
-	c.JSON(http.StatusOK, fmt.Sprintf("Resource %d deleted", resourceID))
+	summary, err := database.DeleteResourceCascade(uint(resourceID))
+	if err != nil {
+		deleteError(c, err)
+		return
+	}
 
+	c.JSON(http.StatusOK, fmt.Sprintf("Resource %d deleted with %s", resourceID, summary.String()))
 }
 
-// GetResourcesV0 returns all resources.
+// GetResourcesV0 returns the resources the user can view.
 // @Summary      List resources
-// @Description  Returns a list of all resources.
+// @Description  Returns a list of the resources in repositories the user can view.
 // @Tags         resources
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -113,7 +133,7 @@ func GetResourcesV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -123,7 +143,7 @@ func GetResourcesV0(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, resources)
+	c.JSON(http.StatusOK, controllers.GetPermissions(c).FilterResources(resources))
 }
 
 // GetResourceV0 returns a resource by ID.
@@ -142,7 +162,7 @@ func GetResourceV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -179,7 +199,7 @@ func GetResourceEntriesV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -250,7 +270,7 @@ func GetResourceEntriesV0(c *gin.Context) {
 
 // GetResourceSummaryV0 returns a media type summary for a resource.
 // @Summary      Get resource summary
-// @Description  Returns media type totals and per-type summaries for a given resource.
+// @Description  Returns media type totals and per-type summaries for a given resource, and the file formats totalled from the format profiles of its entries.
 // @Tags         resources
 // @Produce      json
 // @Security     ApiKeyAuth
@@ -264,7 +284,7 @@ func GetResourceSummaryV0(c *gin.Context) {
 
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
 		return
 	}
 
@@ -287,11 +307,18 @@ func GetResourceSummaryV0(c *gin.Context) {
 		return
 	}
 
+	formats, err := database.GetFormatSummaryByResource(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	resourceSummary := SummaryTotalsResource{}
 	resourceSummary.ResourceIdentifier = resource.CollectionCode
 	resourceSummary.ResourceTitle = resource.Title
 	resourceSummary.Totals = summaries.GetTotals()
 	resourceSummary.Summaries = summaries.GetSlice()
+	resourceSummary.Formats = formats
 
 	c.JSON(http.StatusOK, resourceSummary)
 }
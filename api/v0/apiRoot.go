@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -30,10 +31,11 @@ type SummaryTotalsRepo struct {
 }
 
 type SummaryTotalsResource struct {
-	ResourceIdentifier string             `json:"resource_identifier"`
-	ResourceTitle      string             `json:"resource_title"`
-	Totals             database.Totals    `json:"totals"`
-	Summaries          []database.Summary `json:"summaries"`
+	ResourceIdentifier string                 `json:"resource_identifier"`
+	ResourceTitle      string                 `json:"resource_title"`
+	Totals             database.Totals        `json:"totals"`
+	Summaries          []database.Summary     `json:"summaries"`
+	Formats            database.FormatSummary `json:"formats"`
 }
 
 type SummaryTotalsAccession struct {
@@ -52,7 +54,7 @@ type APIError struct {
 
 // APILogin authenticates a user and returns an API token.
 // @Summary      Login
-// @Description  Authenticates a user by email and password, returning a session token valid for 3 hours.
+// @Description  Authenticates a user by email and password, returning a session token valid for 3 hours. Repeated failures lock the account or the client address for a while.
 // @Tags         auth
 // @Produce      json
 // @Param        user      path   string  true  "User email address"
@@ -60,6 +62,7 @@ type APIError struct {
 // @Success      200  {object}  models.Token
 // @Failure      400  {object}  APIError
 // @Failure      401  {string}  string
+// @Failure      429  {string}  string
 // @Failure      500  {string}  string
 // @Router       /users/{user}/login [post]
 func APILogin(c *gin.Context) {
@@ -75,22 +78,19 @@ func APILogin(c *gin.Context) {
 		return
 	}
 
-	user, err := database.FindUserByEmail(email)
+	user, status, err := controllers.CheckLogin(email, password, c.ClientIP(), controllers.LoginViaAPI)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, map[string]string{"error": "login failed - user not found"})
+		c.JSON(status, map[string]string{"error": "login failed - " + err.Error()})
 		return
 	}
 
-	hash := sha512.Sum512([]byte(password + user.Salt))
-	userSHA512 := hex.EncodeToString(hash[:])
-
-	if userSHA512 != user.EncryptedPassword {
-		c.JSON(http.StatusBadRequest, fmt.Sprintf("storedChecksum: %s, calculatedChecksum: %s", user.EncryptedPassword, userSHA512))
+	if !user.CanAccessAPI {
+		c.JSON(http.StatusUnauthorized, "login failed -- user not authorized to access api")
 		return
 	}
 
-	if !user.CanAccessAPI {
-		c.JSON(http.StatusUnauthorized, "login failed -- user not authorized to access api")
+	if err := controllers.RecordLoginSuccess(&user, c.ClientIP(), controllers.LoginViaAPI); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -102,12 +102,13 @@ func APILogin(c *gin.Context) {
 	user.Salt = "####"
 
 	apiToken := models.Token{
-		Token:   token,
-		UserID:  user.ID,
-		IsValid: true,
-		Expires: time.Now().Add(time.Hour * 3),
-		User:    user,
-		Type:    "api",
+		Token:     token,
+		UserID:    user.ID,
+		IsValid:   true,
+		Expires:   time.Now().Add(time.Hour * 3),
+		User:      user,
+		Type:      "api",
+		IPAddress: c.ClientIP(),
 	}
 
 	//expire users other tokens
@@ -127,18 +128,24 @@ func APILogin(c *gin.Context) {
 
 // APILogout invalidates the current API token.
 // @Summary      Logout
-// @Description  Invalidates the current API token.
+// @Description  Invalidates the current API token. Long-lived API keys can not be logged out, they are revoked from the user's page.
 // @Tags         auth
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Success      200  {string}  string
+// @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      500  {string}  string
 // @Router       /logout [delete]
 func APILogout(c *gin.Context) {
 	token, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ACCESS_DENIED)
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
+		return
+	}
+
+	if strings.HasPrefix(token, models.APIKeyPrefix) {
+		c.JSON(http.StatusBadRequest, map[string]string{"error": "api keys can not be logged out, revoke the key instead"})
 		return
 	}
 
@@ -181,7 +188,7 @@ func GetV0Root(c *gin.Context) {
 func DeleteSessionsV0(c *gin.Context) {
 	_, err := checkToken(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, err.Error())
+		c.JSON(tokenErrorStatus(err), err.Error())
 		return
 	}
 
@@ -200,6 +207,13 @@ func checkToken(c *gin.Context) (string, error) {
 		return "", fmt.Errorf("no `X-Medialog-Token` set in request header")
 	}
 
+	if strings.HasPrefix(token, models.APIKeyPrefix) {
+		if err := checkAPIKey(token, c.Request.Method); err != nil {
+			return token, err
+		}
+		return token, authorizeToken(c, token)
+	}
+
 	apiToken, err := database.FindToken(token)
 	if err != nil {
 		return "", fmt.Errorf("could not find supplied token: %s", token)
@@ -209,5 +223,89 @@ func checkToken(c *gin.Context) (string, error) {
 		return "", fmt.Errorf("invalid token - please reauthenticate")
 	}
 
-	return token, nil
+	return token, authorizeToken(c, token)
+}
+
+// authorizeToken checks that the owner of a token or api key holds the role the request's route requires
+func authorizeToken(c *gin.Context, token string) error {
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		return err
+	}
+
+	user, err := database.FindUser(userID)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := controllers.LoadPermissions(user)
+	if err != nil {
+		return err
+	}
+
+	if err := controllers.Authorize(c, permissions); err != nil {
+		return err
+	}
+
+	c.Set(controllers.ContextKeyPermissions, permissions)
+	return nil
+}
+
+// tokenErrorStatus is the response code for a failed checkToken, a valid token without the role the route requires is
+// forbidden and a request that names its repositories ambiguously is bad
+func tokenErrorStatus(err error) int {
+	if status := controllers.AuthorizationStatus(err); status == http.StatusForbidden || status == http.StatusBadRequest {
+		return status
+	}
+	return http.StatusUnauthorized
+}
+
+// checkAPIKey validates a long-lived api key, its owner and whether its scope allows the request method
+func checkAPIKey(key string, method string) error {
+	apiKey, err := database.FindAPIKey(key)
+	if err != nil {
+		return fmt.Errorf("could not find supplied api key")
+	}
+
+	if apiKey.IsRevoked() {
+		return fmt.Errorf("api key has been revoked")
+	}
+
+	if apiKey.IsExpired() {
+		return fmt.Errorf("api key has expired")
+	}
+
+	user, err := database.FindUser(apiKey.UserID)
+	if err != nil {
+		return fmt.Errorf("could not find the owner of the api key")
+	}
+
+	if !user.IsActive || !user.CanAccessAPI {
+		return fmt.Errorf("user not authorized to access api")
+	}
+
+	if !apiKey.Allows(method) {
+		return fmt.Errorf("api key scope `%s` does not allow %s requests", apiKey.Scope, method)
+	}
+
+	if err := database.TouchAPIKey(apiKey.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// isAdminToken reports whether the owner of a token or api key is an admin
+func isAdminToken(token string) (bool, error) {
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		return false, err
+	}
+
+	user, err := database.FindUser(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return user.IsAdmin, nil
 }
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+// GetStagingReportV0 returns the report of the last staging scan.
+// @Summary      Staging report
+// @Description  Returns the outcome of the last staging scan since the server started: the files matched to entries, the fields filled in, and the images that matched no entry.
+// @Tags         reports
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  controllers.StagingReport
+// @Failure      401  {string}  string
+// @Failure      404  {string}  string
+// @Router       /reports/staging [get]
+func GetStagingReportV0(c *gin.Context) {
+	if _, err := checkToken(c); err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	report := controllers.GetLastStagingReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, "no staging scan has run yet")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ScanStagingV0 scans the staging directories now.
+// @Summary      Scan staging directories
+// @Description  Matches the files in every configured staging directory to entries by image filename or by collection code and media ID, fills in the entries' image filename, image format and location, and returns the report.
+// @Tags         reports
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  controllers.StagingReport
+// @Failure      401  {string}  string
+// @Failure      500  {string}  string
+// @Router       /staging/scan [post]
+func ScanStagingV0(c *gin.Context) {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	userID, err := database.FindUserIDByToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	report, err := controllers.ScanStaging(int(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
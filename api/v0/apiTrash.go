@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+var ADMIN_ONLY = map[string]string{"error": "admin access required"}
+
+// checkAdminToken validates the token and requires its owner to be an admin, it writes the error response itself
+func checkAdminToken(c *gin.Context) bool {
+	token, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), ACCESS_DENIED)
+		return false
+	}
+
+	admin, err := isAdminToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return false
+	}
+
+	if !admin {
+		c.JSON(http.StatusForbidden, ADMIN_ONLY)
+		return false
+	}
+
+	return true
+}
+
+// GetTrashV0 lists the deleted objects.
+// @Summary      List trash
+// @Description  Returns the deleted repositories, resources, accessions and entries. Objects deleted together with their parent are listed under the parent only. Admin only.
+// @Tags         trash
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  database.Trash
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {string}  string
+// @Router       /trash [get]
+func GetTrashV0(c *gin.Context) {
+	if !checkAdminToken(c) {
+		return
+	}
+
+	trash, err := database.FindTrash()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+// RestoreTrashItemV0 restores a deleted object.
+// @Summary      Restore from trash
+// @Description  Restores a deleted object along with everything that was deleted with it. Objects inside a deleted parent can not be restored on their own. Admin only.
+// @Tags         trash
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        type  path  string  true  "Object type"  Enums(repositories, resources, accessions, entries)
+// @Param        id    path  string  true  "Object ID, a UUID for entries"
+// @Success      200  {string}  string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /trash/{type}/{id}/restore [post]
+func RestoreTrashItemV0(c *gin.Context) {
+	if !checkAdminToken(c) {
+		return
+	}
+
+	summary, err := database.RestoreFromTrash(c.Param("type"), c.Param("id"))
+	if err != nil {
+		c.JSON(controllers.TrashErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	message := fmt.Sprintf("%s %s restored", c.Param("type"), c.Param("id"))
+	if summary.HasDependents() {
+		message = fmt.Sprintf("%s with %s", message, summary.String())
+	}
+	c.JSON(http.StatusOK, message)
+}
+
+// PurgeTrashItemV0 permanently deletes a deleted object.
+// @Summary      Purge from trash
+// @Description  Permanently deletes a deleted object with everything below it. Admin only.
+// @Tags         trash
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        type  path  string  true  "Object type"  Enums(repositories, resources, accessions, entries)
+// @Param        id    path  string  true  "Object ID, a UUID for entries"
+// @Success      200  {string}  string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /trash/{type}/{id} [delete]
+func PurgeTrashItemV0(c *gin.Context) {
+	if !checkAdminToken(c) {
+		return
+	}
+
+	summary, err := database.PurgeFromTrash(c.Param("type"), c.Param("id"))
+	if err != nil {
+		c.JSON(controllers.TrashErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	message := fmt.Sprintf("%s %s purged", c.Param("type"), c.Param("id"))
+	if summary.HasDependents() {
+		message = fmt.Sprintf("%s with %s", message, summary.String())
+	}
+	c.JSON(http.StatusOK, message)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// GetVocabulariesV0 returns every controlled vocabulary.
+// @Summary      List vocabularies
+// @Description  Returns the terms of every controlled vocabulary keyed by vocabulary name. Retired terms are omitted unless include_retired=true.
+// @Tags         vocabularies
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        include_retired  query     bool  false  "Include retired terms"
+// @Success      200  {object}  map[string][]models.VocabularyTerm
+// @Failure      401  {string}  string
+// @Router       /vocabularies [get]
+func GetVocabulariesV0(c *gin.Context) {
+	_, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	includeRetired := c.Query("include_retired") == "true"
+	vocabularies := map[string][]models.VocabularyTerm{}
+	for name := range controllers.GetVocabularyNames() {
+		vocabularies[name] = controllers.GetVocabularyTerms(name, includeRetired)
+	}
+
+	c.JSON(http.StatusOK, vocabularies)
+}
+
+// GetVocabularyV0 returns the terms of a controlled vocabulary.
+// @Summary      Get vocabulary
+// @Description  Returns the terms of a single controlled vocabulary, e.g. mediatypes or storage_locations. Retired terms are omitted unless include_retired=true.
+// @Tags         vocabularies
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        vocabulary       path      string  true   "Vocabulary name"
+// @Param        include_retired  query     bool    false  "Include retired terms"
+// @Success      200  {array}   models.VocabularyTerm
+// @Failure      400  {string}  string
+// @Failure      401  {string}  string
+// @Router       /vocabularies/{vocabulary} [get]
+func GetVocabularyV0(c *gin.Context) {
+	_, err := checkToken(c)
+	if err != nil {
+		c.JSON(tokenErrorStatus(err), err.Error())
+		return
+	}
+
+	vocabulary := c.Param("vocabulary")
+	if !controllers.IsVocabulary(vocabulary) {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("%s is not a controlled vocabulary", vocabulary))
+		return
+	}
+
+	c.JSON(http.StatusOK, controllers.GetVocabularyTerms(vocabulary, c.Query("include_retired") == "true"))
+}
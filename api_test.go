@@ -10,11 +10,16 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/nyudlts/go-medialog/api/v0"
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
 	"github.com/nyudlts/go-medialog/models"
 	router "github.com/nyudlts/go-medialog/router"
+	"github.com/nyudlts/go-medialog/version"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,16 +32,36 @@ func TestAPI(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	var err error
-	env, err = router.GetEnvironment(configuration, environment)
-	if err != nil {
-		t.Error(err)
+	if configuration == "" {
+		//run against an in-memory sqlite database when no config is provided
+		env = models.Environment{
+			DatabaseConfig: models.DatabaseConfig{Driver: database.DriverSQLite, Path: ":memory:"},
+			AdminEmail:     "admin@medialog.test",
+		}
+	} else {
+		env, err = router.GetEnvironment(configuration, environment)
+		if err != nil {
+			t.Error(err)
+		}
 	}
 
 	r, err = router.SetupRouter(env, true, false)
 	if err != nil {
 		t.Error(err)
 	}
-	t.Logf("[INFO] Running Go-Medialog %s", version)
+
+	if configuration == "" {
+		if err := database.MigrateModels(); err != nil {
+			t.Fatal(err)
+		}
+
+		password, err := controllers.CreateAdminUser(env.AdminEmail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.TestCreds = models.TestCreds{Username: env.AdminEmail, Password: password}
+	}
+	t.Logf("[INFO] Running Go-Medialog %s", version.GetAppVersion())
 
 	t.Run("test get API root", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
@@ -383,6 +408,45 @@ func TestAPI(t *testing.T) {
 		assert.NotEqual(t, "00000000-0000-0000-0000-000000000000", entry.ID.String())
 	})
 
+	t.Run("test create an entry with the next media id", func(t *testing.T) {
+		create := func(form url.Values) (int, models.Entry) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			form.Add("mediatype", "mediatype_floppy_3_5")
+			form.Add("stock_unit", "MB")
+			form.Add("stock_size_num", "3.5")
+			form.Add("resource_id", fmt.Sprintf("%d", resource.ID))
+			form.Add("repository_id", fmt.Sprintf("%d", repository.ID))
+			form.Add("accession_id", fmt.Sprintf("%d", accession.ID))
+			req, err := http.NewRequestWithContext(c, "POST", fmt.Sprintf("%s/entries", APIROOT), strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Error(err)
+			}
+			req.Header.Add("X-Medialog-Token", token)
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			r.ServeHTTP(recorder, req)
+
+			created := models.Entry{}
+			if recorder.Code == http.StatusOK {
+				if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+					t.Error(err)
+				}
+			}
+			return recorder.Code, created
+		}
+
+		code, next := create(url.Values{})
+		assert.Equal(t, 200, code)
+		assert.Equal(t, entry.MediaID+1, next.MediaID)
+
+		code, _ = create(url.Values{"media_id": {fmt.Sprintf("%d", entry.MediaID)}})
+		assert.Equal(t, 409, code)
+
+		if err := database.DeleteEntry(next.ID); err != nil {
+			t.Error(err)
+		}
+	})
+
 	t.Run("test get all entry ids", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(recorder)
@@ -450,6 +514,35 @@ func TestAPI(t *testing.T) {
 		assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("content-type"))
 	})
 
+	t.Run("test get entry history", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		url := fmt.Sprintf("%s/entries/%s/history", APIROOT, entry.ID)
+		req, err := http.NewRequestWithContext(c, "GET", url, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", token)
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 200, recorder.Code)
+		assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("content-type"))
+
+		body, err := io.ReadAll(recorder.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		revisions := []models.EntryRevision{}
+		if err := json.Unmarshal(body, &revisions); err != nil {
+			t.Error(err)
+		}
+
+		assert.Equal(t, 1, len(revisions))
+		if len(revisions) > 0 {
+			assert.Equal(t, "sl_rsw_acm_born_digital", revisions[0].Changes["location"].To)
+		}
+	})
+
 	t.Run("test update location failure", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(recorder)
@@ -465,11 +558,309 @@ func TestAPI(t *testing.T) {
 		assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("content-type"))
 	})
 
+	t.Run("test update an entry", func(t *testing.T) {
+		edited := entry
+		edited.LabelText = "updated label"
+		edited.MediaID = entry.MediaID + 100
+		edited.CreatedBy = entry.CreatedBy + 1
+		body, err := json.Marshal(edited)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		url := fmt.Sprintf("%s/entries/%s/update", APIROOT, entry.ID)
+		req, err := http.NewRequestWithContext(c, "POST", url, strings.NewReader(string(body)))
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", token)
+		req.Header.Add("Content-Type", "application/json")
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 200, recorder.Code)
+
+		updated, err := database.FindEntry(entry.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "updated label", updated.LabelText)
+		assert.Equal(t, entry.MediaID, updated.MediaID)
+		assert.Equal(t, entry.CreatedBy, updated.CreatedBy)
+	})
+
+	//a technician in the test repository and an entry in another one
+	var otherEntry models.Entry
+	var technicianID uint
+	var technicianKey string
+	t.Run("test create a technician in one repository", func(t *testing.T) {
+		otherRepository := models.Repository{Slug: "other", Title: "Other Repository"}
+		if _, err := database.CreateRepository(&otherRepository); err != nil {
+			t.Fatal(err)
+		}
+		otherResource := models.Resource{Title: "Other Resource", CollectionCode: "other", RepositoryID: otherRepository.ID}
+		if _, err := database.InsertResource(&otherResource); err != nil {
+			t.Fatal(err)
+		}
+		otherAccession := models.Accession{AccessionNum: "other", ResourceID: otherResource.ID}
+		if _, err := database.InsertAccession(&otherAccession); err != nil {
+			t.Fatal(err)
+		}
+		otherEntry = models.Entry{
+			ID:           uuid.New(),
+			MediaID:      1,
+			Mediatype:    "mediatype_floppy_3_5",
+			LabelText:    "other label",
+			RepositoryID: otherRepository.ID,
+			ResourceID:   otherResource.ID,
+			AccessionID:  otherAccession.ID,
+		}
+		if err := database.InsertEntry(&otherEntry); err != nil {
+			t.Fatal(err)
+		}
+
+		technician := models.User{Email: "technician@medialog.test", IsActive: true, CanAccessAPI: true}
+		if err := controllers.SetPassword(&technician, "technician-password"); err != nil {
+			t.Fatal(err)
+		}
+		technicianID, err = database.InsertUser(&technician)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := controllers.GrantUserRole(technicianID, models.RoleGrant{Role: models.RoleTechnician, RepositoryID: repository.ID}, 0); err != nil {
+			t.Fatal(err)
+		}
+		key, apiKey, err := controllers.GenerateAPIKey(technicianID, "technician", models.APIKeyScopeReadWrite, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.InsertAPIKey(&apiKey); err != nil {
+			t.Fatal(err)
+		}
+		technicianKey = key
+	})
+
+	t.Run("test technician cannot update an entry in another repository", func(t *testing.T) {
+		update := func(id uuid.UUID, edited models.Entry) int {
+			body, err := json.Marshal(edited)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			url := fmt.Sprintf("%s/entries/%s/update", APIROOT, id)
+			req, err := http.NewRequestWithContext(c, "POST", url, strings.NewReader(string(body)))
+			if err != nil {
+				t.Error(err)
+			}
+			req.Header.Add("X-Medialog-Token", technicianKey)
+			req.Header.Add("Content-Type", "application/json")
+			r.ServeHTTP(recorder, req)
+			return recorder.Code
+		}
+
+		edited := otherEntry
+		edited.LabelText = "changed by a technician"
+
+		//the url names an entry the technician may edit but the body names the other one
+		assert.Equal(t, 400, update(entry.ID, edited))
+		//the entry itself is in a repository the technician has no role in
+		assert.Equal(t, 403, update(otherEntry.ID, edited))
+
+		unchanged, err := database.FindEntry(otherEntry.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "other label", unchanged.LabelText)
+	})
+
+	t.Run("test technician cannot create an entry with conflicting accession ids", func(t *testing.T) {
+		create := func(query string, accessionID uint) int {
+			body, err := json.Marshal(map[string]interface{}{"accession_id": accessionID, "mediatype": "mediatype_floppy_3_5", "label_text": "conflicting"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			req, err := http.NewRequestWithContext(c, "POST", fmt.Sprintf("%s/entries%s", APIROOT, query), strings.NewReader(string(body)))
+			if err != nil {
+				t.Error(err)
+			}
+			req.Header.Add("X-Medialog-Token", technicianKey)
+			req.Header.Add("Content-Type", "application/json")
+			r.ServeHTTP(recorder, req)
+			return recorder.Code
+		}
+
+		//the query string names the technician's accession but the body names the other one
+		assert.Equal(t, 400, create(fmt.Sprintf("?accession_id=%d", entry.AccessionID), otherEntry.AccessionID))
+		assert.Equal(t, 403, create("", otherEntry.AccessionID))
+		//an accession that does not exist names no repository the technician has a role in
+		assert.Equal(t, 403, create("", 0))
+
+		entries, err := database.FindEntriesByAccessionID(otherEntry.AccessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 1, len(entries))
+	})
+
+	t.Run("test technician only lists entries in its repository", func(t *testing.T) {
+		get := func(requestURL string, v interface{}) int {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			req, err := http.NewRequestWithContext(c, "GET", requestURL, nil)
+			if err != nil {
+				t.Error(err)
+			}
+			req.Header.Add("X-Medialog-Token", technicianKey)
+			r.ServeHTTP(recorder, req)
+			if recorder.Code == http.StatusOK && v != nil {
+				if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+					t.Error(err)
+				}
+			}
+			return recorder.Code
+		}
+
+		ids := []string{}
+		assert.Equal(t, 200, get(fmt.Sprintf("%s/entries?all_ids=true", APIROOT), &ids))
+		assert.Contains(t, ids, entry.ID.String())
+		assert.NotContains(t, ids, otherEntry.ID.String())
+
+		result := database.EntrySearchResult{}
+		assert.Equal(t, 200, get(fmt.Sprintf("%s/search/entries?query=label", APIROOT), &result))
+		assert.NotEmpty(t, result.Results)
+		for _, e := range result.Results {
+			assert.Equal(t, repository.ID, e.RepositoryID)
+		}
+
+		repositories := []models.Repository{}
+		assert.Equal(t, 200, get(fmt.Sprintf("%s/repositories", APIROOT), &repositories))
+		assert.Equal(t, 1, len(repositories))
+
+		//a summary of every repository needs the viewer role in each of them
+		assert.Equal(t, 403, get(fmt.Sprintf("%s/reports/range?start_date=20000101&end_date=20991231", APIROOT), nil))
+		assert.Equal(t, 200, get(fmt.Sprintf("%s/reports/range?start_date=20000101&end_date=20991231&repository_id=%d", APIROOT, repository.ID), nil))
+
+		//routes without a role, like the trash, need an admin
+		assert.Equal(t, 403, get(fmt.Sprintf("%s/trash", APIROOT), nil))
+	})
+
+	t.Run("test delete the technician", func(t *testing.T) {
+		if err := database.DeleteUser(technicianID); err != nil {
+			t.Error(err)
+		}
+	})
+
+	//search functions
+	t.Run("test search entries", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		url := fmt.Sprintf("%s/search/entries?accession_id=%d&mediatype=mediatype_floppy_3_5", APIROOT, accession.ID)
+		req, err := http.NewRequestWithContext(c, "GET", url, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", token)
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 200, recorder.Code)
+
+		result := database.EntrySearchResult{}
+		body, _ := io.ReadAll(recorder.Body)
+		if err := json.Unmarshal(body, &result); err != nil {
+			t.Error(err)
+		}
+
+		assert.Equal(t, int64(1), result.Pagination.TotalRecords)
+		assert.Equal(t, 1, len(result.Facets["status"]))
+	})
+
+	//vocabulary functions
+	t.Run("test get storage locations vocabulary", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		url := fmt.Sprintf("%s/vocabularies/storage_locations", APIROOT)
+		req, err := http.NewRequestWithContext(c, "GET", url, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", token)
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 200, recorder.Code)
+
+		body, err := io.ReadAll(recorder.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		terms := []models.VocabularyTerm{}
+		if err := json.Unmarshal(body, &terms); err != nil {
+			t.Error(err)
+		}
+
+		keys := map[string]bool{}
+		for _, term := range terms {
+			keys[term.Key] = true
+		}
+		assert.True(t, keys["sl_rsw_acm_born_digital"])
+	})
+
+	//api key functions
+	t.Run("test read scoped api key", func(t *testing.T) {
+		user, err := database.FindUserByEmail(env.TestCreds.Username)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, apiKey, err := controllers.GenerateAPIKey(user.ID, "read only", models.APIKeyScopeRead, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.InsertAPIKey(&apiKey); err != nil {
+			t.Fatal(err)
+		}
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		req, err := http.NewRequestWithContext(c, "GET", fmt.Sprintf("%s/repositories", APIROOT), nil)
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", key)
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 200, recorder.Code)
+
+		recorder = httptest.NewRecorder()
+		form := url.Values{"slug": {"read-only"}, "title": {"Read Only"}}
+		req, err = http.NewRequestWithContext(c, "POST", fmt.Sprintf("%s/repositories", APIROOT), strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Add("X-Medialog-Token", key)
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 401, recorder.Code)
+
+		if err := database.RevokeAPIKey(apiKey.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		recorder = httptest.NewRecorder()
+		req, err = http.NewRequestWithContext(c, "GET", fmt.Sprintf("%s/repositories", APIROOT), nil)
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", key)
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 401, recorder.Code)
+	})
+
 	//report functions
 	t.Run("test get summary of range", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(recorder)
-		url := fmt.Sprintf("%s/reports/range?start_date=%s&end_date=%s&repository_id=%d", APIROOT, "20140101", "20241031", repository.ID)
+		url := fmt.Sprintf("%s/reports/range?start_date=%s&end_date=%s&repository_id=%d", APIROOT, "20140101", time.Now().Format("20060102"), repository.ID)
 		req, err := http.NewRequestWithContext(c, "GET", url, nil)
 		if err != nil {
 			t.Error(err)
@@ -494,7 +885,7 @@ func TestAPI(t *testing.T) {
 	t.Run("test get summary of range refreshed only", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(recorder)
-		url := fmt.Sprintf("%s/reports/range?start_date=%s&end_date=%s&is_refreshed=true", APIROOT, "20140101", "20241031")
+		url := fmt.Sprintf("%s/reports/range?start_date=%s&end_date=%s&is_refreshed=true", APIROOT, "20140101", time.Now().Format("20060102"))
 		req, err := http.NewRequestWithContext(c, "GET", url, nil)
 		if err != nil {
 			t.Error(err)
@@ -516,6 +907,68 @@ func TestAPI(t *testing.T) {
 
 	})
 
+	//import functions
+	importCSV := "mediatype,stock_size_num,stock_unit,box_number,label_text\n" +
+		"mediatype_cd,700,MB,1,Letters\n" +
+		"CD-R,700,MB,1,Photographs\n"
+
+	t.Run("test import entries dry run", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		url := fmt.Sprintf("%s/accessions/%d/import", APIROOT, accession.ID)
+		req, err := http.NewRequestWithContext(c, "POST", url, strings.NewReader(importCSV+"mediatype_unknown,0,MB,2,Bad Row\n"))
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", token)
+		req.Header.Add("Content-Type", "text/csv")
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 200, recorder.Code)
+
+		report := controllers.EntryImportReport{}
+		body, _ := io.ReadAll(recorder.Body)
+		if err := json.Unmarshal(body, &report); err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, 2, report.Valid)
+		assert.Equal(t, 1, report.Invalid)
+		assert.False(t, report.Committed)
+	})
+
+	t.Run("test import entries commit", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		url := fmt.Sprintf("%s/accessions/%d/import?commit=true", APIROOT, accession.ID)
+		req, err := http.NewRequestWithContext(c, "POST", url, strings.NewReader(importCSV))
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("X-Medialog-Token", token)
+		req.Header.Add("Content-Type", "text/csv")
+		r.ServeHTTP(recorder, req)
+		assert.Equal(t, 200, recorder.Code)
+
+		report := controllers.EntryImportReport{}
+		body, _ := io.ReadAll(recorder.Body)
+		if err := json.Unmarshal(body, &report); err != nil {
+			t.Error(err)
+		}
+		assert.True(t, report.Committed)
+		assert.Equal(t, 2, len(report.Rows))
+
+		if len(report.Rows) == 2 {
+			assert.Equal(t, "mediatype_cdr", report.Rows[1].Entry.Mediatype)
+			assert.Equal(t, report.Rows[0].Entry.MediaID+1, report.Rows[1].Entry.MediaID)
+		}
+
+		//remove the imported entries
+		for _, row := range report.Rows {
+			if err := database.DeleteEntry(row.Entry.ID); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
 	//delete functions
 
 	t.Run("test delete an entry", func(t *testing.T) {
@@ -38,8 +38,9 @@ func GetAccessions(c *gin.Context) {
 	}
 
 	c.HTML(200, "accessions-index.html", gin.H{
-		"accessions":    accessions,
+		"accessions":    GetPermissions(c).FilterAccessions(accessions),
 		"isAdmin":       sessionCookies.IsAdmin,
+		"permissions":   c.MustGet(ContextKeyPermissions),
 		"repositoryMap": repositoryMap2,
 		"isLoggedIn":    true,
 		"user":          user,
@@ -142,6 +143,7 @@ func GetAccession(c *gin.Context) {
 		"entries":         entries,
 		"isAuthenticated": true,
 		"isAdmin":         sessionCookies.IsAdmin,
+		"permissions":     c.MustGet(ContextKeyPermissions),
 		"summary":         summary,
 		"totals":          summary.GetTotals(),
 		"users":           users,
@@ -150,7 +152,7 @@ func GetAccession(c *gin.Context) {
 		"pagination":      pagination,
 		"limitValues":     LimitValues,
 		"overlimit":       overlimit,
-		"mediatypes":      GetMediatypes(),
+		"mediatypes":      getVocabularyWithRetired(VocabularyMediatypes),
 	})
 }
 
@@ -182,6 +184,7 @@ func NewAccession(c *gin.Context) {
 		"isLoggedIn": true,
 		"isAdmin":    sessionCookies.IsAdmin,
 		"user":       user,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -259,6 +262,7 @@ func EditAccession(c *gin.Context) {
 		"repository": repository,
 		"isLoggedIn": true,
 		"user":       user,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
 	})
 
 }
@@ -300,7 +304,7 @@ func UpdateAccession(c *gin.Context) {
 	c.Redirect(http.StatusFound, fmt.Sprintf(AccessionsShow, accession.ID))
 }
 
-func DeleteAccession(c *gin.Context) {
+func ConfirmDeleteAccession(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -309,15 +313,54 @@ func DeleteAccession(c *gin.Context) {
 
 	accession, err := database.FindAccession(uint(id))
 	if err != nil {
-		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		ThrowError(http.StatusNotFound, err.Error(), c, true)
+		return
+	}
+
+	summary, err := database.FindAccessionDependents(uint(id))
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
 		return
 	}
 
-	if err := database.DeleteAccession(uint(id)); err != nil {
+	renderDeleteConfirmation(c, deleteConfirmation{
+		Object:  "accession",
+		Label:   accession.AccessionNum,
+		Action:  fmt.Sprintf("/accessions/%d/delete", id),
+		Cancel:  fmt.Sprintf("/accessions/%d/show", id),
+		Summary: summary,
+	})
+}
+
+func DeleteAccession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
 
+	accession, err := database.FindAccession(uint(id))
+	if err != nil {
+		ThrowError(http.StatusNotFound, err.Error(), c, true)
+		return
+	}
+
+	cascade, err := isCascadeDelete(c)
+	if err != nil {
+		ThrowError(http.StatusUnauthorized, err.Error(), c, true)
+		return
+	}
+
+	if cascade {
+		_, err = database.DeleteAccessionCascade(uint(id))
+	} else {
+		err = database.DeleteAccession(uint(id))
+	}
+	if err != nil {
+		ThrowError(DeleteErrorStatus(err), err.Error(), c, true)
+		return
+	}
+
 	c.Redirect(http.StatusFound, fmt.Sprintf("/resources/%d/show", accession.ResourceID))
 }
 
@@ -369,8 +412,10 @@ func SlewAccession(c *gin.Context) {
 		"pagination":  pagination,
 		"page":        0,
 		"entries":     entries,
+		"permissions": c.MustGet(ContextKeyPermissions),
 		"isLoggedIn": true,
 		"user":        user,
+		"csrfToken":   c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -406,28 +451,23 @@ func CreateAccessionSlew(c *gin.Context) {
 
 func createSlewEntry(slew Slew, accession models.Accession) error {
 
+	resource, err := database.FindResource(uint(accession.ResourceID))
+	if err != nil {
+		return err
+	}
+
+	repository, err := database.FindRepository(uint(resource.RepositoryID))
+	if err != nil {
+		return err
+	}
+
+	entries := []models.Entry{}
 	for i := 0; i < slew.NumObjects; i++ {
 		entry := models.Entry{}
 		id, _ := uuid.NewUUID()
 		entry.ID = id
-		mediaID, err := database.FindNextMediaCollectionInResource(accession.ResourceID)
 		userID := slew.userID
 
-		if err != nil {
-			return err
-		}
-
-		resource, err := database.FindResource(uint(accession.ResourceID))
-		if err != nil {
-			return err
-		}
-
-		repository, err := database.FindRepository(uint(resource.RepositoryID))
-		if err != nil {
-			return err
-		}
-
-		entry.MediaID = mediaID
 		entry.AccessionID = accession.ID
 		entry.RepositoryID = accession.Resource.RepositoryID
 		entry.Repository = repository
@@ -440,12 +480,11 @@ func createSlewEntry(slew Slew, accession models.Accession) error {
 		entry.CreatedAt = time.Now()
 		entry.UpdatedBy = userID
 		entry.UpdatedAt = time.Now()
-
-		if err := database.InsertEntry(&entry); err != nil {
-			return err
-		}
+		entries = append(entries, entry)
 	}
-	return nil
+
+	//media ids are allocated for the whole slew at once, so a concurrent slew in the resource cannot take the same ids
+	return database.InsertEntries(accession.ResourceID, entries)
 }
 
 func AccessionGenCSV(c *gin.Context) {
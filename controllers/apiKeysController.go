@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+type APIKeyForm struct {
+	Name      string `form:"name"`
+	Scope     string `form:"scope"`
+	ExpiresAt string `form:"expires_at"`
+}
+
+// GenerateAPIKey returns a new random key and the record describing it, the key itself is only stored as a hash
+func GenerateAPIKey(userID uint, name string, scope string, expiresAt *time.Time) (string, models.APIKey, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", models.APIKey{}, err
+	}
+	key := models.APIKeyPrefix + hex.EncodeToString(b)
+
+	apiKey := models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:len(models.APIKeyPrefix)+8],
+		KeyHash:   models.HashAPIKey(key),
+		Scope:     scope,
+		ExpiresAt: expiresAt,
+	}
+
+	return key, apiKey, nil
+}
+
+func CreateAPIKey(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if uint(userID) != user.ID {
+		ThrowError(http.StatusUnauthorized, "API keys can only be created for your own account", c, true)
+		return
+	}
+
+	if !user.CanAccessAPI {
+		ThrowError(http.StatusUnauthorized, "User is not authorized to access the api", c, true)
+		return
+	}
+
+	apiKeyForm := APIKeyForm{}
+	if err := c.Bind(&apiKeyForm); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	name := strings.TrimSpace(apiKeyForm.Name)
+	if name == "" {
+		ThrowError(http.StatusBadRequest, "API key name is required", c, true)
+		return
+	}
+
+	if apiKeyForm.Scope != models.APIKeyScopeRead && apiKeyForm.Scope != models.APIKeyScopeReadWrite {
+		ThrowError(http.StatusBadRequest, fmt.Sprintf("`%s` is not a valid api key scope", apiKeyForm.Scope), c, true)
+		return
+	}
+
+	var expiresAt *time.Time
+	if apiKeyForm.ExpiresAt != "" {
+		expires, err := time.ParseInLocation("2006-01-02", apiKeyForm.ExpiresAt, time.Local)
+		if err != nil {
+			ThrowError(http.StatusBadRequest, fmt.Sprintf("`%s` is not a valid expiry date, use YYYY-MM-DD", apiKeyForm.ExpiresAt), c, true)
+			return
+		}
+		//keys expire at the end of the chosen day
+		expires = expires.AddDate(0, 0, 1).Add(-time.Second)
+		if expires.Before(time.Now()) {
+			ThrowError(http.StatusBadRequest, "API key expiry date is in the past", c, true)
+			return
+		}
+		expiresAt = &expires
+	}
+
+	key, apiKey, err := GenerateAPIKey(user.ID, name, apiKeyForm.Scope, expiresAt)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	if err := database.InsertAPIKey(&apiKey); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	renderUserShow(c, sessionCookies, user, userID, key)
+}
+
+func RevokeAPIKey(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	keyID, err := strconv.Atoi(c.Param("key_id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	apiKey, err := database.FindAPIKeyByID(uint(keyID))
+	if err != nil || apiKey.UserID != uint(userID) {
+		ThrowError(http.StatusNotFound, "API key not found", c, true)
+		return
+	}
+
+	if apiKey.UserID != user.ID && !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, "API keys can only be revoked by their owner or an admin", c, true)
+		return
+	}
+
+	if err := database.RevokeAPIKey(apiKey.ID); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/show", userID))
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const ContextKeyCSRFToken = "csrfToken"
+
+// CSRFFormField is the hidden form field that carries the token back
+const CSRFFormField = "csrf_token"
+
+var csrfToken = "csrf-token"
+
+// CSRFToken returns the token stored in the session, creating one the first time
+func CSRFToken(c *gin.Context) (string, error) {
+	session := sessions.Default(c)
+	if token, ok := session.Get(csrfToken).(string); ok && token != "" {
+		return token, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	session.Set(csrfToken, token)
+	return token, session.Save()
+}
+
+// VerifyCSRF makes the session's token available to templates and rejects web requests that change state
+// without it. A token is only issued to a request that already has a session, pages that show a form before
+// there is one, like the login page, call CSRFToken themselves. The api authenticates every request with a token
+// and is exempt.
+func VerifyCSRF(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.Next()
+		return
+	}
+
+	session := sessions.Default(c)
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if session.ID() != "" {
+			token, err := CSRFToken(c)
+			if err != nil {
+				ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+				c.Abort()
+				return
+			}
+			c.Set(ContextKeyCSRFToken, token)
+		}
+		c.Next()
+		return
+	}
+
+	token, _ := session.Get(csrfToken).(string)
+	if token == "" || subtle.ConstantTimeCompare([]byte(c.PostForm(CSRFFormField)), []byte(token)) != 1 {
+		ThrowError(http.StatusForbidden, "the form has expired, reload the page and try again", c, isLoggedIn(c) == nil)
+		c.Abort()
+		return
+	}
+	c.Set(ContextKeyCSRFToken, token)
+
+	c.Next()
+}
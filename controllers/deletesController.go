@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+// deleteConfirmation describes the object shown on the delete confirmation page
+type deleteConfirmation struct {
+	Object  string
+	Label   string
+	Action  string
+	Cancel  string
+	Summary database.DeleteSummary
+}
+
+func renderDeleteConfirmation(c *gin.Context, confirmation deleteConfirmation) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	c.HTML(http.StatusOK, "delete-confirm.html", gin.H{
+		"isAdmin":      sessionCookies.IsAdmin,
+		"isLoggedIn":   true,
+		"user":         user,
+		"confirmation": confirmation,
+		"csrfToken":    c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+// isCascadeDelete reports whether a delete form asked to remove the children too, only admins may cascade
+func isCascadeDelete(c *gin.Context) (bool, error) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	if c.PostForm("cascade") != "true" {
+		return false, nil
+	}
+	if !sessionCookies.IsAdmin {
+		return false, errors.New("Must be logged in as an admin to delete an object with its contents")
+	}
+	return true, nil
+}
+
+// DeleteErrorStatus maps an error from a delete to a response code
+func DeleteErrorStatus(err error) int {
+	var dependentsError *database.DependentsError
+	switch {
+	case errors.As(err, &dependentsError):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,11 +13,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/fixity"
 	"github.com/nyudlts/go-medialog/models"
 )
 
 const EntriesShow = "/entries/%s/show"
 
+// mediaIDTaken is shown when an entry is created with a media id its resource already uses
+const mediaIDTaken = "media id %d is already used in this resource, enter another one or leave it empty to use the next free one"
+
 func GetEntry(c *gin.Context) {
 	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
 	user := c.MustGet(ContextKeyUser).(models.User)
@@ -59,21 +64,96 @@ func GetEntry(c *gin.Context) {
 		return
 	}
 
+	revisions, err := database.FindEntryRevisions(entry.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	moves, err := database.FindEntryMoves(entry.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	accessionNums, err := database.GetAccessionsMap()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	imageFiles, err := database.FindImageFilesByEntryID(entry.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	fixityEvents, err := database.FindFixityEventsByEntryID(entry.ID, 10)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	formatProfile, err := database.FindFormatProfileByEntryID(entry.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	imagingAttempts, err := database.FindImagingAttemptsByEntryID(entry.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	revisionUserIDs := []int{}
+	for _, revision := range revisions {
+		revisionUserIDs = append(revisionUserIDs, revision.CreatedBy)
+	}
+	for _, move := range moves {
+		revisionUserIDs = append(revisionUserIDs, move.CreatedBy)
+	}
+
+	revisionUsers, err := getUserEmailMap(revisionUserIDs)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
 	c.HTML(http.StatusOK, "entries-show.html", gin.H{
 		"entry":            entry,
 		"accession":        accession,
 		"resource":         resource,
 		"repository":       repository,
 		"isAdmin":          sessionCookies.IsAdmin,
+		"permissions":      c.MustGet(ContextKeyPermissions),
 		"entryUsers":       entryUsers,
 		"isLoggedIn": true,
 		"maxMediaID":       maxMediaID,
-		"interfaces":       getInterfaces(),
+		"interfaces":       getVocabularyWithRetired(VocabularyInterfaces),
 		"hddInterfaces":    getHDDInterfaces(),
-		"imageFormats":     getImageFormats(),
+		"filesystems":      getVocabularyWithRetired(VocabularyFilesystems),
+		"encodingSchemes":  getVocabularyWithRetired(VocabularyEncodingSchemes),
+		"structures":       getVocabularyWithRetired(VocabularyStructures),
+		"imageFormats":     getVocabularyWithRetired(VocabularyImageFormats),
 		"imagingSuccess":   getImageSuccess(),
 		"interpretSuccess": getInterpretSuccess(),
 		"user":             user,
+		"revisions":        revisions,
+		"revisionUsers":    revisionUsers,
+		"moves":            moves,
+		"accessionNums":    accessionNums,
+		"imageFiles":       imageFiles,
+		"fixityEvents":     fixityEvents,
+		"imageRoots":       fixity.Roots(),
+		"formatProfile":    formatProfile,
+		"imagingAttempts":  imagingAttempts,
+		"imagingSoftware":  getVocabularyWithRetired(VocabularyImagingSoftware),
+		"interfaceOptions": getInterfaces(),
+		"softwareOptions":  getImagingSoftware(),
+		"schemeOptions":    getEncodingSchemes(),
+		"today":            time.Now().Format("2006-01-02"),
+		"csrfToken":        c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -161,7 +241,10 @@ func GetEntries(c *gin.Context) {
 		pagination.Filter = filter[0]
 	}
 
-	totalEntries := database.GetCountOfEntriesInDBPaginated(&pagination)
+	//only list entries in repositories the user can view
+	repositoryIDs := GetPermissions(c).ViewableRepositories()
+
+	totalEntries := database.GetCountOfEntriesInDBPaginated(&pagination, repositoryIDs)
 	pagination.TotalRecords = totalEntries
 	totalPages := totalEntries / int64(pagination.Limit)
 	if totalEntries%int64(pagination.Limit) > 0 {
@@ -171,7 +254,7 @@ func GetEntries(c *gin.Context) {
 	overlimit := ((pagination.Page * pagination.Limit) + pagination.Limit) > int(totalEntries)
 
 	//get entries
-	entries, err := database.FindPaginatedEntries(pagination)
+	entries, err := database.FindPaginatedEntries(pagination, repositoryIDs)
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
@@ -194,7 +277,7 @@ func GetEntries(c *gin.Context) {
 		"user":          user,
 		"overlimit":     overlimit,
 		"limitValues":   LimitValues,
-		"mediatypes":    GetMediatypes(),
+		"mediatypes":    getVocabularyWithRetired(VocabularyMediatypes),
 	})
 }
 
@@ -251,10 +334,14 @@ func NewEntry(c *gin.Context) {
 		"interpretation_success": getInterpretSuccess(),
 		"imaging_software":       getImagingSoftware(),
 		"image_formats":          getImageFormats(),
+		"filesystems":            getFilesystems(),
+		"encoding_schemes":       getEncodingSchemes(),
+		"structures":             getStructures(),
 		"media_id":               mediaID,
 		"is_refreshed":           is_refreshed,
 		"isLoggedIn": true,
 		"user":                   user,
+		"csrfToken":              c.MustGet(ContextKeyCSRFToken),
 	})
 
 }
@@ -267,8 +354,20 @@ func CreateEntry(c *gin.Context) {
 		return
 	}
 
+	//an entry without a media id is given the next free one in its resource when it is saved
+	nextMediaID := createEntry.MediaID == 0
+
 	//validate the form
-	if err := createEntry.ValidateEntry(); err != nil {
+	validated := createEntry
+	if nextMediaID {
+		validated.MediaID = 1
+	}
+	if err := validated.ValidateEntry(); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if err := ValidateTechnicalMetadata(createEntry); err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
@@ -278,15 +377,17 @@ func CreateEntry(c *gin.Context) {
 	createEntry.Location = "sl_not_imaged"
 
 	//check if media id is unique
-	b, err := database.IsMediaIDUniqueInResource(createEntry.MediaID, createEntry.ResourceID)
-	if err != nil {
-		ThrowError(http.StatusBadRequest, err.Error(), c, true)
-		return
-	}
+	if !nextMediaID {
+		b, err := database.IsMediaIDUniqueInResource(createEntry.MediaID, createEntry.ResourceID)
+		if err != nil {
+			ThrowError(http.StatusBadRequest, err.Error(), c, true)
+			return
+		}
 
-	if !b {
-		ThrowError(http.StatusBadRequest, fmt.Sprintf("%d is not a unique ID in resource %d", createEntry.MediaID, createEntry.ResourceID), c, true)
-		return
+		if !b {
+			ThrowError(http.StatusConflict, fmt.Sprintf(mediaIDTaken, createEntry.MediaID), c, true)
+			return
+		}
 	}
 
 	//get the user's id
@@ -330,7 +431,16 @@ func CreateEntry(c *gin.Context) {
 	createEntry.Repository = repository
 
 	//insert the entry
-	if err := database.InsertEntry(&createEntry); err != nil {
+	insertEntry := database.InsertEntry
+	if nextMediaID {
+		insertEntry = database.InsertEntryWithNextMediaID
+	}
+	if err := insertEntry(&createEntry); err != nil {
+		var takenError *database.MediaIDTakenError
+		if errors.As(err, &takenError) {
+			ThrowError(http.StatusConflict, fmt.Sprintf(mediaIDTaken, takenError.MediaID), c, true)
+			return
+		}
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
@@ -339,6 +449,27 @@ func CreateEntry(c *gin.Context) {
 	c.Redirect(http.StatusFound, fmt.Sprintf("entries/%s/show", createEntry.ID.String()))
 }
 
+func ConfirmDeleteEntry(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	entry, err := database.FindEntry(id)
+	if err != nil {
+		ThrowError(http.StatusNotFound, err.Error(), c, true)
+		return
+	}
+
+	renderDeleteConfirmation(c, deleteConfirmation{
+		Object: "entry",
+		Label:  fmt.Sprintf("%s %d", entry.Resource.CollectionCode, entry.MediaID),
+		Action: fmt.Sprintf("/entries/%s/delete", id),
+		Cancel: fmt.Sprintf("/entries/%s/show", id),
+	})
+}
+
 func DeleteEntry(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -362,9 +493,6 @@ func DeleteEntry(c *gin.Context) {
 }
 
 func EditEntry(c *gin.Context) {
-	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
-	user := c.MustGet(ContextKeyUser).(models.User)
-
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -377,6 +505,14 @@ func EditEntry(c *gin.Context) {
 		return
 	}
 
+	renderEditEntry(c, entry, nil)
+}
+
+// renderEditEntry shows the entry form, with the values proposed from an imaging log listed when there are any
+func renderEditEntry(c *gin.Context, entry models.Entry, proposal *ImagingLogProposal) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
 	accession, err := database.FindAccession(entry.AccessionID)
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -401,17 +537,25 @@ func EditEntry(c *gin.Context) {
 		"accession":              accession,
 		"resource":               resource,
 		"repository":             repository,
-		"interfaces":             getInterfaces(),
-		"stock_units":            getStockUnits(),
+		"mediatypes":             vocabularyOptions(VocabularyMediatypes, entry.Mediatype),
+		"interfaces":             vocabularyOptions(VocabularyInterfaces, entry.Interface),
+		"stock_units":            vocabularyOptions(VocabularyStockUnits, entry.StockUnit),
 		"optical_content_types":  getOpticalContentTypes(),
 		"hdd_interfaces":         getHDDInterfaces(),
 		"imaging_success":        getImageSuccess(),
 		"interpretation_success": getInterpretSuccess(),
-		"imaging_software":       getImagingSoftware(),
-		"image_formats":          getImageFormats(),
+		"imaging_software":       vocabularyOptions(VocabularyImagingSoftware, entry.ImagingSoftware),
+		"image_formats":          vocabularyOptions(VocabularyImageFormats, entry.ImageFormat),
+		"storage_locations":      vocabularyOptions(VocabularyStorageLocations, entry.Location),
+		"entry_statuses":         vocabularyOptions(VocabularyEntryStatuses, entry.Status),
+		"filesystems":            vocabularyListOptions(VocabularyFilesystems, entry.Filesystems),
+		"encoding_schemes":       vocabularyOptions(VocabularyEncodingSchemes, entry.EncodingScheme),
+		"structures":             vocabularyOptions(VocabularyStructures, entry.Structure),
 		"is_refreshed":           is_refreshed,
+		"proposal":               proposal,
 		"isLoggedIn": true,
 		"user":                   user,
+		"csrfToken":              c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -439,6 +583,11 @@ func UpdateEntry(c *gin.Context) {
 		return
 	}
 
+	if err := ValidateTechnicalMetadata(editedEntry); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
 	//find the original entry
 	entry, err := database.FindEntry(id)
 	if err != nil {
@@ -480,12 +629,6 @@ func CloneEntry(c *gin.Context) {
 		return
 	}
 
-	nextID, err := database.FindNextMediaCollectionInResource(uint(entry.ResourceID))
-	if err != nil {
-		ThrowError(http.StatusBadRequest, err.Error(), c, true)
-		return
-	}
-
 	//generate a new uuid
 	newUUID, err := uuid.NewUUID()
 	if err != nil {
@@ -519,7 +662,6 @@ func CloneEntry(c *gin.Context) {
 
 	entry.ID = newUUID
 	entry.LabelText = ""
-	entry.MediaID = nextID
 	entry.CreatedAt = time.Now()
 	entry.CreatedBy = userID
 	entry.UpdatedAt = time.Now()
@@ -531,7 +673,8 @@ func CloneEntry(c *gin.Context) {
 	entry.RepositoryID = repository.ID
 	entry.Repository = repository
 
-	if err := database.InsertEntry(&entry); err != nil {
+	//the media id is allocated when the clone is inserted, so concurrent clones in a resource get distinct ids
+	if err := database.InsertEntryWithNextMediaID(&entry); err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
@@ -564,7 +707,7 @@ func FindEntry(c *gin.Context) {
 
 func EntriesGenCSV(c *gin.Context) {
 	//find the entries
-	entries, err := database.FindEntriesFiltered(c.Request.URL.Query()["filter"][0])
+	entries, err := database.FindEntriesFiltered(c.Query("filter"), GetPermissions(c).ViewableRepositories())
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
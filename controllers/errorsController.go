@@ -35,7 +35,10 @@ func ThrowError(code int, msg string, c *gin.Context, loggedIn bool) {
 		"isAdmin":    user.IsAdmin,
 		"user":       user,
 	})
-	session.Save()
+	//a visitor without a session is not given one just to show an error
+	if session.ID() != "" {
+		session.Save()
+	}
 }
 
 func TestError(c *gin.Context) {
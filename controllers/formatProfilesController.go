@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/imaging"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// maxFormatReportSize caps uploaded reports, the DFXML of a large hard drive runs to tens of megabytes
+const maxFormatReportSize = 256 << 20
+
+// AttachFormatReport parses a DFXML, Siegfried or DROID report and merges it into the entry's format profile.
+// File count and size come from the latest report, filesystems from DFXML and formats from Siegfried or DROID.
+func AttachFormatReport(entryID uuid.UUID, r io.Reader, userID int) (models.FormatProfile, error) {
+	if _, err := database.FindEntry(entryID); err != nil {
+		return models.FormatProfile{}, fmt.Errorf("entry %s: %w", entryID, err)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxFormatReportSize+1))
+	if err != nil {
+		return models.FormatProfile{}, err
+	}
+	if len(data) > maxFormatReportSize {
+		return models.FormatProfile{}, fmt.Errorf("reports are limited to %d bytes", maxFormatReportSize)
+	}
+
+	report, err := imaging.ParseFormatReport(data)
+	if err != nil {
+		return models.FormatProfile{}, err
+	}
+
+	profile, err := database.FindFormatProfileByEntryID(entryID)
+	if err != nil {
+		return profile, err
+	}
+
+	profile.FileCount = report.FileCount
+	profile.TotalBytes = report.TotalBytes
+	if report.Kind == imaging.ReportDFXML {
+		profile.Filesystems = report.Filesystems
+	} else {
+		profile.Formats = []models.FormatCount{}
+		for _, format := range report.Formats {
+			profile.Formats = append(profile.Formats, models.FormatCount{
+				PUID:    format.PUID,
+				Format:  format.Format,
+				Version: format.Version,
+				Count:   format.Count,
+				Bytes:   format.Bytes,
+			})
+		}
+	}
+	if !slices.Contains(profile.Reports, report.Kind) {
+		profile.Reports = append(profile.Reports, report.Kind)
+	}
+	profile.UpdatedAt = time.Now()
+	profile.UpdatedBy = userID
+
+	if err := database.SaveFormatProfile(&profile); err != nil {
+		return profile, err
+	}
+	return database.FindFormatProfileByEntryID(entryID)
+}
+
+func UploadFormatReport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	file, err := c.FormFile("report")
+	if err != nil {
+		ThrowError(http.StatusBadRequest, "no report was uploaded", c, true)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+	defer f.Close()
+
+	if _, err := AttachFormatReport(id, f, userID); err != nil {
+		ThrowError(http.StatusBadRequest, fmt.Sprintf("%s: %s", file.Filename, err.Error()), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf(EntriesShow, id.String()))
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/fixity"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// RegisterImageFiles hashes every file below a directory and registers it against an entry
+func RegisterImageFiles(entryID uuid.UUID, dir string, userID int) ([]models.ImageFile, error) {
+	if _, err := database.FindEntry(entryID); err != nil {
+		return []models.ImageFile{}, fmt.Errorf("entry %s: %w", entryID, err)
+	}
+
+	paths, err := fixity.ListFiles(dir)
+	if err != nil {
+		return []models.ImageFile{}, err
+	}
+	if len(paths) == 0 {
+		return []models.ImageFile{}, fmt.Errorf("no files found in %s", dir)
+	}
+
+	imageFiles := []models.ImageFile{}
+	for _, path := range paths {
+		checksums, err := fixity.HashFile(path)
+		if err != nil {
+			return imageFiles, err
+		}
+
+		imageFile := models.ImageFile{
+			EntryID:      entryID,
+			Path:         path,
+			Size:         checksums.Size,
+			MD5:          checksums.MD5,
+			SHA256:       checksums.SHA256,
+			Status:       models.ImageFileUnchecked,
+			RegisteredAt: time.Now(),
+			RegisteredBy: userID,
+		}
+		if err := database.RegisterImageFile(&imageFile); err != nil {
+			return imageFiles, err
+		}
+		imageFiles = append(imageFiles, imageFile)
+	}
+	return imageFiles, nil
+}
+
+// CheckImageFile re-hashes a file, compares it with its registered checksums and records the outcome
+func CheckImageFile(imageFile *models.ImageFile) (models.FixityEvent, error) {
+	status := models.ImageFileOK
+	detail := "checksums match"
+
+	checksums, err := fixity.HashFile(imageFile.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		status = models.ImageFileMissing
+		detail = "file not found"
+	case err != nil:
+		status = models.ImageFileMissing
+		detail = err.Error()
+	case checksums.Size != imageFile.Size:
+		status = models.ImageFileChanged
+		detail = fmt.Sprintf("size changed from %d to %d bytes", imageFile.Size, checksums.Size)
+	case !checksums.Matches(fixity.Checksums{Size: imageFile.Size, MD5: imageFile.MD5, SHA256: imageFile.SHA256}):
+		status = models.ImageFileChanged
+		detail = fmt.Sprintf("sha256 changed from %s to %s", imageFile.SHA256, checksums.SHA256)
+	}
+
+	return database.RecordFixityEvent(imageFile, status, detail)
+}
+
+// FixitySummary counts the outcomes of a fixity check run
+type FixitySummary struct {
+	Checked int `json:"checked"`
+	Passed  int `json:"passed"`
+	Missing int `json:"missing"`
+	Changed int `json:"changed"`
+}
+
+func (s FixitySummary) String() string {
+	return fmt.Sprintf("%d image files checked, %d passed, %d missing, %d changed", s.Checked, s.Passed, s.Missing, s.Changed)
+}
+
+// CheckFixity re-hashes every registered image file and records a fixity event for each
+func CheckFixity() (FixitySummary, error) {
+	summary := FixitySummary{}
+	imageFiles, err := database.FindImageFiles()
+	if err != nil {
+		return summary, err
+	}
+
+	for i := range imageFiles {
+		if _, err := CheckImageFile(&imageFiles[i]); err != nil {
+			return summary, err
+		}
+		summary.Checked++
+		switch imageFiles[i].Status {
+		case models.ImageFileOK:
+			summary.Passed++
+		case models.ImageFileMissing:
+			summary.Missing++
+		case models.ImageFileChanged:
+			summary.Changed++
+		}
+	}
+	return summary, nil
+}
+
+func RegisterEntryImageFiles(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	dir := strings.TrimSpace(c.PostForm("path"))
+	if !fixity.InRoots(dir) {
+		ThrowError(http.StatusBadRequest, fmt.Sprintf("`%s` is not inside a configured image root", dir), c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	if _, err := RegisterImageFiles(id, dir, userID); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf(EntriesShow, id.String()))
+}
+
+func CheckEntryFixity(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	imageFiles, err := database.FindImageFilesByEntryID(id)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	for i := range imageFiles {
+		if _, err := CheckImageFile(&imageFiles[i]); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+			return
+		}
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf(EntriesShow, id.String()))
+}
+
+func ReportsFixity(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	problems, err := database.FindImageFileProblems(GetPermissions(c).ViewableRepositories())
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	c.HTML(http.StatusOK, "reports-fixity.html", gin.H{
+		"problems":   problems,
+		"isLoggedIn": true,
+		"isAdmin":    sessionCookies.IsAdmin,
+		"user":       user,
+	})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// ValidateImagingAttempt checks an attempt's result and that its interface, software and encoding scheme are current vocabulary terms
+func ValidateImagingAttempt(attempt models.ImagingAttempt) error {
+	if attempt.ImagingSuccess != models.ImageSuccessYes && attempt.ImagingSuccess != models.ImageSuccessNo {
+		return fmt.Errorf("imaging_success must be %s or %s", models.ImageSuccessYes, models.ImageSuccessNo)
+	}
+	if attempt.Interface != "" && !IsActiveTerm(VocabularyInterfaces, attempt.Interface) {
+		return fmt.Errorf("`%s` is not an interface", attempt.Interface)
+	}
+	if attempt.ImagingSoftware != "" && !IsActiveTerm(VocabularyImagingSoftware, attempt.ImagingSoftware) {
+		return fmt.Errorf("`%s` is not an imaging software", attempt.ImagingSoftware)
+	}
+	if attempt.EncodingScheme != "" && !IsActiveTerm(VocabularyEncodingSchemes, attempt.EncodingScheme) {
+		return fmt.Errorf("`%s` is not an encoding scheme", attempt.EncodingScheme)
+	}
+	if attempt.AttemptedAt.After(time.Now().Add(24 * time.Hour)) {
+		return fmt.Errorf("attempted_at is in the future")
+	}
+	return nil
+}
+
+// RecordImagingAttempt validates and saves an attempt, the entry's imaging fields are derived from its attempts
+func RecordImagingAttempt(entryID uuid.UUID, attempt models.ImagingAttempt, userID int) (models.ImagingAttempt, error) {
+	if _, err := database.FindEntry(entryID); err != nil {
+		return attempt, fmt.Errorf("entry %s: %w", entryID, err)
+	}
+
+	attempt.ID = 0
+	attempt.EntryID = entryID
+	attempt.CreatedBy = userID
+	attempt.CreatedAt = time.Now()
+	if attempt.AttemptedAt.IsZero() {
+		attempt.AttemptedAt = attempt.CreatedAt
+	}
+	if err := ValidateImagingAttempt(attempt); err != nil {
+		return attempt, err
+	}
+
+	if err := database.InsertImagingAttempt(&attempt); err != nil {
+		return attempt, err
+	}
+	return attempt, nil
+}
+
+func CreateImagingAttempt(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	attempt := models.ImagingAttempt{}
+	if err := c.Bind(&attempt); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if date := strings.TrimSpace(c.PostForm("attempted_at")); date != "" {
+		attempt.AttemptedAt, err = time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			ThrowError(http.StatusBadRequest, fmt.Sprintf("attempted_at `%s` is not a date", date), c, true)
+			return
+		}
+		//attempts on the same day keep the order they were recorded in
+		now := time.Now()
+		attempt.AttemptedAt = attempt.AttemptedAt.Add(time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second)
+	}
+
+	if _, err := RecordImagingAttempt(id, attempt, userID); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf(EntriesShow, id.String()))
+}
+
+func DeleteImagingAttempt(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	attemptID, err := strconv.Atoi(c.Param("attempt_id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	if err := database.DeleteImagingAttempt(id, uint(attemptID), userID); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf(EntriesShow, id.String()))
+}
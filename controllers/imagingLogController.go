@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/imaging"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// maxImagingLogSize caps uploaded imaging logs, FTK Imager and KryoFlux logs are a few kilobytes
+const maxImagingLogSize = 4 << 20
+
+// ProposedValue is a value read from an imaging log for one of an entry's fields
+type ProposedValue struct {
+	Field         string `json:"field"`
+	Current       string `json:"current"`
+	CurrentLabel  string `json:"current_label"`
+	Proposed      string `json:"proposed"`
+	ProposedLabel string `json:"proposed_label"`
+}
+
+// ImagingLogProposal holds the values proposed for an entry from an imaging log, nothing is saved until the technician submits the entry form
+type ImagingLogProposal struct {
+	Log      imaging.Log     `json:"log"`
+	Values   []ProposedValue `json:"values"`
+	Warnings []string        `json:"warnings"`
+}
+
+// propose records a value that differs from the entry's, label resolves terms to their labels
+func (p *ImagingLogProposal) propose(field string, current string, proposed string, label func(string) string) {
+	if proposed == "" || proposed == current {
+		return
+	}
+	p.Values = append(p.Values, ProposedValue{Field: field, Current: current, CurrentLabel: label(current), Proposed: proposed, ProposedLabel: label(proposed)})
+}
+
+func termLabel(vocabulary string) func(string) string {
+	return func(key string) string {
+		label, _ := lookupTerm(vocabulary, key)
+		return label
+	}
+}
+
+func sameLabel(value string) string { return value }
+
+// Apply sets the proposed values on an entry
+func (p ImagingLogProposal) Apply(entry *models.Entry) {
+	for _, value := range p.Values {
+		switch value.Field {
+		case "imaging_software":
+			entry.ImagingSoftware = value.Proposed
+		case "image_format":
+			entry.ImageFormat = value.Proposed
+		case "image_filename":
+			entry.ImageFilename = value.Proposed
+		case "imaging_success":
+			entry.ImagingSuccess = value.Proposed
+		case "interface":
+			entry.Interface = value.Proposed
+		case "imaging_note":
+			entry.ImagingNote = value.Proposed
+		}
+	}
+}
+
+// ProposeFromImagingLog maps what was read from an imaging log to vocabulary terms and compares them with the entry
+func ProposeFromImagingLog(entry models.Entry, log imaging.Log) ImagingLogProposal {
+	proposal := ImagingLogProposal{Log: log, Values: []ProposedValue{}, Warnings: []string{}}
+
+	if log.Version != "" {
+		if software, ok := imagingSoftwareTerm(log.Software, log.Version); ok {
+			proposal.propose("imaging_software", entry.ImagingSoftware, software, termLabel(VocabularyImagingSoftware))
+		} else {
+			proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("%s %s is not in the imaging software vocabulary", log.Software, log.Version))
+		}
+	}
+
+	if log.ImageFilename != "" {
+		proposal.propose("image_filename", entry.ImageFilename, log.ImageFilename, sameLabel)
+		if format, ok := imageFormatForFile(log.ImageFilename); ok && IsActiveTerm(VocabularyImageFormats, format) {
+			proposal.propose("image_format", entry.ImageFormat, format, termLabel(VocabularyImageFormats))
+		} else {
+			proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("no image format matches %s", log.ImageFilename))
+		}
+	}
+
+	if log.Verified != nil {
+		success := "image_success_no"
+		if *log.Verified {
+			success = "image_success_yes"
+		}
+		proposal.propose("imaging_success", entry.ImagingSuccess, success, func(key string) string { return image_success[key] })
+	}
+	if log.BadTracks > 0 {
+		proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("%d of %d tracks were not read cleanly", log.BadTracks, log.GoodTracks+log.BadTracks))
+	}
+
+	if iface := interfaceTerm(log); iface != "" && IsActiveTerm(VocabularyInterfaces, iface) {
+		proposal.propose("interface", entry.Interface, iface, getInterface)
+	}
+
+	//hashes and size have no fields of their own and are added to the imaging note
+	if summary := imagingLogSummary(log); summary != "" && !strings.Contains(entry.ImagingNote, summary) {
+		note := summary
+		if entry.ImagingNote != "" {
+			note = entry.ImagingNote + "; " + summary
+		}
+		proposal.propose("imaging_note", entry.ImagingNote, note, sameLabel)
+	}
+
+	return proposal
+}
+
+// imagingSoftwareTerm finds the imaging software term for a version, FTK Imager terms carry the full version
+// and KryoFlux terms the DTC version without trailing zeros, so 3.00 is v30 and 3.50 is v35
+func imagingSoftwareTerm(software string, version string) (string, bool) {
+	digits := strings.ReplaceAll(version, ".", "")
+	prefix := ""
+	switch software {
+	case imaging.SoftwareFTKImager:
+		prefix = "imaging_software_ftk_imager_v"
+	case imaging.SoftwareKryoFlux:
+		prefix = "imaging_software_kryoflux_imager_v"
+	default:
+		return "", false
+	}
+
+	for {
+		if IsActiveTerm(VocabularyImagingSoftware, prefix+digits) {
+			return prefix + digits, true
+		}
+		if len(digits) < 3 || !strings.HasSuffix(digits, "0") {
+			return "", false
+		}
+		digits = strings.TrimSuffix(digits, "0")
+	}
+}
+
+// interfaceTerm guesses the interface from the software or the drive model reported by FTK Imager
+func interfaceTerm(log imaging.Log) string {
+	if log.Software == imaging.SoftwareKryoFlux {
+		return "interface_kryoflux"
+	}
+
+	drive := strings.ToLower(log.DriveModel + " " + log.SourceType)
+	switch {
+	case strings.Contains(drive, "ultrabay"):
+		return "interface_tableau_ultrabay"
+	case strings.Contains(drive, "t8"):
+		return "interface_tableau_t8r2"
+	case strings.Contains(drive, "card reader"), strings.Contains(drive, "ultrablock") && strings.Contains(drive, "card"):
+		return "interface_tableau_ultrablock_card"
+	case strings.Contains(drive, "ultrablock"):
+		return "interface_tableau_ultrablock"
+	case strings.Contains(drive, "cd"), strings.Contains(drive, "dvd"), strings.Contains(drive, "optical"):
+		return "interface_optical_HP"
+	}
+	return ""
+}
+
+// imagingLogSummary describes the size and hashes in a log for the imaging note
+func imagingLogSummary(log imaging.Log) string {
+	parts := []string{}
+	if log.ImageSize > 0 {
+		parts = append(parts, fmt.Sprintf("%d bytes", log.ImageSize))
+	}
+	if log.MD5 != "" {
+		parts = append(parts, "MD5 "+log.MD5)
+	}
+	if log.SHA1 != "" {
+		parts = append(parts, "SHA1 "+log.SHA1)
+	}
+	if log.SHA256 != "" {
+		parts = append(parts, "SHA256 "+log.SHA256)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s log: %s", log.Software, strings.Join(parts, ", "))
+}
+
+// ReadImagingLog parses an uploaded imaging log and proposes values for an entry
+func ReadImagingLog(entry models.Entry, r io.Reader) (ImagingLogProposal, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImagingLogSize+1))
+	if err != nil {
+		return ImagingLogProposal{}, err
+	}
+	if len(data) > maxImagingLogSize {
+		return ImagingLogProposal{}, fmt.Errorf("imaging logs are limited to %d bytes", maxImagingLogSize)
+	}
+
+	log, err := imaging.Parse(data)
+	if err != nil {
+		return ImagingLogProposal{}, err
+	}
+	return ProposeFromImagingLog(entry, log), nil
+}
+
+// UploadImagingLog shows the entry form with the values proposed from an uploaded imaging log filled in
+func UploadImagingLog(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	entry, err := database.FindEntry(id)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	file, err := c.FormFile("log")
+	if err != nil {
+		ThrowError(http.StatusBadRequest, "no imaging log was uploaded", c, true)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+	defer f.Close()
+
+	proposal, err := ReadImagingLog(entry, f)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, fmt.Sprintf("%s: %s", file.Filename, err.Error()), c, true)
+		return
+	}
+
+	proposal.Apply(&entry)
+	renderEditEntry(c, entry, &proposal)
+}
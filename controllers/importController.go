@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// importColumns maps the accepted csv headers to a setter on the entry, headers match the entry json names
+var importColumns = map[string]func(*models.Entry, string) error{
+	"mediatype": func(e *models.Entry, v string) error {
+		return setImportTerm(&e.Mediatype, VocabularyMediatypes, v)
+	},
+	"stock_size_num": func(e *models.Entry, v string) error {
+		if v == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return fmt.Errorf("stock size number: `%s` is not a number", v)
+		}
+		e.StockSizeNum = float32(f)
+		return nil
+	},
+	"stock_unit": func(e *models.Entry, v string) error {
+		return setImportTerm(&e.StockUnit, VocabularyStockUnits, v)
+	},
+	"status": func(e *models.Entry, v string) error {
+		return setImportTerm(&e.Status, VocabularyEntryStatuses, v)
+	},
+	"location": func(e *models.Entry, v string) error {
+		return setImportTerm(&e.Location, VocabularyStorageLocations, v)
+	},
+	"box_number":          func(e *models.Entry, v string) error { e.BoxNumber = v; return nil },
+	"label_text":          func(e *models.Entry, v string) error { e.LabelText = v; return nil },
+	"manufacturer":        func(e *models.Entry, v string) error { e.Manufacturer = v; return nil },
+	"manufacturer_serial": func(e *models.Entry, v string) error { e.ManufacturerSerial = v; return nil },
+	"media_note":          func(e *models.Entry, v string) error { e.MediaNote = v; return nil },
+	"original_id":         func(e *models.Entry, v string) error { e.OriginalID = v; return nil },
+	"content_type":        func(e *models.Entry, v string) error { e.ContentType = v; return nil },
+	"disposition_note":    func(e *models.Entry, v string) error { e.DispositionNote = v; return nil },
+}
+
+// setImportTerm accepts either a vocabulary key or its label, matched case-insensitively, and stores the key
+func setImportTerm(field *string, vocabulary string, value string) error {
+	if value == "" {
+		return nil
+	}
+	if IsActiveTerm(vocabulary, value) {
+		*field = value
+		return nil
+	}
+	for key, label := range getVocabulary(vocabulary) {
+		if key != "" && strings.EqualFold(label, value) {
+			*field = key
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: `%s` is not a valid term", vocabularyTitles[vocabulary], value)
+}
+
+type EntryImportRow struct {
+	Row    int          `json:"row"`
+	Entry  models.Entry `json:"entry"`
+	Errors []string     `json:"errors"`
+}
+
+type EntryImportReport struct {
+	AccessionID uint             `json:"accession_id"`
+	Columns     []string         `json:"columns"`
+	Rows        []EntryImportRow `json:"rows"`
+	Valid       int              `json:"valid"`
+	Invalid     int              `json:"invalid"`
+	Committed   bool             `json:"committed"`
+}
+
+func (r EntryImportReport) HasErrors() bool { return r.Invalid > 0 }
+
+// ParseEntryImport reads a csv of entries for an accession and validates every row, media ids are provisional until the import is committed
+func ParseEntryImport(csvBytes []byte, accession models.Accession, userID int) (EntryImportReport, error) {
+	report := EntryImportReport{AccessionID: accession.ID, Rows: []EntryImportRow{}}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(csvBytes, []byte("\xef\xbb\xbf"))))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return report, fmt.Errorf("the csv is empty")
+	} else if err != nil {
+		return report, err
+	}
+
+	for _, h := range header {
+		column := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(h)), " ", "_")
+		if _, ok := importColumns[column]; !ok {
+			return report, fmt.Errorf("unsupported column `%s`", h)
+		}
+		report.Columns = append(report.Columns, column)
+	}
+
+	nextMediaID, err := database.FindNextMediaCollectionInResource(accession.ResourceID)
+	if err != nil {
+		return report, err
+	}
+
+	for rowNum := 2; ; rowNum++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := EntryImportRow{Row: rowNum, Errors: []string{}}
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+			report.Rows = append(report.Rows, row)
+			report.Invalid++
+			continue
+		}
+
+		entry := models.Entry{
+			Status:       "es_to_be_processed",
+			Location:     "sl_not_imaged",
+			RepositoryID: accession.Resource.RepositoryID,
+			ResourceID:   accession.ResourceID,
+			AccessionID:  accession.ID,
+			CreatedBy:    userID,
+			UpdatedBy:    userID,
+		}
+
+		for i, value := range record {
+			if err := importColumns[report.Columns[i]](&entry, strings.TrimSpace(value)); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+
+		entry.ID, _ = uuid.NewUUID()
+		entry.MediaID = nextMediaID + uint(report.Valid)
+		if err := entry.ValidateEntry(); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+
+		row.Entry = entry
+		if len(row.Errors) > 0 {
+			row.Entry.MediaID = 0
+			report.Invalid++
+		} else {
+			report.Valid++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	if len(report.Rows) == 0 {
+		return report, fmt.Errorf("the csv has no rows")
+	}
+
+	return report, nil
+}
+
+// CommitEntryImport inserts every row of a validated import in one transaction
+func CommitEntryImport(report *EntryImportReport, resourceID uint) error {
+	if report.HasErrors() {
+		return fmt.Errorf("the import has %d invalid rows", report.Invalid)
+	}
+
+	now := time.Now()
+	entries := []models.Entry{}
+	for _, row := range report.Rows {
+		entry := row.Entry
+		entry.CreatedAt = now
+		entry.UpdatedAt = now
+		entries = append(entries, entry)
+	}
+
+	if err := database.InsertEntries(resourceID, entries); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		report.Rows[i].Entry = entries[i]
+	}
+	report.Committed = true
+	return nil
+}
+
+func ImportAccessionEntries(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	accession, err := database.FindAccession(uint(id))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	repository, err := database.FindRepository(accession.Resource.RepositoryID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	c.HTML(http.StatusOK, "accessions-import.html", gin.H{
+		"isAdmin":    sessionCookies.IsAdmin,
+		"accession":  accession,
+		"repository": repository,
+		"columns":    importColumns,
+		"isLoggedIn": true,
+		"user":       user,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+// CreateAccessionImport validates an uploaded csv and shows the dry-run report, the csv is resubmitted with commit=true to insert the entries
+func CreateAccessionImport(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	accession, err := database.FindAccession(uint(id))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	repository, err := database.FindRepository(accession.Resource.RepositoryID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	csvBytes, err := readImportForm(c)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	report, err := ParseEntryImport(csvBytes, accession, userID)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if c.PostForm("commit") == "true" && !report.HasErrors() {
+		if err := CommitEntryImport(&report, accession.ResourceID); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+			return
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf(AccessionsShow, accession.ID))
+		return
+	}
+
+	c.HTML(http.StatusOK, "accessions-import-report.html", gin.H{
+		"isAdmin":    sessionCookies.IsAdmin,
+		"accession":  accession,
+		"repository": repository,
+		"report":     report,
+		"csv":        string(csvBytes),
+		"isLoggedIn": true,
+		"user":       user,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+// readImportForm returns the uploaded csv file, or the csv text posted back from the dry-run report
+func readImportForm(c *gin.Context) ([]byte, error) {
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+
+	if csvText := c.PostForm("csv"); csvText != "" {
+		return []byte(csvText), nil
+	}
+
+	return nil, fmt.Errorf("no csv file provided")
+}
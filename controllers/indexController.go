@@ -13,15 +13,18 @@ func GetIndex(c *gin.Context) {
 	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
 	user := c.MustGet(ContextKeyUser).(models.User)
 
+	//only list entries in repositories the user can view
+	repositoryIDs := GetPermissions(c).ViewableRepositories()
+
 	pagination := database.Pagination{Limit: 10, Offset: 0, Sort: "updated_at desc", Page: 0}
-	pagination.TotalRecords = database.GetCountOfEntriesInDB()
+	pagination.TotalRecords = database.GetCountOfEntriesInRepositories(repositoryIDs)
 	totalPages := pagination.TotalRecords / int64(pagination.Limit)
 	if pagination.TotalRecords%int64(pagination.Limit) > 0 {
 		totalPages++
 	}
 	pagination.TotalPages = int(totalPages)
 
-	entries, err := database.FindPaginatedEntries(pagination)
+	entries, err := database.FindPaginatedEntries(pagination, repositoryIDs)
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
@@ -41,7 +44,7 @@ func GetIndex(c *gin.Context) {
 		"isLoggedIn":    true,
 		"user":          user,
 		"limitValues":   LimitValues,
-		"mediatypes":    GetMediatypes(),
+		"mediatypes":    getVocabularyWithRetired(VocabularyMediatypes),
 	})
 }
 
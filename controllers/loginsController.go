@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+const (
+	LoginViaWeb = "web"
+	LoginViaAPI = "api"
+)
+
+// loginConfig sets when accounts and addresses are locked out after failed logins
+var loginConfig = models.LoginConfig{}
+
+func SetLoginConfig(config models.LoginConfig) { loginConfig = config }
+
+// passwordPolicy is checked whenever a password is set
+var passwordPolicy = models.PasswordPolicy{}
+
+func SetPasswordPolicy(policy models.PasswordPolicy) { passwordPolicy = policy }
+
+func GetPasswordPolicy() models.PasswordPolicy { return passwordPolicy }
+
+// loginFailed is returned to anyone whose email or password was wrong, it does not say which
+const loginFailed = "invalid email or password"
+
+// CheckLogin verifies an email and password sent from an address. Every failed attempt is recorded, an address with
+// too many recent failures is refused and an account is locked after too many wrong passwords in a row. A locked
+// account is refused like an unknown email so the response does not tell which accounts exist, the lock is only
+// shown in the log and to administrators. On failure it returns the http status and an error that is safe to show.
+func CheckLogin(email string, password string, ipAddress string, via string) (models.User, int, error) {
+	attempt := models.LoginAttempt{Email: email, IPAddress: ipAddress, Via: via}
+
+	failures, err := database.CountFailedLoginsByIP(ipAddress, time.Now().Add(-loginConfig.Lockout()))
+	if err != nil {
+		return models.User{}, http.StatusInternalServerError, err
+	}
+	if failures >= int64(loginConfig.AddressMaxFailures()) {
+		recordLogin(attempt, "address throttled")
+		return models.User{}, http.StatusTooManyRequests, fmt.Errorf("too many failed logins from your address, try again later")
+	}
+
+	user, err := database.FindUserByEmail(email)
+	if err != nil {
+		recordLogin(attempt, "unknown email")
+		return models.User{}, http.StatusUnauthorized, fmt.Errorf(loginFailed)
+	}
+	attempt.UserID = user.ID
+
+	if user.IsLocked() {
+		recordLogin(attempt, "account locked")
+		return models.User{}, http.StatusUnauthorized, fmt.Errorf(loginFailed)
+	}
+
+	passwordOK, err := CheckPassword(&user, password)
+	if err != nil {
+		return models.User{}, http.StatusInternalServerError, err
+	}
+
+	if !passwordOK {
+		if _, err := recordLoginFailure(user, ipAddress, via, "wrong password"); err != nil {
+			return models.User{}, http.StatusInternalServerError, err
+		}
+		return models.User{}, http.StatusUnauthorized, fmt.Errorf(loginFailed)
+	}
+
+	if !user.IsActive {
+		recordLogin(attempt, "inactive")
+		return models.User{}, http.StatusUnauthorized, fmt.Errorf("User %s is not active, contact a system administrator", user.Email)
+	}
+
+	return user, http.StatusOK, nil
+}
+
+// RecordLoginSuccess records a completed login and clears the user's failed logins, it is called once every
+// factor was checked
+func RecordLoginSuccess(user *models.User, ipAddress string, via string) error {
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := database.UnlockUser(user.ID); err != nil {
+			return err
+		}
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+	}
+
+	recordLogin(models.LoginAttempt{Email: user.Email, UserID: user.ID, IPAddress: ipAddress, Via: via, Success: true}, "")
+	return nil
+}
+
+// recordLoginFailure records a failed login by a known user and counts it towards locking their account, it returns
+// when the account is locked until if this failure locked it
+func recordLoginFailure(user models.User, ipAddress string, via string, reason string) (*time.Time, error) {
+	recordLogin(models.LoginAttempt{Email: user.Email, UserID: user.ID, IPAddress: ipAddress, Via: via}, reason)
+	lockedUntil, err := database.RecordLoginFailure(user.ID, loginConfig.AccountMaxFailures(), loginConfig.Lockout())
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		log.Printf("[WARN] locked user %d until %s after %d failed logins", user.ID, lockedUntil.Format(time.RFC3339), loginConfig.AccountMaxFailures())
+	}
+	return lockedUntil, nil
+}
+
+// lockedError is only shown to a user who already gave the right password
+func lockedError(until time.Time) error {
+	return fmt.Errorf("account is locked after too many failed logins, try again after %s or contact a system administrator", until.Format("15:04"))
+}
+
+func recordLogin(attempt models.LoginAttempt, reason string) {
+	attempt.Reason = reason
+	if err := database.InsertLoginAttempt(&attempt); err != nil {
+		log.Printf("[ERROR] could not record login attempt for %s: %s", attempt.Email, err.Error())
+	}
+}
+
+// passwordCharacters are safe to paste into a url query or a shell
+const passwordCharacters = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789-_.~!"
+
+// GeneratePassword returns a random password that meets the password policy
+func GeneratePassword() (string, error) {
+	length := max(passwordPolicy.MinimumLength(), 24)
+	for {
+		password, err := randomCharacters(passwordCharacters, length)
+		if err != nil {
+			return "", err
+		}
+		if passwordPolicy.Check(password) == nil {
+			return password, nil
+		}
+	}
+}
+
+// randomCharacters returns n characters picked at random from an alphabet
+func randomCharacters(alphabet string, n int) (string, error) {
+	size := big.NewInt(int64(len(alphabet)))
+	b := make([]byte, n)
+	for i := range b {
+		r, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[r.Int64()]
+	}
+	return string(b), nil
+}
+
+func UnlockUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if err := database.UnlockUser(uint(id)); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/show", id))
+}
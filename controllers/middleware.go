@@ -31,7 +31,21 @@ func RequireAuth(c *gin.Context) {
 		return
 	}
 
+	permissions, err := LoadPermissions(user)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+		c.Abort()
+		return
+	}
+
+	if err := Authorize(c, permissions); err != nil {
+		ThrowError(AuthorizationStatus(err), err.Error(), c, true)
+		c.Abort()
+		return
+	}
+
 	c.Set(ContextKeySessionCookies, sessionCookies)
 	c.Set(ContextKeyUser, user)
+	c.Set(ContextKeyPermissions, permissions)
 	c.Next()
 }
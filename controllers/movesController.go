@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// parseEntryIDs reads the selected entry ids of a single or bulk move
+func parseEntryIDs(values []string) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	for _, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return ids, fmt.Errorf("entry id `%s` is not valid", value)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return ids, fmt.Errorf("no entries selected")
+	}
+	return ids, nil
+}
+
+func MoveEntriesForm(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	ids, err := parseEntryIDs(c.QueryArray("entry_ids"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	entries := []models.Entry{}
+	for _, id := range ids {
+		entry, err := database.FindEntry(id)
+		if err != nil {
+			ThrowError(http.StatusNotFound, fmt.Sprintf("entry %s: %s", id, err.Error()), c, true)
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	accessions, err := database.FindAccessions()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+	sort.Slice(accessions, func(i, j int) bool {
+		if accessions[i].Resource.CollectionCode != accessions[j].Resource.CollectionCode {
+			return accessions[i].Resource.CollectionCode < accessions[j].Resource.CollectionCode
+		}
+		return accessions[i].AccessionNum < accessions[j].AccessionNum
+	})
+
+	c.HTML(http.StatusOK, "entries-move.html", gin.H{
+		"isAdmin":    sessionCookies.IsAdmin,
+		"isLoggedIn": true,
+		"user":       user,
+		"entries":    entries,
+		"accessions": accessions,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+func MoveEntries(c *gin.Context) {
+	ids, err := parseEntryIDs(c.PostFormArray("entry_ids"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	accessionID, err := strconv.Atoi(c.PostForm("accession_id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, "a target accession is required", c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusUnauthorized, err.Error(), c, true)
+		return
+	}
+
+	if _, err := database.MoveEntries(ids, uint(accessionID), userID); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if len(ids) == 1 {
+		c.Redirect(http.StatusFound, fmt.Sprintf("/entries/%s/show", ids[0]))
+		return
+	}
+	c.Redirect(http.StatusFound, fmt.Sprintf("/accessions/%d/show", accessionID))
+}
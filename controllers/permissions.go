@@ -0,0 +1,552 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+const ContextKeyPermissions = "permissions"
+
+// Permissions are the roles a user holds, admins hold the admin role everywhere
+type Permissions struct {
+	IsAdmin      bool            `json:"is_admin"`
+	Global       string          `json:"global"`
+	Repositories map[uint]string `json:"repositories"`
+}
+
+// LoadPermissions reads a user's role grants
+func LoadPermissions(user models.User) (Permissions, error) {
+	permissions := Permissions{IsAdmin: user.IsAdmin, Repositories: map[uint]string{}}
+	grants, err := database.FindRoleGrantsByUserID(user.ID)
+	if err != nil {
+		return permissions, err
+	}
+	for _, grant := range grants {
+		if grant.IsGlobal() {
+			permissions.Global = grant.Role
+		} else {
+			permissions.Repositories[grant.RepositoryID] = grant.Role
+		}
+	}
+	return permissions, nil
+}
+
+// Role returns the user's role in a repository, the higher of their global role and their role there
+func (p Permissions) Role(repositoryID uint) string {
+	if p.IsAdmin {
+		return models.RoleAdmin
+	}
+	role := p.Global
+	if local, ok := p.Repositories[repositoryID]; ok && models.RoleRank(local) > models.RoleRank(role) {
+		role = local
+	}
+	return role
+}
+
+// Can reports whether the user holds at least a role in a repository
+func (p Permissions) Can(role string, repositoryID uint) bool {
+	return models.RoleRank(p.Role(repositoryID)) >= models.RoleRank(role)
+}
+
+// CanGlobally reports whether the user holds at least a role in every repository
+func (p Permissions) CanGlobally(role string) bool {
+	return p.IsAdmin || models.RoleRank(p.Global) >= models.RoleRank(role)
+}
+
+// Holds reports whether the user holds at least a role in any repository
+func (p Permissions) Holds(role string) bool {
+	if p.CanGlobally(role) {
+		return true
+	}
+	for _, local := range p.Repositories {
+		if models.RoleRank(local) >= models.RoleRank(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// ViewableRepositories lists the repositories the user can view, it is nil when they can view every repository
+func (p Permissions) ViewableRepositories() []uint {
+	if p.CanGlobally(models.RoleViewer) {
+		return nil
+	}
+	repositoryIDs := []uint{}
+	for repositoryID, role := range p.Repositories {
+		if models.RoleRank(role) >= models.RoleRank(models.RoleViewer) {
+			repositoryIDs = append(repositoryIDs, repositoryID)
+		}
+	}
+	return repositoryIDs
+}
+
+// FilterRepositories keeps the repositories the user can view
+func (p Permissions) FilterRepositories(repositories []models.Repository) []models.Repository {
+	viewable := []models.Repository{}
+	for _, repository := range repositories {
+		if p.Can(models.RoleViewer, repository.ID) {
+			viewable = append(viewable, repository)
+		}
+	}
+	return viewable
+}
+
+// FilterResources keeps the resources in repositories the user can view
+func (p Permissions) FilterResources(resources []models.Resource) []models.Resource {
+	viewable := []models.Resource{}
+	for _, resource := range resources {
+		if p.Can(models.RoleViewer, resource.RepositoryID) {
+			viewable = append(viewable, resource)
+		}
+	}
+	return viewable
+}
+
+// FilterAccessions keeps the accessions in repositories the user can view, their resources must be loaded
+func (p Permissions) FilterAccessions(accessions []models.Accession) []models.Accession {
+	viewable := []models.Accession{}
+	for _, accession := range accessions {
+		if p.Can(models.RoleViewer, accession.Resource.RepositoryID) {
+			viewable = append(viewable, accession)
+		}
+	}
+	return viewable
+}
+
+// GetPermissions returns the permissions a request was authorized with
+func GetPermissions(c *gin.Context) Permissions {
+	return c.MustGet(ContextKeyPermissions).(Permissions)
+}
+
+// ForbiddenError is returned when a user lacks the role a request requires
+type ForbiddenError struct {
+	Role         string
+	RepositoryID uint
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.RepositoryID == 0 {
+		return fmt.Sprintf("the %s role is required", e.Role)
+	}
+	return fmt.Sprintf("the %s role in repository %d is required", e.Role, e.RepositoryID)
+}
+
+// FieldConflictError is returned when a request sends a field that scopes it both in the query string and in its body,
+// the role check and the handler could otherwise read different values
+type FieldConflictError struct {
+	Field string
+}
+
+func (e *FieldConflictError) Error() string {
+	return fmt.Sprintf("%s is sent in both the query string and the body", e.Field)
+}
+
+// AuthorizationStatus maps an authorization error to a response code
+func AuthorizationStatus(err error) int {
+	var forbidden *ForbiddenError
+	if errors.As(err, &forbidden) {
+		return http.StatusForbidden
+	}
+	var conflict *FieldConflictError
+	if errors.As(err, &conflict) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// scope finds the repositories a request acts on
+type scope func(c *gin.Context) ([]uint, error)
+
+// requirement is the least role a route needs, in the repositories of its scope or globally when it has none. A scope
+// that names no repositories, because its record is missing or its id does not parse, is only allowed for users who
+// hold the role globally, a scope that may name none on purpose is wrapped with orEvery. A requirement that is met
+// anywhere only needs the role in one repository, the handler then lists only what the user can view.
+type requirement struct {
+	role     string
+	scope    scope
+	anywhere bool
+}
+
+func global(role string) requirement { return requirement{role: role} }
+
+func in(role string, s scope) requirement { return requirement{role: role, scope: s} }
+
+func anywhere(role string) requirement { return requirement{role: role, anywhere: true} }
+
+// anyUser lets every logged in user through, the handler does its own checks
+var anyUser = requirement{}
+
+// routeRequirements are keyed by method and route path. Routes not listed need a global admin.
+var routeRequirements = map[string]requirement{
+	//index, the handler lists only entries the user can view
+	"GET /": anyUser,
+
+	//accessions
+	"GET /accessions":             anywhere(models.RoleViewer),
+	"GET /accessions/new":         in(models.RoleCurator, resourceField("resource_id")),
+	"POST /accessions":            in(models.RoleCurator, resourceField("resource_id")),
+	"GET /accessions/:id/show":    in(models.RoleViewer, accessionParam),
+	"GET /accessions/:id/edit":    in(models.RoleCurator, accessionParam),
+	"POST /accessions/:id/update": in(models.RoleCurator, accessionParam),
+	"GET /accessions/:id/delete":  in(models.RoleCurator, accessionParam),
+	"POST /accessions/:id/delete": in(models.RoleCurator, accessionParam),
+	"GET /accessions/:id/slew":    in(models.RoleTechnician, accessionParam),
+	"POST /accessions/slew":       in(models.RoleTechnician, accessionField("accession_id")),
+	"GET /accessions/:id/csv":     in(models.RoleViewer, accessionParam),
+	"GET /accessions/:id/import":  in(models.RoleTechnician, accessionParam),
+	"POST /accessions/:id/import": in(models.RoleTechnician, accessionParam),
+
+	//repositories
+	"GET /repositories":             anywhere(models.RoleViewer),
+	"GET /repositories/:id/show":    in(models.RoleViewer, repositoryParam),
+	"GET /repositories/new":         global(models.RoleAdmin),
+	"POST /repositories":            global(models.RoleAdmin),
+	"GET /repositories/:id/edit":    in(models.RoleAdmin, repositoryParam),
+	"POST /repositories/:id/update": in(models.RoleAdmin, repositoryParam),
+	"GET /repositories/:id/delete":  in(models.RoleAdmin, repositoryParam),
+	"POST /repositories/:id/delete": in(models.RoleAdmin, repositoryParam),
+
+	//resources
+	"GET /resources":             anywhere(models.RoleViewer),
+	"GET /resources/:id/show":    in(models.RoleViewer, resourceParam),
+	"GET /resources/:id/csv":     in(models.RoleViewer, resourceParam),
+	"GET /resources/new":         in(models.RoleCurator, repositoryField("repository_id")),
+	"POST /resources":            in(models.RoleCurator, repositoryField("repository_id")),
+	"GET /resources/:id/edit":    in(models.RoleCurator, resourceParam),
+	"POST /resources/:id/update": in(models.RoleCurator, resourceParam),
+	"GET /resources/:id/delete":  in(models.RoleCurator, resourceParam),
+	"POST /resources/:id/delete": in(models.RoleCurator, resourceParam),
+
+	//entries
+	"GET /entries":                                          anywhere(models.RoleViewer),
+	"GET /entries/csv":                                      anywhere(models.RoleViewer),
+	"GET /entries/new":                                      in(models.RoleTechnician, accessionField("accession_id")),
+	"POST /entries":                                         in(models.RoleTechnician, accessionField("accession_id")),
+	"GET /entries/:id/show":                                 in(models.RoleViewer, entryParam),
+	"GET /entries/:id/previous":                             in(models.RoleViewer, entryParam),
+	"GET /entries/:id/next":                                 in(models.RoleViewer, entryParam),
+	"POST /entries/find":                                    in(models.RoleViewer, resourceField("resource_id")),
+	"GET /entries/:id/edit":                                 in(models.RoleTechnician, entryParam),
+	"POST /entries/:id/update":                              in(models.RoleTechnician, entryParam),
+	"POST /entries/:id/clone":                               in(models.RoleTechnician, entryParam),
+	"GET /entries/:id/delete":                               in(models.RoleCurator, entryParam),
+	"POST /entries/:id/delete":                              in(models.RoleCurator, entryParam),
+	"GET /entries/move":                                     in(models.RoleCurator, entriesField("entry_ids")),
+	"POST /entries/move":                                    in(models.RoleCurator, scopes(entriesField("entry_ids"), accessionField("accession_id"))),
+	"POST /entries/:id/image_files":                         in(models.RoleTechnician, entryParam),
+	"POST /entries/:id/fixity":                              in(models.RoleTechnician, entryParam),
+	"POST /entries/:id/imaging_log":                         in(models.RoleTechnician, entryParam),
+	"POST /entries/:id/format_report":                       in(models.RoleTechnician, entryParam),
+	"POST /entries/:id/imaging_attempts":                    in(models.RoleTechnician, entryParam),
+	"POST /entries/:id/imaging_attempts/:attempt_id/delete": in(models.RoleTechnician, entryParam),
+
+	//users, the handlers let users manage their own account
+	"GET /users":                                global(models.RoleAdmin),
+	"GET /users/new":                            global(models.RoleAdmin),
+	"GET /users/logout":                         anyUser,
+	"GET /users/:id/show":                       anyUser,
+	"GET /users/:id/edit":                       anyUser,
+	"GET /users/:id/reset_password":             anyUser,
+	"POST /users/update":                        anyUser,
+	"POST /users/:id/reset_password":            anyUser,
+	"POST /users/:id/api_keys":                  anyUser,
+	"POST /users/:id/api_keys/:key_id/revoke":   anyUser,
+	"POST /users/:id/deactivate":                global(models.RoleAdmin),
+	"POST /users/:id/reactivate":                global(models.RoleAdmin),
+	"POST /users/:id/make_admin":                global(models.RoleAdmin),
+	"POST /users/:id/remove_admin":              global(models.RoleAdmin),
+	"POST /users/:id/allow_api":                 global(models.RoleAdmin),
+	"POST /users/:id/revoke_api":                global(models.RoleAdmin),
+	"POST /users/:id/roles":                     global(models.RoleAdmin),
+	"POST /users/:id/roles/:grant_id/delete":    global(models.RoleAdmin),
+	"POST /users/:id/unlock":                    global(models.RoleAdmin),
+	"POST /users/:id/two_factor/setup":          anyUser,
+	"POST /users/:id/two_factor/enable":         anyUser,
+	"POST /users/:id/two_factor/recovery_codes": anyUser,
+	"POST /users/:id/two_factor/reset":          anyUser,
+
+	//vocabularies
+	"POST /vocabularies/:id/retire":  global(models.RoleAdmin),
+	"POST /vocabularies/:id/restore": global(models.RoleAdmin),
+
+	//search
+	"GET /search": anywhere(models.RoleViewer),
+
+	//sessions
+	"GET /sessions":             global(models.RoleAdmin),
+	"GET /sessions/dump":        anyUser,
+	"POST /sessions/:id/logout": global(models.RoleAdmin),
+
+	//reports, a report of every repository needs the viewer role in each of them
+	"GET /reports":               anywhere(models.RoleViewer),
+	"POST /reports/range":        in(models.RoleViewer, orEvery(repositoryField("repository-id"))),
+	"POST /reports/csv":          in(models.RoleViewer, orEvery(repositoryField("repository-id"))),
+	"GET /reports/fixity":        anywhere(models.RoleViewer),
+	"GET /reports/staging":       global(models.RoleTechnician),
+	"POST /reports/staging/scan": global(models.RoleTechnician),
+
+	//api
+	"DELETE /api/v0/logout":                                   anyUser,
+	"GET /api/v0/repositories":                                anywhere(models.RoleViewer),
+	"GET /api/v0/repositories/:id":                            in(models.RoleViewer, repositoryParam),
+	"GET /api/v0/repositories/:id/entries":                    in(models.RoleViewer, repositoryParam),
+	"GET /api/v0/repositories/:id/summary":                    in(models.RoleViewer, repositoryParam),
+	"POST /api/v0/repositories":                               global(models.RoleAdmin),
+	"DELETE /api/v0/repositories/:id":                         in(models.RoleAdmin, repositoryParam),
+	"GET /api/v0/resources":                                   anywhere(models.RoleViewer),
+	"GET /api/v0/resources/:id":                               in(models.RoleViewer, resourceParam),
+	"GET /api/v0/resources/:id/entries":                       in(models.RoleViewer, resourceParam),
+	"GET /api/v0/resources/:id/summary":                       in(models.RoleViewer, resourceParam),
+	"POST /api/v0/resources":                                  in(models.RoleCurator, repositoryField("repository_id")),
+	"DELETE /api/v0/resources/:id":                            in(models.RoleCurator, resourceParam),
+	"GET /api/v0/accessions":                                  anywhere(models.RoleViewer),
+	"GET /api/v0/accessions/:id":                              in(models.RoleViewer, accessionParam),
+	"GET /api/v0/accessions/:id/entries":                      in(models.RoleViewer, accessionParam),
+	"GET /api/v0/accessions/:id/summary":                      in(models.RoleViewer, accessionParam),
+	"POST /api/v0/accessions":                                 in(models.RoleCurator, resourceField("resource_id")),
+	"DELETE /api/v0/accessions/:id":                           in(models.RoleCurator, accessionParam),
+	"POST /api/v0/accessions/:id/import":                      in(models.RoleTechnician, accessionParam),
+	"POST /api/v0/entries":                                    in(models.RoleTechnician, accessionField("accession_id")),
+	"GET /api/v0/entries":                                     anywhere(models.RoleViewer),
+	"GET /api/v0/entries/:id":                                 in(models.RoleViewer, entryParam),
+	"GET /api/v0/entries/:id/history":                         in(models.RoleViewer, entryParam),
+	"GET /api/v0/entries/:id/image_files":                     in(models.RoleViewer, entryParam),
+	"GET /api/v0/entries/:id/format_profile":                  in(models.RoleViewer, entryParam),
+	"GET /api/v0/entries/:id/imaging_attempts":                in(models.RoleViewer, entryParam),
+	"DELETE /api/v0/entries/:id":                              in(models.RoleCurator, entryParam),
+	"POST /api/v0/entries/:id/move":                           in(models.RoleCurator, scopes(entryParam, accessionField("accession_id"))),
+	"POST /api/v0/entries/move":                               in(models.RoleCurator, scopes(entriesField("entry_ids"), accessionField("accession_id"))),
+	"PATCH /api/v0/entries/:id/update_location":               in(models.RoleTechnician, entryParam),
+	"POST /api/v0/entries/:id/update":                         in(models.RoleTechnician, entryParam),
+	"POST /api/v0/entries/:id/image_files":                    in(models.RoleTechnician, entryParam),
+	"POST /api/v0/entries/:id/imaging_log":                    in(models.RoleTechnician, entryParam),
+	"POST /api/v0/entries/:id/format_profile":                 in(models.RoleTechnician, entryParam),
+	"POST /api/v0/entries/:id/imaging_attempts":               in(models.RoleTechnician, entryParam),
+	"DELETE /api/v0/entries/:id/imaging_attempts/:attempt_id": in(models.RoleTechnician, entryParam),
+	"GET /api/v0/search/entries":                              anywhere(models.RoleViewer),
+	"GET /api/v0/vocabularies":                                anywhere(models.RoleViewer),
+	"GET /api/v0/vocabularies/:vocabulary":                    anywhere(models.RoleViewer),
+	"GET /api/v0/reports/range":                               in(models.RoleViewer, orEvery(repositoryField("repository_id"))),
+	"GET /api/v0/reports/fixity":                              anywhere(models.RoleViewer),
+	"GET /api/v0/reports/staging":                             global(models.RoleTechnician),
+	"POST /api/v0/staging/scan":                               global(models.RoleTechnician),
+}
+
+func routeRequirement(method string, path string) requirement {
+	if req, ok := routeRequirements[method+" "+path]; ok {
+		return req
+	}
+	return global(models.RoleAdmin)
+}
+
+// Authorize checks that a user holds the role the request's route requires
+func Authorize(c *gin.Context, permissions Permissions) error {
+	req := routeRequirement(c.Request.Method, c.FullPath())
+	if req.role == "" {
+		return nil
+	}
+
+	if req.anywhere {
+		if !permissions.Holds(req.role) {
+			return &ForbiddenError{Role: req.role}
+		}
+		return nil
+	}
+
+	if req.scope == nil {
+		if !permissions.CanGlobally(req.role) {
+			return &ForbiddenError{Role: req.role}
+		}
+		return nil
+	}
+
+	repositoryIDs, err := req.scope(c)
+	if err != nil {
+		return err
+	}
+	if len(repositoryIDs) == 0 && !permissions.CanGlobally(req.role) {
+		return &ForbiddenError{Role: req.role}
+	}
+	for _, repositoryID := range repositoryIDs {
+		if !permissions.Can(req.role, repositoryID) {
+			return &ForbiddenError{Role: req.role, RepositoryID: repositoryID}
+		}
+	}
+	return nil
+}
+
+// repositoryOf resolves a record to its repository, a record that does not exist names no repository
+func repositoryOf(find func() (uint, error)) ([]uint, error) {
+	repositoryID, err := find()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []uint{}, nil
+	}
+	if err != nil {
+		return []uint{}, err
+	}
+	return []uint{repositoryID}, nil
+}
+
+func entryRepositories(values []string) ([]uint, error) {
+	repositoryIDs := []uint{}
+	for _, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			continue
+		}
+		ids, err := repositoryOf(func() (uint, error) { return database.FindRepositoryIDByEntryID(id) })
+		if err != nil {
+			return repositoryIDs, err
+		}
+		repositoryIDs = append(repositoryIDs, ids...)
+	}
+	return repositoryIDs, nil
+}
+
+// idRepositories resolves numeric ids with find, ids that do not parse name no repository
+func idRepositories(values []string, find func(uint) (uint, error)) ([]uint, error) {
+	repositoryIDs := []uint{}
+	for _, value := range values {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			continue
+		}
+		ids, err := repositoryOf(func() (uint, error) { return find(uint(id)) })
+		if err != nil {
+			return repositoryIDs, err
+		}
+		repositoryIDs = append(repositoryIDs, ids...)
+	}
+	return repositoryIDs, nil
+}
+
+func repositoryItself(id uint) (uint, error) { return id, nil }
+
+func entryParam(c *gin.Context) ([]uint, error) { return entryRepositories([]string{c.Param("id")}) }
+
+func accessionParam(c *gin.Context) ([]uint, error) {
+	return idRepositories([]string{c.Param("id")}, database.FindRepositoryIDByAccessionID)
+}
+
+func resourceParam(c *gin.Context) ([]uint, error) {
+	return idRepositories([]string{c.Param("id")}, database.FindRepositoryIDByResourceID)
+}
+
+func repositoryParam(c *gin.Context) ([]uint, error) {
+	return idRepositories([]string{c.Param("id")}, repositoryItself)
+}
+
+func entriesField(name string) scope {
+	return func(c *gin.Context) ([]uint, error) {
+		values, err := requestValues(c, name)
+		if err != nil {
+			return []uint{}, err
+		}
+		return entryRepositories(values)
+	}
+}
+
+func accessionField(name string) scope { return idField(name, database.FindRepositoryIDByAccessionID) }
+
+func resourceField(name string) scope { return idField(name, database.FindRepositoryIDByResourceID) }
+
+func repositoryField(name string) scope { return idField(name, repositoryItself) }
+
+func idField(name string, find func(uint) (uint, error)) scope {
+	return func(c *gin.Context) ([]uint, error) {
+		values, err := requestValues(c, name)
+		if err != nil {
+			return []uint{}, err
+		}
+		return idRepositories(values, find)
+	}
+}
+
+// orEvery is the repositories a scope names, a request that names none acts on every repository
+func orEvery(s scope) scope {
+	return func(c *gin.Context) ([]uint, error) {
+		repositoryIDs, err := s(c)
+		if err != nil || len(repositoryIDs) > 0 {
+			return repositoryIDs, err
+		}
+		return database.FindRepositoryIDs()
+	}
+}
+
+// scopes joins the repositories of several scopes
+func scopes(all ...scope) scope {
+	return func(c *gin.Context) ([]uint, error) {
+		repositoryIDs := []uint{}
+		for _, s := range all {
+			ids, err := s(c)
+			if err != nil {
+				return repositoryIDs, err
+			}
+			repositoryIDs = append(repositoryIDs, ids...)
+		}
+		return repositoryIDs, nil
+	}
+}
+
+// requestValues reads a field from the query string or from a json body or posted form. Handlers read a field from
+// one or the other, so a request that sends it in both is refused rather than checked against values the handler may
+// not use.
+func requestValues(c *gin.Context, name string) ([]string, error) {
+	query := c.QueryArray(name)
+	body := bodyValues(c, name)
+	if len(query) > 0 && len(body) > 0 {
+		return []string{}, &FieldConflictError{Field: name}
+	}
+	if len(query) > 0 {
+		return query, nil
+	}
+	return body, nil
+}
+
+// bodyValues reads a field from a json body or a posted form, a json body is put back for the handler to bind
+func bodyValues(c *gin.Context, name string) []string {
+	if c.Request.Body == nil || c.Request.Method == http.MethodGet {
+		return []string{}
+	}
+
+	if c.ContentType() != gin.MIMEJSON {
+		return c.PostFormArray(name)
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return []string{}
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return []string{}
+	}
+
+	switch value := fields[name].(type) {
+	case nil:
+		return []string{}
+	case []interface{}:
+		values := []string{}
+		for _, v := range value {
+			values = append(values, fmt.Sprint(v))
+		}
+		return values
+	case float64:
+		return []string{strconv.FormatFloat(value, 'f', -1, 64)}
+	default:
+		return []string{fmt.Sprint(value)}
+	}
+}
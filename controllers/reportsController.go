@@ -29,6 +29,7 @@ func ReportsIndex(c *gin.Context) {
 		"isLoggedIn":    true,
 		"isAdmin":       sessionCookies.IsAdmin,
 		"user":          user,
+		"csrfToken":     c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -66,6 +67,7 @@ func ReportsRange(c *gin.Context) {
 		"isLoggedIn":    true,
 		"isAdmin":       sessionCookies.IsAdmin,
 		"user":          user,
+		"csrfToken":     c.MustGet(ContextKeyCSRFToken),
 	})
 
 }
@@ -20,6 +20,7 @@ func NewRepository(c *gin.Context) {
 		"isAuthenticated": true,
 		"isLoggedIn": true,
 		"user":            user,
+		"csrfToken":       c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -68,6 +69,7 @@ func EditRepository(c *gin.Context) {
 		"repository": repository,
 		"isLoggedIn": true,
 		"user":       user,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
 	})
 
 }
@@ -110,18 +112,57 @@ func UpdateRepository(c *gin.Context) {
 	c.Redirect(http.StatusFound, fmt.Sprintf("/repositories/%d/show", id))
 }
 
-func DeleteRepository(c *gin.Context) {
+func ConfirmDeleteRepository(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
 
-	if err := database.DeleteRepository(uint(id)); err != nil {
+	repository, err := database.FindRepository(uint(id))
+	if err != nil {
+		ThrowError(http.StatusNotFound, err.Error(), c, true)
+		return
+	}
+
+	summary, err := database.FindRepositoryDependents(uint(id))
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	renderDeleteConfirmation(c, deleteConfirmation{
+		Object:  "repository",
+		Label:   repository.Slug,
+		Action:  fmt.Sprintf("/repositories/%d/delete", id),
+		Cancel:  fmt.Sprintf("/repositories/%d/show", id),
+		Summary: summary,
+	})
+}
+
+func DeleteRepository(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
 
+	cascade, err := isCascadeDelete(c)
+	if err != nil {
+		ThrowError(http.StatusUnauthorized, err.Error(), c, true)
+		return
+	}
+
+	if cascade {
+		_, err = database.DeleteRepositoryCascade(uint(id))
+	} else {
+		err = database.DeleteRepository(uint(id))
+	}
+	if err != nil {
+		ThrowError(DeleteErrorStatus(err), err.Error(), c, true)
+		return
+	}
+
 	c.Redirect(http.StatusFound, "/repositories")
 }
 
@@ -136,8 +177,9 @@ func GetRepositories(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "repositories-index.html", gin.H{
-		"repositories": repositories,
+		"repositories": GetPermissions(c).FilterRepositories(repositories),
 		"isAdmin":      sessionCookies.IsAdmin,
+		"permissions":  c.MustGet(ContextKeyPermissions),
 		"isLoggedIn": true,
 		"user":         user,
 	})
@@ -170,6 +212,7 @@ func GetRepository(c *gin.Context) {
 		"repository": repository,
 		"resources":  resources,
 		"isAdmin":    sessionCookies.IsAdmin,
+		"permissions": c.MustGet(ContextKeyPermissions),
 		"isLoggedIn": true,
 		"user":       user,
 	})
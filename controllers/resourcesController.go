@@ -37,6 +37,12 @@ func GetResource(c *gin.Context) {
 		return
 	}
 
+	formatSummary, err := database.GetFormatSummaryByResource(resource.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
 	//get associacted accessions
 	accessions, err := database.FindAccessionsByResourceID(resource.ID)
 	if err != nil {
@@ -104,16 +110,18 @@ func GetResource(c *gin.Context) {
 		"accessions":      accessions,
 		"entries":         entries,
 		"isAdmin":         sessionCookies.IsAdmin,
+		"permissions":     c.MustGet(ContextKeyPermissions),
 		"isAuthenticated": true,
 		"pagination":      pagination,
 		"summary":         summary,
 		"totals":          summary.GetTotals(),
+		"formatSummary":   formatSummary,
 		"entry_users":     entryUsers,
 		"isLoggedIn": true,
 		"user":            user,
 		"overlimit":       overlimit,
 		"limitValues":     LimitValues,
-		"mediatypes":      GetMediatypes(),
+		"mediatypes":      getVocabularyWithRetired(VocabularyMediatypes),
 	})
 }
 
@@ -134,9 +142,10 @@ func GetResources(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "resources-index.html", gin.H{
-		"resources":       resources,
+		"resources":       GetPermissions(c).FilterResources(resources),
 		"isAuthenticated": true,
 		"isAdmin":         sessionCookies.IsAdmin,
+		"permissions":     c.MustGet(ContextKeyPermissions),
 		"repositoryMap":   repositoryMap,
 		"isLoggedIn": true,
 		"user":            user,
@@ -164,6 +173,7 @@ func NewResource(c *gin.Context) {
 		"repository": repository,
 		"isLoggedIn": true,
 		"user":       user,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -230,6 +240,7 @@ func EditResource(c *gin.Context) {
 		"resource":   resource,
 		"isLoggedIn": true,
 		"user":       user,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -271,7 +282,7 @@ func UpdateResource(c *gin.Context) {
 	c.Redirect(http.StatusFound, fmt.Sprintf("/resources/%d/show", resource.ID))
 }
 
-func DeleteResource(c *gin.Context) {
+func ConfirmDeleteResource(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -280,15 +291,54 @@ func DeleteResource(c *gin.Context) {
 
 	resource, err := database.FindResource(uint(id))
 	if err != nil {
-		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		ThrowError(http.StatusNotFound, err.Error(), c, true)
+		return
+	}
+
+	summary, err := database.FindResourceDependents(uint(id))
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
 		return
 	}
 
-	if err := database.DeleteResource(uint(id)); err != nil {
+	renderDeleteConfirmation(c, deleteConfirmation{
+		Object:  "resource",
+		Label:   resource.CollectionCode,
+		Action:  fmt.Sprintf("/resources/%d/delete", id),
+		Cancel:  fmt.Sprintf("/resources/%d/show", id),
+		Summary: summary,
+	})
+}
+
+func DeleteResource(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
 
+	resource, err := database.FindResource(uint(id))
+	if err != nil {
+		ThrowError(http.StatusNotFound, err.Error(), c, true)
+		return
+	}
+
+	cascade, err := isCascadeDelete(c)
+	if err != nil {
+		ThrowError(http.StatusUnauthorized, err.Error(), c, true)
+		return
+	}
+
+	if cascade {
+		_, err = database.DeleteResourceCascade(uint(id))
+	} else {
+		err = database.DeleteResource(uint(id))
+	}
+	if err != nil {
+		ThrowError(DeleteErrorStatus(err), err.Error(), c, true)
+		return
+	}
+
 	c.Redirect(http.StatusFound, fmt.Sprintf("/repositories/%d/show", resource.RepositoryID))
 }
 
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// ValidateRoleGrant checks a grant's role and repository, the global admin role is given with make admin instead
+func ValidateRoleGrant(grant models.RoleGrant) error {
+	if !slices.Contains(models.Roles, grant.Role) {
+		return fmt.Errorf("`%s` is not a role", grant.Role)
+	}
+	if grant.IsGlobal() {
+		if grant.Role == models.RoleAdmin {
+			return fmt.Errorf("make the user an admin to grant the admin role in every repository")
+		}
+		return nil
+	}
+	if _, err := database.FindRepository(grant.RepositoryID); err != nil {
+		return fmt.Errorf("repository %d: %w", grant.RepositoryID, err)
+	}
+	return nil
+}
+
+// GrantUserRole gives a user a role globally or in a repository, replacing the role they had there
+func GrantUserRole(userID uint, grant models.RoleGrant, grantedBy int) (models.RoleGrant, error) {
+	if _, err := database.FindUser(userID); err != nil {
+		return grant, fmt.Errorf("user %d: %w", userID, err)
+	}
+	if err := ValidateRoleGrant(grant); err != nil {
+		return grant, err
+	}
+
+	grant.ID = 0
+	grant.UserID = userID
+	grant.CreatedAt = time.Now()
+	grant.CreatedBy = grantedBy
+	return grant, database.GrantRole(&grant)
+}
+
+func CreateRoleGrant(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	grant := models.RoleGrant{}
+	if err := c.Bind(&grant); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	grantedBy, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	if _, err := GrantUserRole(uint(userID), grant, grantedBy); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/show", userID))
+}
+
+func DeleteRoleGrant(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	grantID, err := strconv.Atoi(c.Param("grant_id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if err := database.RevokeRole(uint(userID), uint(grantID)); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/show", userID))
+}
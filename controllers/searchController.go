@@ -1,31 +1,168 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nyudlts/go-medialog/database"
 	"github.com/nyudlts/go-medialog/models"
 )
 
+// searchFilters are the query parameters that narrow a search, in addition to the query itself
+var searchFilters = []string{"repository_id", "resource_id", "accession_id", "mediatype", "status", "location", "created_from", "created_to", "updated_from", "updated_to"}
+
+// ParseEntrySearch reads the query, filters and paging of an entry search from the request
+func ParseEntrySearch(c *gin.Context) (database.EntrySearch, database.Pagination, error) {
+	search := database.ParseEntryQuery(c.Query("query"))
+	pagination := database.Pagination{Limit: 10, Sort: c.Query("sort")}
+
+	ids := map[string]*uint{"repository_id": &search.RepositoryID, "resource_id": &search.ResourceID, "accession_id": &search.AccessionID}
+	for param, id := range ids {
+		if value := c.Query(param); value != "" {
+			i, err := strconv.Atoi(value)
+			if err != nil || i < 0 {
+				return search, pagination, fmt.Errorf("%s: `%s` is not a valid id", param, value)
+			}
+			*id = uint(i)
+		}
+	}
+
+	search.Mediatype = c.Query("mediatype")
+	search.Status = c.Query("status")
+	search.Location = c.Query("location")
+
+	dates := map[string]*time.Time{"created_from": &search.CreatedFrom, "created_to": &search.CreatedTo, "updated_from": &search.UpdatedFrom, "updated_to": &search.UpdatedTo}
+	for param, date := range dates {
+		if value := c.Query(param); value != "" {
+			d, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return search, pagination, fmt.Errorf("%s: `%s` is not a valid date, use YYYY-MM-DD", param, value)
+			}
+			*date = d
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return search, pagination, fmt.Errorf("limit: `%s` is not valid", value)
+		}
+		pagination.Limit = limit
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil {
+			return search, pagination, fmt.Errorf("page: `%s` is not valid", value)
+		}
+		if page > 0 {
+			pagination.Page = page
+		}
+	}
+	pagination.Offset = pagination.Page * pagination.Limit
+
+	return search, pagination, nil
+}
+
+type facetLink struct {
+	Value  string
+	Label  string
+	Count  int64
+	URL    string
+	Active bool
+}
+
+// searchURL rebuilds the search url with one parameter changed, an empty value removes it, the page is always reset
+func searchURL(params url.Values, param string, value string) string {
+	updated := url.Values{}
+	for k, v := range params {
+		if k != "page" {
+			updated[k] = v
+		}
+	}
+	if value == "" {
+		updated.Del(param)
+	} else {
+		updated.Set(param, value)
+	}
+	return "/search?" + updated.Encode()
+}
+
 func GlobalSearch(c *gin.Context) {
 	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
 	user := c.MustGet(ContextKeyUser).(models.User)
 	query := c.Query("query")
 
+	search, pagination, err := ParseEntrySearch(c)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	//only search repositories the user can view
+	permissions := GetPermissions(c)
+	search.RepositoryIDs = permissions.ViewableRepositories()
+
 	//get Entry matches
-	entries, err := database.SearchEntries(query)
+	result, err := database.SearchEntryPage(search, pagination)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	repositories, err := database.FindRepositories()
 	if err != nil {
 		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
 		return
 	}
 
+	resources, err := database.FindResources()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	params := c.Request.URL.Query()
+	labels := map[string]func(string) string{"mediatype": GetMediaType, "status": GetEntryStatus}
+	facets := map[string][]facetLink{}
+	for facet, counts := range result.Facets {
+		for _, count := range counts {
+			link := facetLink{Value: count.Value, Label: labels[facet](count.Value), Count: count.Count}
+			link.Active = params.Get(facet) == count.Value
+			if link.Active {
+				link.URL = searchURL(params, facet, "")
+			} else {
+				link.URL = searchURL(params, facet, count.Value)
+			}
+			facets[facet] = append(facets[facet], link)
+		}
+	}
+
+	filters := map[string]string{}
+	for _, filter := range searchFilters {
+		filters[filter] = c.Query(filter)
+	}
+
 	c.HTML(200, "results.html", gin.H{
-		"user":       user,
-		"isLoggedIn": true,
-		"isAdmin": sessionCookies.IsAdmin,
-		"query":      query,
-		"entries":    entries,
+		"user":         user,
+		"isLoggedIn":   true,
+		"isAdmin":      sessionCookies.IsAdmin,
+		"query":        query,
+		"filters":      filters,
+		"entries":      result.Results,
+		"pagination":   result.Pagination,
+		"facets":       facets,
+		"repositories": permissions.FilterRepositories(repositories),
+		"resources":    permissions.FilterResources(resources),
+		"mediatypes":   getVocabularyWithRetired(VocabularyMediatypes),
+		"statuses":     getVocabularyWithRetired(VocabularyEntryStatuses),
+		"locations":    getVocabularyWithRetired(VocabularyStorageLocations),
+		"prevURL":      searchURL(params, "page", strconv.Itoa(result.Pagination.Page-1)),
+		"nextURL":      searchURL(params, "page", strconv.Itoa(result.Pagination.Page+1)),
 	})
 
 }
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
 )
 
 var userkey = "user"
@@ -16,6 +18,11 @@ var isAdmin = "is-admin"
 var canAccessAPI = "can-access-api"
 var sessionToken = "token"
 
+// sessionOptions are the cookie options of the session store, a session started at login is given them
+var sessionOptions = sessions.Options{Path: "/", HttpOnly: true}
+
+func SetSessionOptions(options sessions.Options) { sessionOptions = options }
+
 func ExpireTokens() {
 	tokens := database.GetTokens()
 
@@ -84,8 +91,17 @@ func setCookie(name string, value interface{}, c *gin.Context) {
 	session.Save()
 }
 
+// login drops the session the user logged in from and starts a new one for them, so a session id set before the
+// login cannot be used after it. The new session gets a new csrf token.
 func login(userid int, c *gin.Context) error {
 	session := sessions.Default(c)
+	session.Clear()
+	session.Options(sessions.Options{Path: sessionOptions.Path, Domain: sessionOptions.Domain, MaxAge: -1})
+	if err := session.Save(); err != nil {
+		return err
+	}
+
+	session.Options(sessionOptions)
 	session.Set(userkey, userid)
 	if err := session.Save(); err != nil {
 		return err
@@ -100,8 +116,17 @@ func logout(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session token"})
 		return
 	}
+	//the login token ends with the session so it no longer shows as active
+	if token, ok := session.Get(sessionToken).(string); ok {
+		if t, err := database.FindToken(token); err == nil {
+			if err := database.ExpireToken(t.ID); err != nil {
+				log.Printf("[ERROR] %s", err.Error())
+			}
+		}
+	}
 	session.Delete(userkey)
 	session.Delete(isAdmin)
+	session.Delete(sessionToken)
 	if err := session.Save(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session"})
 		return
@@ -166,3 +191,54 @@ func DumpSession(c *gin.Context) {
 func TestSession(c *gin.Context) {
 	c.JSON(200, "TBD")
 }
+
+const sessionsAdminOnly = "Must be logged in as an admin to manage sessions"
+
+// GetSessions lists the active web sessions and api logins of every user
+func GetSessions(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, sessionsAdminOnly, c, true)
+		return
+	}
+
+	activeSessions, err := database.FindActiveSessions()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	c.HTML(http.StatusOK, "sessions-index.html", gin.H{
+		"isAdmin":        sessionCookies.IsAdmin,
+		"isLoggedIn":     true,
+		"user":           user,
+		"activeSessions": activeSessions,
+		"sessionToken":   sessionCookies.SessionToken,
+		"csrfToken":      c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+// ForceLogout ends a session, the user has to log in again on their next request
+func ForceLogout(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, sessionsAdminOnly, c, true)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if err := database.EndSession(uint(id)); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/sessions")
+}
@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/fixity"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+// stagingRoots maps storage location codes to the staging directories the workstations write images to
+var stagingRoots = map[string]string{}
+
+func SetStagingRoots(roots map[string]string) { stagingRoots = roots }
+
+func GetStagingRoots() map[string]string { return stagingRoots }
+
+// imageFormatExtensions maps image file extensions to image format terms
+var imageFormatExtensions = map[string]string{
+	".ad1": "image_format_ad1",
+	".e01": "image_format_e01",
+	".img": "image_format_raw",
+	".dd":  "image_format_raw",
+	".raw": "image_format_raw",
+	".001": "image_format_raw",
+	".iso": "image_format_iso",
+	".bin": "image_format_bincue",
+	".cue": "image_format_bincue",
+}
+
+// segmentExtension matches the extensions of split E01 images after the first segment
+var segmentExtension = regexp.MustCompile(`^\.e\d\d$`)
+
+// imageFormatForFile returns the image format term for a file name, or false if it is not a disk image
+func imageFormatForFile(name string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if format, ok := imageFormatExtensions[ext]; ok {
+		return format, true
+	}
+	if segmentExtension.MatchString(ext) {
+		return "image_format_e01", true
+	}
+	return "", false
+}
+
+const (
+	MatchedByImageFilename = "image_filename"
+	MatchedByMediaID       = "collection_code_media_id"
+)
+
+// StagingFile is a file found in a staging directory and the entry it was matched to, if any
+type StagingFile struct {
+	Path      string    `json:"path"`
+	Location  string    `json:"location"`
+	EntryID   uuid.UUID `json:"entry_id"`
+	Label     string    `json:"label"`
+	MatchedBy string    `json:"matched_by"`
+	Updated   []string  `json:"updated"`
+	Reason    string    `json:"reason"`
+}
+
+// StagingReport is the outcome of a staging scan
+type StagingReport struct {
+	ScannedAt time.Time     `json:"scanned_at"`
+	Scanned   int           `json:"scanned"`
+	Matched   []StagingFile `json:"matched"`
+	Unmatched []StagingFile `json:"unmatched"`
+	Errors    []string      `json:"errors"`
+}
+
+func (r StagingReport) String() string {
+	return fmt.Sprintf("%d files scanned, %d matched, %d unmatched, %d errors", r.Scanned, len(r.Matched), len(r.Unmatched), len(r.Errors))
+}
+
+// lastStagingReport is kept for the staging report page
+var lastStagingReport = struct {
+	sync.RWMutex
+	report *StagingReport
+}{}
+
+func GetLastStagingReport() *StagingReport {
+	lastStagingReport.RLock()
+	defer lastStagingReport.RUnlock()
+	return lastStagingReport.report
+}
+
+// stagingMatcher resolves file names to entries by image filename or by collection code and media id
+type stagingMatcher struct {
+	resources []models.Resource //longest collection codes first, so mss.1 does not shadow mss.10
+}
+
+func newStagingMatcher() (stagingMatcher, error) {
+	resources, err := database.FindResources()
+	if err != nil {
+		return stagingMatcher{}, err
+	}
+	sort.SliceStable(resources, func(i, j int) bool { return len(resources[i].CollectionCode) > len(resources[j].CollectionCode) })
+	return stagingMatcher{resources: resources}, nil
+}
+
+// match returns the entry a file belongs to, how it was matched, or why it could not be
+func (m stagingMatcher) match(name string) (models.Entry, string, string) {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+
+	entries, err := database.FindEntriesByImageFilename([]string{name, stem})
+	if err != nil {
+		return models.Entry{}, "", err.Error()
+	}
+	switch {
+	case len(entries) == 1:
+		return entries[0], MatchedByImageFilename, ""
+	case len(entries) > 1:
+		return models.Entry{}, "", fmt.Sprintf("image filename is used by %d entries", len(entries))
+	}
+
+	lower := strings.ToLower(stem)
+	reason := "no entry matches the file name"
+	for _, resource := range m.resources {
+		code := strings.ToLower(resource.CollectionCode)
+		if code == "" || !strings.HasPrefix(lower, code) {
+			continue
+		}
+		//the code must end at a separator or where letters give way to digits, so mss.123 is not media id 23 of mss.1
+		rest := lower[len(code):]
+		trimmed := strings.TrimLeft(rest, "_-. ")
+		if len(trimmed) == len(rest) && unicode.IsDigit(rune(code[len(code)-1])) {
+			continue
+		}
+		digits := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "0123456789"))]
+		if digits == "" {
+			continue
+		}
+		mediaID, err := strconv.Atoi(digits)
+		if err != nil {
+			continue
+		}
+		id, err := database.FindEntryInResource(int(resource.ID), mediaID)
+		if err != nil {
+			//a shorter collection code may still match
+			reason = fmt.Sprintf("no entry %d in %s", mediaID, resource.CollectionCode)
+			continue
+		}
+		entry, err := database.FindEntry(uuid.MustParse(id))
+		if err != nil {
+			return models.Entry{}, "", err.Error()
+		}
+		return entry, MatchedByMediaID, ""
+	}
+	return models.Entry{}, "", reason
+}
+
+// ScanStaging matches the files in every staging directory to entries and fills in their image filename, format and location
+func ScanStaging(userID int) (StagingReport, error) {
+	report := StagingReport{ScannedAt: time.Now(), Matched: []StagingFile{}, Unmatched: []StagingFile{}, Errors: []string{}}
+
+	matcher, err := newStagingMatcher()
+	if err != nil {
+		return report, err
+	}
+
+	locations := []string{}
+	for location := range stagingRoots {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	for _, location := range locations {
+		if _, ok := lookupTerm(VocabularyStorageLocations, location); !ok {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s is not a storage location", location))
+			continue
+		}
+
+		paths, err := fixity.ListFiles(stagingRoots[location])
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+
+		for _, path := range paths {
+			report.Scanned++
+			name := filepath.Base(path)
+			format, isImage := imageFormatForFile(name)
+			file := StagingFile{Path: path, Location: location, Updated: []string{}}
+
+			entry, matchedBy, reason := matcher.match(name)
+			if matchedBy == "" {
+				//sidecar files such as logs are only reported when they belong to an entry
+				if isImage {
+					file.Reason = reason
+					report.Unmatched = append(report.Unmatched, file)
+				}
+				continue
+			}
+
+			file.EntryID = entry.ID
+			file.Label = fmt.Sprintf("%s %d", entry.Resource.CollectionCode, entry.MediaID)
+			file.MatchedBy = matchedBy
+
+			if isImage && entry.ImageFilename == "" {
+				entry.ImageFilename = name
+				file.Updated = append(file.Updated, "image_filename")
+			}
+			if isImage && entry.ImageFormat == "" {
+				entry.ImageFormat = format
+				file.Updated = append(file.Updated, "image_format")
+			}
+			if entry.Location != location {
+				entry.Location = location
+				file.Updated = append(file.Updated, "location")
+			}
+
+			if len(file.Updated) > 0 {
+				entry.UpdatedBy = userID
+				entry.UpdatedAt = time.Now()
+				if err := database.UpdateEntry(&entry); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", path, err.Error()))
+					continue
+				}
+			}
+			report.Matched = append(report.Matched, file)
+		}
+	}
+
+	lastStagingReport.Lock()
+	lastStagingReport.report = &report
+	lastStagingReport.Unlock()
+
+	return report, nil
+}
+
+func ReportsStaging(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	c.HTML(http.StatusOK, "reports-staging.html", gin.H{
+		"report":       GetLastStagingReport(),
+		"stagingRoots": stagingRoots,
+		"isLoggedIn":   true,
+		"isAdmin":      sessionCookies.IsAdmin,
+		"user":         user,
+		"csrfToken":    c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+func ScanStagingNow(c *gin.Context) {
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	if _, err := ScanStaging(userID); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/reports/staging")
+}
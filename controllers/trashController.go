@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+const trashAdminOnly = "Must be logged in as an admin to manage the trash"
+
+func GetTrash(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, trashAdminOnly, c, true)
+		return
+	}
+
+	trash, err := database.FindTrash()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	c.HTML(http.StatusOK, "trash-index.html", gin.H{
+		"isAdmin":    sessionCookies.IsAdmin,
+		"isLoggedIn": true,
+		"user":       user,
+		"trash":      trash,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+func RestoreTrashItem(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, trashAdminOnly, c, true)
+		return
+	}
+
+	if _, err := database.RestoreFromTrash(c.Param("type"), c.Param("id")); err != nil {
+		ThrowError(TrashErrorStatus(err), err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/trash")
+}
+
+func PurgeTrashItem(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, trashAdminOnly, c, true)
+		return
+	}
+
+	if _, err := database.PurgeFromTrash(c.Param("type"), c.Param("id")); err != nil {
+		ThrowError(TrashErrorStatus(err), err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/trash")
+}
+
+// TrashErrorStatus maps an error from a restore or purge to a response code
+func TrashErrorStatus(err error) int {
+	var trashError *database.TrashError
+	switch {
+	case errors.As(err, &trashError):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,469 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// twoFactorConfig sets the issuer shown in authenticator apps and the roles that must use two-factor authentication
+var twoFactorConfig = models.TwoFactorConfig{}
+
+func SetTwoFactorConfig(config models.TwoFactorConfig) error {
+	for _, role := range config.Required {
+		if models.RoleRank(role) < 0 {
+			return fmt.Errorf("`%s` is not a role, two_factor required roles must be one of %s", role, strings.Join(models.Roles, ", "))
+		}
+	}
+	twoFactorConfig = config
+	return nil
+}
+
+// session keys for a login that passed the password step and waits for a code
+var twoFactorUserID = "2fa-user"
+var twoFactorExpires = "2fa-expires"
+
+// twoFactorTimeout is how long a user has to send a code after their password was accepted
+const twoFactorTimeout = 5 * time.Minute
+
+const (
+	totpPeriod          = 30
+	recoveryCodeCount   = 10
+	recoveryCodeLetters = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var totpOptions = totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// twoFactorRequired reports whether one of the user's roles must use two-factor authentication
+func twoFactorRequired(user models.User) (bool, error) {
+	if len(twoFactorConfig.Required) == 0 {
+		return false, nil
+	}
+	permissions, err := LoadPermissions(user)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range twoFactorConfig.Required {
+		if permissions.Holds(role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func startTwoFactor(userID uint, c *gin.Context) error {
+	session := sessions.Default(c)
+	session.Set(twoFactorUserID, int(userID))
+	session.Set(twoFactorExpires, time.Now().Add(twoFactorTimeout).Unix())
+	return session.Save()
+}
+
+func endTwoFactor(c *gin.Context) {
+	session := sessions.Default(c)
+	session.Delete(twoFactorUserID)
+	session.Delete(twoFactorExpires)
+}
+
+// pendingTwoFactorUser returns the user whose password was accepted in this session and who still has to send a code
+func pendingTwoFactorUser(c *gin.Context) (models.User, error) {
+	session := sessions.Default(c)
+	userID, ok := session.Get(twoFactorUserID).(int)
+	expires, ok2 := session.Get(twoFactorExpires).(int64)
+	if !ok || !ok2 || time.Now().Unix() > expires {
+		return models.User{}, fmt.Errorf("your login has expired, log in again")
+	}
+	return database.FindUser(uint(userID))
+}
+
+// newTOTPSecret returns a random base32 secret for an authenticator app
+func newTOTPSecret(user models.User) (string, error) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: twoFactorConfig.IssuerName(), AccountName: user.Email})
+	if err != nil {
+		return "", err
+	}
+	return key.Secret(), nil
+}
+
+// provisioningKey returns the otpauth key for a user's secret, which authenticator apps scan from a QR code
+func provisioningKey(user models.User) (*otp.Key, error) {
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	return totp.Generate(totp.GenerateOpts{Issuer: twoFactorConfig.IssuerName(), AccountName: user.Email, Secret: secret})
+}
+
+// qrCodeDataURI renders a key's QR code as a png the page can show inline
+func qrCodeDataURI(key *otp.Key) (template.URL, error) {
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+// matchTOTP returns the time step a code belongs to, allowing one step of clock drift either way
+func matchTOTP(secret string, code string, now time.Time) (int64, bool) {
+	if secret == "" || len(code) != otp.DigitsSix.Length() {
+		return 0, false
+	}
+	step := now.Unix() / totpPeriod
+	for _, s := range []int64{step - 1, step, step + 1} {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(s*totpPeriod, 0), totpOptions)
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+// newRecoveryCodes replaces a user's recovery codes, the codes are only returned here
+func newRecoveryCodes(userID uint) ([]string, error) {
+	codes := []string{}
+	hashes := []string{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomCharacters(recoveryCodeLetters, 10)
+		if err != nil {
+			return nil, err
+		}
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	if err := database.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkSecondFactor accepts a code from the user's authenticator app or one of their unused recovery codes, each
+// code works once
+func checkSecondFactor(user models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := matchTOTP(user.TOTPSecret, code, time.Now()); ok {
+		return database.UseTOTPStep(user.ID, step)
+	}
+	return database.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+}
+
+// TwoFactorLogin asks a user whose password was accepted for a code, or has them set up two-factor authentication
+// when their role requires it
+func TwoFactorLogin(c *gin.Context) {
+	user, err := pendingTwoFactorUser(c)
+	if err != nil {
+		ThrowError(http.StatusUnauthorized, err.Error(), c, false)
+		return
+	}
+
+	if !user.TOTPEnabled && user.TOTPSecret == "" {
+		if user.TOTPSecret, err = newTOTPSecret(user); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+		if err := database.SetTOTPSecret(user.ID, user.TOTPSecret); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+	}
+
+	renderTwoFactorLogin(c, http.StatusOK, user, "")
+}
+
+func renderTwoFactorLogin(c *gin.Context, status int, user models.User, message string) {
+	h := gin.H{
+		"email":     user.Email,
+		"enroll":    !user.TOTPEnabled,
+		"message":   message,
+		"csrfToken": c.MustGet(ContextKeyCSRFToken),
+	}
+
+	if !user.TOTPEnabled {
+		key, err := provisioningKey(user)
+		if err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+		qrCode, err := qrCodeDataURI(key)
+		if err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+		h["secret"] = key.Secret()
+		h["provisioningURI"] = key.URL()
+		h["qrCode"] = qrCode
+	}
+
+	c.HTML(status, "users-two-factor.html", h)
+}
+
+// VerifyTwoFactor checks the code sent by a user whose password was accepted and starts their session. Wrong codes
+// count towards locking the account like wrong passwords.
+func VerifyTwoFactor(c *gin.Context) {
+	user, err := pendingTwoFactorUser(c)
+	if err != nil {
+		ThrowError(http.StatusUnauthorized, err.Error(), c, false)
+		return
+	}
+
+	if user.IsLocked() {
+		endTwoFactor(c)
+		sessions.Default(c).Save()
+		ThrowError(http.StatusTooManyRequests, lockedError(*user.LockedUntil).Error(), c, false)
+		return
+	}
+
+	if !user.IsActive {
+		endTwoFactor(c)
+		sessions.Default(c).Save()
+		ThrowError(http.StatusUnauthorized, fmt.Sprintf("User %s is not active, contact a system administrator", user.Email), c, false)
+		return
+	}
+
+	code := strings.TrimSpace(c.PostForm("code"))
+	var recoveryCodes []string
+	var codeOK bool
+	if user.TOTPEnabled {
+		if codeOK, err = checkSecondFactor(user, code); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+	} else if step, ok := matchTOTP(user.TOTPSecret, code, time.Now()); ok {
+		//the user set up two-factor authentication while logging in
+		if err := database.EnableTOTP(user.ID, step); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+		if recoveryCodes, err = newRecoveryCodes(user.ID); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+		codeOK = true
+	}
+
+	if !codeOK {
+		lockedUntil, err := recordLoginFailure(user, c.ClientIP(), LoginViaWeb, "wrong code")
+		if err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+			return
+		}
+		if lockedUntil != nil {
+			endTwoFactor(c)
+			sessions.Default(c).Save()
+			ThrowError(http.StatusTooManyRequests, lockedError(*lockedUntil).Error(), c, false)
+			return
+		}
+		renderTwoFactorLogin(c, http.StatusUnauthorized, user, "the code was not accepted, try again")
+		return
+	}
+
+	endTwoFactor(c)
+	if err := RecordLoginSuccess(&user, c.ClientIP(), LoginViaWeb); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+		return
+	}
+	if err := startSession(user.ID, c); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+		return
+	}
+
+	if len(recoveryCodes) > 0 {
+		c.HTML(http.StatusOK, "users-two-factor.html", gin.H{
+			"email":         user.Email,
+			"recoveryCodes": recoveryCodes,
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/")
+}
+
+// twoFactorAccount returns the logged in user when they are changing their own two-factor authentication
+func twoFactorAccount(c *gin.Context) (models.User, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return models.User{}, err
+	}
+	user := c.MustGet(ContextKeyUser).(models.User)
+	if int(user.ID) != id {
+		return models.User{}, fmt.Errorf("you can only set up two-factor authentication for your own account")
+	}
+	return database.FindUser(user.ID)
+}
+
+// SetupTwoFactor gives the logged in user a new secret to add to their authenticator app
+func SetupTwoFactor(c *gin.Context) {
+	user, err := twoFactorAccount(c)
+	if err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
+	if user.TOTPEnabled {
+		ThrowError(http.StatusBadRequest, "two-factor authentication is already on, turn it off first to set it up again", c, true)
+		return
+	}
+
+	if user.TOTPSecret, err = newTOTPSecret(user); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+	if err := database.SetTOTPSecret(user.ID, user.TOTPSecret); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	renderTwoFactorSetup(c, user, "", nil)
+}
+
+// EnableTwoFactor turns on two-factor authentication once the user sends a code from their authenticator app
+func EnableTwoFactor(c *gin.Context) {
+	user, err := twoFactorAccount(c)
+	if err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
+	if user.TOTPEnabled || user.TOTPSecret == "" {
+		ThrowError(http.StatusBadRequest, "two-factor authentication is not being set up", c, true)
+		return
+	}
+
+	step, ok := matchTOTP(user.TOTPSecret, strings.TrimSpace(c.PostForm("code")), time.Now())
+	if !ok {
+		renderTwoFactorSetup(c, user, "the code was not accepted, check the time on your device and try again", nil)
+		return
+	}
+
+	if err := database.EnableTOTP(user.ID, step); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	recoveryCodes, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	renderTwoFactorSetup(c, user, "", recoveryCodes)
+}
+
+// RegenerateRecoveryCodes replaces the logged in user's recovery codes
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, err := twoFactorAccount(c)
+	if err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
+	if !user.TOTPEnabled {
+		ThrowError(http.StatusBadRequest, "two-factor authentication is not on", c, true)
+		return
+	}
+
+	recoveryCodes, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	renderTwoFactorSetup(c, user, "", recoveryCodes)
+}
+
+func renderTwoFactorSetup(c *gin.Context, user models.User, message string, recoveryCodes []string) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	h := gin.H{
+		"isLoggedIn":    true,
+		"isAdmin":       sessionCookies.IsAdmin,
+		"user":          user,
+		"message":       message,
+		"recoveryCodes": recoveryCodes,
+		"csrfToken":     c.MustGet(ContextKeyCSRFToken),
+	}
+
+	if recoveryCodes == nil {
+		key, err := provisioningKey(user)
+		if err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+			return
+		}
+		qrCode, err := qrCodeDataURI(key)
+		if err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+			return
+		}
+		h["secret"] = key.Secret()
+		h["provisioningURI"] = key.URL()
+		h["qrCode"] = qrCode
+	}
+
+	c.HTML(http.StatusOK, "users-two-factor-setup.html", h)
+}
+
+// ResetTwoFactor turns off a user's two-factor authentication and deletes their recovery codes. Admins may reset
+// anyone's, for example when they lose their device. Users whose role requires two-factor authentication can not
+// turn off their own.
+func ResetTwoFactor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if err := requireSelfOrAdmin(c, id); err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
+	user, err := database.FindUser(uint(id))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	if !c.MustGet(ContextKeyUser).(models.User).IsAdmin {
+		required, err := twoFactorRequired(user)
+		if err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+			return
+		}
+		if required {
+			ThrowError(http.StatusForbidden, "your role requires two-factor authentication, ask an administrator to reset it", c, true)
+			return
+		}
+	}
+
+	if err := database.ResetTwoFactor(user.ID); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/users/%d/show", user.ID))
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"crypto/md5"
 	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
@@ -11,9 +10,11 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/nyudlts/go-medialog/database"
 	"github.com/nyudlts/go-medialog/models"
+	"github.com/nyudlts/go-medialog/passwords"
 )
 
 func GetUsers(c *gin.Context) {
@@ -37,6 +38,7 @@ func GetUsers(c *gin.Context) {
 		"isAuthenticated": true,
 		"isAdmin":         isAdmin,
 		"isLoggedIn": true,
+		"csrfToken":       c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -53,6 +55,7 @@ func GetUser(c *gin.Context) {
 	cookieId, err := getUserkey(c)
 	if err != nil {
 		ThrowError(http.StatusExpectationFailed, "User is not logged in / Unauthorized", c, true)
+		return
 	}
 
 	if (uuserID != cookieId) && !sessionCookies.IsAdmin {
@@ -60,17 +63,72 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
+	renderUserShow(c, sessionCookies, user, uuserID, "")
+}
+
+// renderUserShow renders a user's page with their api keys, newKey is the plaintext of a key that was just created
+func renderUserShow(c *gin.Context, sessionCookies SessionCookies, user models.User, uuserID int, newKey string) {
 	uuser, err := database.GetRedactedUser(uuserID)
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
 
+	apiKeys, err := database.FindAPIKeysByUserID(uuser.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	roleGrants, err := database.FindRoleGrantsByUserID(uuser.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	loginAttempts, err := database.FindLoginAttemptsByUserID(uuser.ID, 10)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	required, err := twoFactorRequired(uuser)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	recoveryCodesLeft, err := database.CountUnusedRecoveryCodes(uuser.ID)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	repositories, err := database.FindRepositories()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+	repositoryMap := map[uint]string{}
+	for _, repository := range repositories {
+		repositoryMap[repository.ID] = repository.Slug
+	}
+
 	c.HTML(200, "users-show.html", gin.H{
-		"isLoggedIn": true,
-		"isAdmin":    isAdmin,
-		"uuser":      uuser,
-		"user":       user,
+		"isLoggedIn":        true,
+		"isAdmin":           sessionCookies.IsAdmin,
+		"uuser":             uuser,
+		"user":              user,
+		"apiKeys":           apiKeys,
+		"apiKeyScopes":      []string{models.APIKeyScopeRead, models.APIKeyScopeReadWrite},
+		"newKey":            newKey,
+		"roleGrants":        roleGrants,
+		"repositoryMap":     repositoryMap,
+		"roles":             models.Roles,
+		"loginAttempts":     loginAttempts,
+		"twoFactorRequired": required,
+		"recoveryCodesLeft": recoveryCodesLeft,
+		"csrfToken":         c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
@@ -83,21 +141,33 @@ func NewUser(c *gin.Context) {
 		return
 	}
 
+	repositoryMap, err := database.GetRepositoryMap()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
 	c.HTML(http.StatusOK, "users-new.html", gin.H{
 		"isAdmin":         sessionCookies.IsAdmin,
 		"isAuthenticated": true,
 		"isLoggedIn": true,
 		"user":            user,
+		"repositoryMap":   repositoryMap,
+		"roles":           models.Roles,
+		"passwordPolicy":  passwordPolicy.String(),
+		"csrfToken":       c.MustGet(ContextKeyCSRFToken),
 	})
 }
 
 type UserForm struct {
-	ID        int    `form:"id"`
-	Password1 string `form:"password_1"`
-	Password2 string `form:"password_2"`
-	Email     string `form:"email"`
-	FirstName string `form:"first_name"`
-	LastName  string `form:"last_name"`
+	ID           int    `form:"id"`
+	Password1    string `form:"password_1"`
+	Password2    string `form:"password_2"`
+	Email        string `form:"email"`
+	FirstName    string `form:"first_name"`
+	LastName     string `form:"last_name"`
+	Role         string `form:"role"`
+	RepositoryID uint   `form:"repository_id"`
 }
 
 func CreateUser(c *gin.Context) {
@@ -113,20 +183,46 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
+	if err := passwordPolicy.Check(createUser.Password1); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
 	user := models.User{}
 	user.Email = createUser.Email
 	user.FirstName = createUser.FirstName
 	user.LastName = createUser.LastName
 	user.IsActive = true
-	user.Salt = GenerateStringRunes(16)
-	hash := sha512.Sum512([]byte(createUser.Password1 + user.Salt))
-	user.EncryptedPassword = hex.EncodeToString(hash[:])
+	if err := SetPassword(&user, createUser.Password1); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	grant := models.RoleGrant{Role: createUser.Role, RepositoryID: createUser.RepositoryID}
+	if createUser.Role != "" {
+		if err := ValidateRoleGrant(grant); err != nil {
+			ThrowError(http.StatusBadRequest, err.Error(), c, true)
+			return
+		}
+	}
 
 	if _, err := database.InsertUser(&user); err != nil {
 		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
 		return
 	}
 
+	if createUser.Role != "" {
+		grantedBy, err := getUserkey(c)
+		if err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+			return
+		}
+		if _, err := GrantUserRole(user.ID, grant, grantedBy); err != nil {
+			ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+			return
+		}
+	}
+
 	c.Redirect(http.StatusFound, "/users")
 }
 
@@ -138,15 +234,13 @@ func CreateAdminUser(email string) (string, error) {
 	user.IsActive = true
 	user.CanAccessAPI = true
 	user.IsAdmin = true
-	salt := GenerateStringRunes(16)
-	md5Hash := md5.Sum([]byte(salt))
-	salt = hex.EncodeToString(md5Hash[:])
-	user.Salt = salt
-	password := GenerateStringRunes(16)
-	md5hash := md5.Sum([]byte(password))
-	password = hex.EncodeToString(md5hash[:])
-	sha512hash := sha512.Sum512([]byte(password + user.Salt))
-	user.EncryptedPassword = hex.EncodeToString(sha512hash[:])
+	password, err := GeneratePassword()
+	if err != nil {
+		return "", err
+	}
+	if err := SetPassword(&user, password); err != nil {
+		return "", err
+	}
 	if _, err := database.InsertUser(&user); err != nil {
 		return "", err
 	}
@@ -163,6 +257,11 @@ func EditUser(c *gin.Context) {
 		return
 	}
 
+	if err := requireSelfOrAdmin(c, userID); err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
 	updateUser, err := database.GetRedactedUser(userID)
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -174,6 +273,7 @@ func EditUser(c *gin.Context) {
 		"isAdmin":    sessionCookies.IsAdmin,
 		"user":       user,
 		"updateUser": updateUser,
+		"csrfToken":  c.MustGet(ContextKeyCSRFToken),
 	})
 
 }
@@ -185,6 +285,11 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if err := requireSelfOrAdmin(c, updateUser.ID); err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
 	user, err := database.FindUser(uint(updateUser.ID))
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -211,29 +316,52 @@ func AuthenticateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := database.FindUserByEmail(authUser.Email)
+	user, status, err := CheckLogin(authUser.Email, authUser.Password1, c.ClientIP(), LoginViaWeb)
 	if err != nil {
-		ThrowError(http.StatusUnauthorized, err.Error(), c, false)
+		ThrowError(status, err.Error(), c, false)
 		return
 	}
 
-	if !user.IsActive {
-		ThrowError(http.StatusUnauthorized, fmt.Sprintf("User %s is not active, contact a system administrator", user.Email), c, false)
+	required, err := twoFactorRequired(user)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+		return
 	}
 
-	hash := sha512.Sum512([]byte(authUser.Password1 + user.Salt))
-	userSHA512 := hex.EncodeToString(hash[:])
+	//users with two-factor authentication get their session once they send a code
+	if user.TOTPEnabled || required {
+		if err := startTwoFactor(user.ID, c); err != nil {
+			ThrowError(http.StatusInternalServerError, "Failed to save session", c, false)
+			return
+		}
+		c.Redirect(http.StatusFound, "/users/two_factor")
+		return
+	}
 
-	if userSHA512 != user.EncryptedPassword {
-		ThrowError(http.StatusBadRequest, "password was incorrect", c, false)
+	if err := RecordLoginSuccess(&user, c.ClientIP(), LoginViaWeb); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, false)
 		return
 	}
 
-	if err := login(int(user.ID), c); err != nil {
-		ThrowError(http.StatusInternalServerError, "Failed to save session", c, false)
+	if err := startSession(user.ID, c); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, false)
 		return
 	}
 
+	c.Redirect(http.StatusFound, "/")
+}
+
+// startSession logs a user in once every factor was checked, issuing the token the session is authenticated with
+func startSession(userID uint, c *gin.Context) error {
+	user, err := database.FindUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := login(int(user.ID), c); err != nil {
+		return fmt.Errorf("Failed to save session")
+	}
+
 	if user.IsAdmin {
 		setCookie("is-admin", true, c)
 	} else {
@@ -247,36 +375,38 @@ func AuthenticateUser(c *gin.Context) {
 	}
 
 	sessionToken := GenerateStringRunes(24)
-	hash = sha512.Sum512([]byte(sessionToken))
+	hash := sha512.Sum512([]byte(sessionToken))
 	sessionToken = hex.EncodeToString(hash[:])
 	setCookie("token", sessionToken, c)
 
 	token := models.Token{
-		Token:   sessionToken,
-		UserID:  user.ID,
-		Expires: time.Now().Add(time.Hour * 3),
-		IsValid: true,
-		Type:    "application",
+		Token:     sessionToken,
+		UserID:    user.ID,
+		Expires:   time.Now().Add(time.Hour * 3),
+		IsValid:   true,
+		Type:      "application",
+		SessionID: sessions.Default(c).ID(),
+		IPAddress: c.ClientIP(),
 	}
 
 	ExpireTokens()
 
 	if err := database.ExpireAppTokensByUserID(user.ID); err != nil {
-		ThrowError(http.StatusInternalServerError, "could not expire tokens for users", c, false)
+		return fmt.Errorf("could not expire tokens for users")
 	}
 
 	if err := database.InsertToken(&token); err != nil {
-		ThrowError(http.StatusInternalServerError, "could not save session token", c, false)
+		return fmt.Errorf("could not save session token")
 	}
 
 	user.SignInCount = user.SignInCount + 1
 	user.PreviousIPAddress = user.CurrentIPAddress
 	user.CurrentIPAddress = c.ClientIP()
 	if err := database.UpdateUser(&user); err != nil {
-		ThrowError(http.StatusInternalServerError, "failed to update user", c, false)
+		return fmt.Errorf("failed to update user")
 	}
 
-	c.Redirect(http.StatusFound, "/")
+	return nil
 }
 
 func ResetUserPassword(c *gin.Context) {
@@ -288,6 +418,11 @@ func ResetUserPassword(c *gin.Context) {
 		return
 	}
 
+	if err := requireSelfOrAdmin(c, id); err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
 	user, err := database.FindUserByID(id)
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -299,6 +434,8 @@ func ResetUserPassword(c *gin.Context) {
 		"isAdmin":         isAdmin,
 		"isAuthenticated": true,
 		"isLoggedIn": true,
+		"passwordPolicy":  passwordPolicy.String(),
+		"csrfToken":       c.MustGet(ContextKeyCSRFToken),
 	})
 
 }
@@ -310,20 +447,31 @@ func ResetPassword(c *gin.Context) {
 		return
 	}
 
+	if err := requireSelfOrAdmin(c, resetUser.ID); err != nil {
+		ThrowError(http.StatusForbidden, err.Error(), c, true)
+		return
+	}
+
 	if resetUser.Password1 != resetUser.Password2 {
 		ThrowError(http.StatusBadRequest, "passwords do not match", c, true)
 		return
 	}
 
+	if err := passwordPolicy.Check(resetUser.Password1); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
 	user, err := database.FindUserByID(resetUser.ID)
 	if err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
 		return
 	}
 
-	user.Salt = GenerateStringRunes(16)
-	hash := sha512.Sum512([]byte(resetUser.Password1 + user.Salt))
-	user.EncryptedPassword = hex.EncodeToString(hash[:])
+	if err := SetPassword(&user, resetUser.Password1); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
 
 	if err := database.UpdateUser(&user); err != nil {
 		ThrowError(http.StatusBadRequest, err.Error(), c, true)
@@ -474,7 +622,15 @@ func RemoveUserAdmin(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/users")
 }
 
-func LoginUser(c *gin.Context) { c.HTML(http.StatusOK, "users-login.html", gin.H{}) }
+func LoginUser(c *gin.Context) {
+	token, err := CSRFToken(c)
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, false)
+		return
+	}
+
+	c.HTML(http.StatusOK, "users-login.html", gin.H{"csrfToken": token})
+}
 
 func LogoutUser(c *gin.Context) {
 
@@ -491,6 +647,43 @@ func GenerateStringRunes(n int) string {
 	return string(b)
 }
 
+// SetPassword hashes a password with the default hasher, it does not save the user
+func SetPassword(user *models.User, password string) error {
+	encryptedPassword, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	user.EncryptedPassword = encryptedPassword
+	user.Salt = ""
+	return nil
+}
+
+// CheckPassword verifies a user's password and upgrades hashes not made by the default hasher after a successful check
+func CheckPassword(user *models.User, password string) (bool, error) {
+	encryptedPassword := user.EncryptedPassword
+	if !passwords.IsTagged(encryptedPassword) {
+		//untagged hashes predate the password hash migration
+		encryptedPassword = passwords.EncodeLegacy(user.Salt, encryptedPassword)
+	}
+
+	ok, err := passwords.Verify(password, encryptedPassword)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if passwords.NeedsRehash(encryptedPassword) {
+		if err := SetPassword(user, password); err != nil {
+			return true, err
+		}
+		if err := database.UpdateUser(user); err != nil {
+			return true, err
+		}
+		log.Printf("[INFO] upgraded password hash for user %d to %s", user.ID, passwords.Default().Algorithm())
+	}
+
+	return true, nil
+}
+
 func getUserEmailMap(ids []int) (map[int]string, error) {
 	users := map[int]string{}
 	for _, id := range ids {
@@ -507,6 +700,15 @@ func getUserEmailMap(ids []int) (map[int]string, error) {
 	return users, nil
 }
 
+// requireSelfOrAdmin allows users to change their own account and admins to change any account
+func requireSelfOrAdmin(c *gin.Context, userID int) error {
+	user := c.MustGet(ContextKeyUser).(models.User)
+	if int(user.ID) != userID && !user.IsAdmin {
+		return fmt.Errorf("you can only change your own account")
+	}
+	return nil
+}
+
 func DeleteUser(id uint) error {
 
 	if err := database.DeleteUser(id); err != nil {
@@ -1,5 +1,17 @@
 package controllers
 
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
 var is_refreshed = map[bool]string{true: "yes", false: "no"}
 
 var entryStatuses = map[string]string{
@@ -9,13 +21,11 @@ var entryStatuses = map[string]string{
 	"":                   "unknown",
 }
 
-func GetEntryStatuses() map[string]string { return entryStatuses }
+func GetEntryStatuses() map[string]string { return getVocabulary(VocabularyEntryStatuses) }
 
 func GetEntryStatus(s string) string {
-	for k, v := range entryStatuses {
-		if k == s {
-			return v
-		}
+	if label, ok := lookupTerm(VocabularyEntryStatuses, s); ok {
+		return label
 	}
 	return "No Match"
 }
@@ -34,25 +44,20 @@ var storageLocations = map[string]string{
 	"sl_unknown":              "unknown",
 }
 
-func GetStorageLocations() map[string]string { return storageLocations }
+func GetStorageLocations() map[string]string { return getVocabulary(VocabularyStorageLocations) }
 
 func GetStorageLocation(s string) string {
-	for k, v := range storageLocations {
-		if k == s {
-			return v
-		}
+	if label, ok := lookupTerm(VocabularyStorageLocations, s); ok {
+		return label
 	}
 	return "No Match"
 }
 
-func GetMediatypes() map[string]string { return Mediatypes }
+func GetMediatypes() map[string]string { return getVocabulary(VocabularyMediatypes) }
 
 func GetMediaType(s string) string {
-
-	for k, v := range Mediatypes {
-		if k == s {
-			return v
-		}
+	if label, ok := lookupTerm(VocabularyMediatypes, s); ok {
+		return label
 	}
 	return "No Match"
 }
@@ -83,7 +88,50 @@ var Mediatypes = map[string]string{
 	"mediatype_zip":              "Zip Disk",
 }
 
-func getInterfaces() map[string]string { return interfaces }
+const (
+	MediaCategoryFloppy   = "floppy"
+	MediaCategoryOptical  = "optical"
+	MediaCategoryHDD      = "hdd"
+	MediaCategoryTransfer = "transfer"
+)
+
+// MediatypeCategory groups a mediatype by the technical metadata that applies to it, the entry forms
+// only show the fields of the selected mediatype's category. Unknown mediatypes get no category and see every field.
+func MediatypeCategory(mediatype string) string {
+	switch {
+	case strings.HasPrefix(mediatype, "mediatype_floppy"):
+		return MediaCategoryFloppy
+	case strings.HasPrefix(mediatype, "mediatype_cd"), strings.HasPrefix(mediatype, "mediatype_dvd"), mediatype == "mediatype_laserdisc", mediatype == "mediatype_minidisc":
+		return MediaCategoryOptical
+	case strings.HasSuffix(mediatype, "_transfer"):
+		return MediaCategoryTransfer
+	case mediatype == "":
+		return ""
+	}
+	if _, ok := Mediatypes[mediatype]; ok {
+		return MediaCategoryHDD
+	}
+	return ""
+}
+
+// ValidateTechnicalMetadata checks that an entry's filesystems, encoding scheme and disc structure are vocabulary terms,
+// retired terms are accepted so an entry that already has one can still be saved
+func ValidateTechnicalMetadata(entry models.Entry) error {
+	for _, filesystem := range entry.Filesystems {
+		if _, ok := lookupTerm(VocabularyFilesystems, filesystem); !ok || filesystem == "" {
+			return fmt.Errorf("filesystem: `%s` is not valid", filesystem)
+		}
+	}
+	if _, ok := lookupTerm(VocabularyEncodingSchemes, entry.EncodingScheme); !ok && entry.EncodingScheme != "" {
+		return fmt.Errorf("encoding scheme: `%s` is not valid", entry.EncodingScheme)
+	}
+	if _, ok := lookupTerm(VocabularyStructures, entry.Structure); !ok && entry.Structure != "" {
+		return fmt.Errorf("structure: `%s` is not valid", entry.Structure)
+	}
+	return nil
+}
+
+func getInterfaces() map[string]string { return getVocabulary(VocabularyInterfaces) }
 
 var interfaces = map[string]string{
 	"":                                  "",
@@ -96,15 +144,11 @@ var interfaces = map[string]string{
 }
 
 func getInterface(s string) string {
-	for k, y := range interfaces {
-		if k == s {
-			return y
-		}
-	}
-	return ""
+	label, _ := lookupTerm(VocabularyInterfaces, s)
+	return label
 }
 
-func getImagingSoftware() map[string]string { return imaging_software }
+func getImagingSoftware() map[string]string { return getVocabulary(VocabularyImagingSoftware) }
 
 var imaging_software = map[string]string{
 	"":                                      "",
@@ -132,7 +176,7 @@ var hdd_interfaces = map[string]string{
 	"hdd_interface_ide":   "IDE",
 }
 
-func getImageFormats() map[string]string { return image_formats }
+func getImageFormats() map[string]string { return getVocabulary(VocabularyImageFormats) }
 
 var image_formats = map[string]string{
 	"":                     "",
@@ -146,12 +190,16 @@ var image_formats = map[string]string{
 	"image_format_wavcue":  "WAV/CUE",
 }
 
+func getEncodingSchemes() map[string]string { return getVocabulary(VocabularyEncodingSchemes) }
+
 var encoding_schemes = map[string]string{
 	"":                              "",
 	"encoding_scheme_mfm":           "MFM",
 	"encoding_scheme_apple_400_800": "Apple 400/800",
 }
 
+func getFilesystems() map[string]string { return getVocabulary(VocabularyFilesystems) }
+
 var filesystems = map[string]string{
 	"":                       "",
 	"filesystem_fat12":       "FAT12",
@@ -184,7 +232,7 @@ var interpret_success = map[string]string{
 	"interpret_success_no":         "No",
 }
 
-func getStockUnits() map[string]string { return stock_unit }
+func getStockUnits() map[string]string { return getVocabulary(VocabularyStockUnits) }
 
 var stock_unit = map[string]string{
 	"":   "",
@@ -223,6 +271,8 @@ var content_type = map[string]string{
 	"content_email": "Email", //this should be removed
 }
 
+func getStructures() map[string]string { return getVocabulary(VocabularyStructures) }
+
 var structure = map[string]string{
 	"":                   "",
 	"structure_data":     "Data Disc",
@@ -230,3 +280,179 @@ var structure = map[string]string{
 	"structure_cdda":     "Compact Disc Digital Audio",
 	"structure_complex":  "Complex Optical Image",
 }
+
+// controlled vocabularies stored in the vocabulary_terms table, the maps above are their seed values
+const (
+	VocabularyMediatypes       = "mediatypes"
+	VocabularyStorageLocations = "storage_locations"
+	VocabularyInterfaces       = "interfaces"
+	VocabularyImagingSoftware  = "imaging_software"
+	VocabularyImageFormats     = "image_formats"
+	VocabularyStockUnits       = "stock_units"
+	VocabularyEntryStatuses    = "entry_statuses"
+	VocabularyFilesystems      = "filesystems"
+	VocabularyEncodingSchemes  = "encoding_schemes"
+	VocabularyStructures       = "structures"
+)
+
+var vocabularyDefaults = map[string]map[string]string{
+	VocabularyMediatypes:       Mediatypes,
+	VocabularyStorageLocations: storageLocations,
+	VocabularyInterfaces:       interfaces,
+	VocabularyImagingSoftware:  imaging_software,
+	VocabularyImageFormats:     image_formats,
+	VocabularyStockUnits:       stock_unit,
+	VocabularyEntryStatuses:    entryStatuses,
+	VocabularyFilesystems:      filesystems,
+	VocabularyEncodingSchemes:  encoding_schemes,
+	VocabularyStructures:       structure,
+}
+
+var vocabularyTitles = map[string]string{
+	VocabularyMediatypes:       "Mediatypes",
+	VocabularyStorageLocations: "Storage Locations",
+	VocabularyInterfaces:       "Interfaces",
+	VocabularyImagingSoftware:  "Imaging Software",
+	VocabularyImageFormats:     "Image Formats",
+	VocabularyStockUnits:       "Stock Units",
+	VocabularyEntryStatuses:    "Entry Statuses",
+	VocabularyFilesystems:      "Filesystems",
+	VocabularyEncodingSchemes:  "Encoding Schemes",
+	VocabularyStructures:       "Disc Structures",
+}
+
+func GetVocabularyNames() map[string]string { return vocabularyTitles }
+
+func IsVocabulary(vocabulary string) bool {
+	_, ok := vocabularyDefaults[vocabulary]
+	return ok
+}
+
+// vocabularyCache holds every term, including retired ones, keyed by vocabulary then key
+var vocabularyCache = struct {
+	sync.RWMutex
+	terms map[string]map[string]models.VocabularyTerm
+}{}
+
+// LoadVocabularies seeds any empty vocabulary from its defaults and reloads the cache
+func LoadVocabularies() error {
+	for vocabulary, defaults := range vocabularyDefaults {
+		if err := database.SeedVocabulary(vocabulary, defaults); err != nil {
+			return err
+		}
+	}
+
+	terms, err := database.FindVocabularyTerms()
+	if err != nil {
+		return err
+	}
+
+	cache := map[string]map[string]models.VocabularyTerm{}
+	for _, term := range terms {
+		if _, ok := cache[term.Vocabulary]; !ok {
+			cache[term.Vocabulary] = map[string]models.VocabularyTerm{}
+		}
+		cache[term.Vocabulary][term.Key] = term
+	}
+
+	vocabularyCache.Lock()
+	vocabularyCache.terms = cache
+	vocabularyCache.Unlock()
+	return nil
+}
+
+// InvalidateVocabularies drops the cache so the next lookup reloads the terms from the database
+func InvalidateVocabularies() {
+	vocabularyCache.Lock()
+	vocabularyCache.terms = nil
+	vocabularyCache.Unlock()
+}
+
+// vocabularyTerms returns the cached terms of a vocabulary, falling back to the defaults when the table cannot be read
+func vocabularyTerms(vocabulary string) map[string]models.VocabularyTerm {
+	vocabularyCache.RLock()
+	terms := vocabularyCache.terms
+	vocabularyCache.RUnlock()
+
+	if terms == nil {
+		if err := LoadVocabularies(); err != nil {
+			log.Printf("[WARNING] could not load vocabularies, using defaults: %s", err.Error())
+			defaults := map[string]models.VocabularyTerm{}
+			for key, label := range vocabularyDefaults[vocabulary] {
+				defaults[key] = models.VocabularyTerm{Vocabulary: vocabulary, Key: key, Label: label}
+			}
+			return defaults
+		}
+		vocabularyCache.RLock()
+		terms = vocabularyCache.terms
+		vocabularyCache.RUnlock()
+	}
+
+	return terms[vocabulary]
+}
+
+// GetVocabularyTerms returns the cached terms of a vocabulary ordered by label, retired terms only when asked for
+func GetVocabularyTerms(vocabulary string, includeRetired bool) []models.VocabularyTerm {
+	terms := []models.VocabularyTerm{}
+	for _, term := range vocabularyTerms(vocabulary) {
+		if !term.IsRetired || includeRetired {
+			terms = append(terms, term)
+		}
+	}
+	slices.SortFunc(terms, func(a, b models.VocabularyTerm) int {
+		return cmp.Or(cmp.Compare(a.Label, b.Label), cmp.Compare(a.Key, b.Key))
+	})
+	return terms
+}
+
+// getVocabulary returns the active terms of a vocabulary as key/label pairs for select options
+func getVocabulary(vocabulary string) map[string]string {
+	options := map[string]string{}
+	for key, term := range vocabularyTerms(vocabulary) {
+		if !term.IsRetired {
+			options[key] = term.Label
+		}
+	}
+	return options
+}
+
+// getVocabularyWithRetired returns every term of a vocabulary, used for filters over existing records
+func getVocabularyWithRetired(vocabulary string) map[string]string {
+	options := map[string]string{}
+	for key, term := range vocabularyTerms(vocabulary) {
+		options[key] = term.Label
+	}
+	return options
+}
+
+// vocabularyOptions returns the active terms plus the current value, so editing a record does not drop a retired term
+func vocabularyOptions(vocabulary string, current string) map[string]string {
+	options := getVocabulary(vocabulary)
+	if label, ok := lookupTerm(vocabulary, current); ok {
+		options[current] = label
+	}
+	return options
+}
+
+// vocabularyListOptions returns the active terms plus every current value, for fields that hold several terms
+func vocabularyListOptions(vocabulary string, current []string) map[string]string {
+	options := getVocabulary(vocabulary)
+	for _, key := range current {
+		if label, ok := lookupTerm(vocabulary, key); ok {
+			options[key] = label
+		}
+	}
+	return options
+}
+
+// lookupTerm returns the label of a key, retired terms are still resolved for existing records
+func lookupTerm(vocabulary string, key string) (string, bool) {
+	term, ok := vocabularyTerms(vocabulary)[key]
+	return term.Label, ok
+}
+
+// IsActiveTerm reports whether a key is a current, non-retired term of a vocabulary
+func IsActiveTerm(vocabulary string, key string) bool {
+	term, ok := vocabularyTerms(vocabulary)[key]
+	return ok && !term.IsRetired
+}
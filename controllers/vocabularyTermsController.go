@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+const vocabularyAdminOnly = "Must be logged in as an admin to manage vocabularies"
+
+type vocabularyListing struct {
+	Name  string
+	Title string
+	Terms []models.VocabularyTerm
+}
+
+func GetVocabularies(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, vocabularyAdminOnly, c, true)
+		return
+	}
+
+	//make sure any missing vocabularies are seeded before listing them
+	if err := LoadVocabularies(); err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	terms, err := database.FindVocabularyTerms()
+	if err != nil {
+		ThrowError(http.StatusInternalServerError, err.Error(), c, true)
+		return
+	}
+
+	grouped := map[string][]models.VocabularyTerm{}
+	for _, term := range terms {
+		grouped[term.Vocabulary] = append(grouped[term.Vocabulary], term)
+	}
+
+	listings := []vocabularyListing{}
+	for name, title := range vocabularyTitles {
+		listings = append(listings, vocabularyListing{Name: name, Title: title, Terms: grouped[name]})
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].Title < listings[j].Title })
+
+	c.HTML(http.StatusOK, "vocabularies-index.html", gin.H{
+		"vocabularies": listings,
+		"isAdmin":      sessionCookies.IsAdmin,
+		"isLoggedIn":   true,
+		"user":         user,
+		"csrfToken":    c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+func NewVocabularyTerm(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, vocabularyAdminOnly, c, true)
+		return
+	}
+
+	c.HTML(http.StatusOK, "vocabularies-new.html", gin.H{
+		"vocabularies": vocabularyTitles,
+		"vocabulary":   c.Query("vocabulary"),
+		"isAdmin":      sessionCookies.IsAdmin,
+		"isLoggedIn":   true,
+		"user":         user,
+		"csrfToken":    c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+func CreateVocabularyTerm(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, vocabularyAdminOnly, c, true)
+		return
+	}
+
+	term := models.VocabularyTerm{}
+	if err := c.Bind(&term); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	term.Key = strings.TrimSpace(term.Key)
+	term.Label = strings.TrimSpace(term.Label)
+	if !IsVocabulary(term.Vocabulary) {
+		ThrowError(http.StatusBadRequest, fmt.Sprintf("%s is not a controlled vocabulary", term.Vocabulary), c, true)
+		return
+	}
+	if term.Key == "" || term.Label == "" {
+		ThrowError(http.StatusBadRequest, "a vocabulary term requires a key and a label", c, true)
+		return
+	}
+	if _, ok := lookupTerm(term.Vocabulary, term.Key); ok {
+		ThrowError(http.StatusBadRequest, fmt.Sprintf("%s already contains the key %s", term.Vocabulary, term.Key), c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	term.ID = 0
+	term.IsRetired = false
+	term.CreatedAt = time.Now()
+	term.CreatedBy = userID
+	term.UpdatedAt = time.Now()
+	term.UpdatedBy = userID
+
+	if err := database.InsertVocabularyTerm(&term); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+	InvalidateVocabularies()
+
+	c.Redirect(http.StatusFound, "/vocabularies")
+}
+
+func EditVocabularyTerm(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+	user := c.MustGet(ContextKeyUser).(models.User)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, vocabularyAdminOnly, c, true)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	term, err := database.FindVocabularyTerm(uint(id))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	c.HTML(http.StatusOK, "vocabularies-edit.html", gin.H{
+		"term":            term,
+		"vocabularyTitle": vocabularyTitles[term.Vocabulary],
+		"isAdmin":         sessionCookies.IsAdmin,
+		"isLoggedIn":      true,
+		"user":            user,
+		"csrfToken":       c.MustGet(ContextKeyCSRFToken),
+	})
+}
+
+// UpdateVocabularyTerm changes the label of a term, keys are fixed since entries store them
+func UpdateVocabularyTerm(c *gin.Context) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, vocabularyAdminOnly, c, true)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	term, err := database.FindVocabularyTerm(uint(id))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	label := strings.TrimSpace(c.PostForm("label"))
+	if label == "" {
+		ThrowError(http.StatusBadRequest, "a vocabulary term requires a label", c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	term.Label = label
+	term.UpdatedAt = time.Now()
+	term.UpdatedBy = userID
+
+	if err := database.UpdateVocabularyTerm(&term); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+	InvalidateVocabularies()
+
+	c.Redirect(http.StatusFound, "/vocabularies")
+}
+
+func RetireVocabularyTerm(c *gin.Context) { setVocabularyTermRetired(c, true) }
+
+func RestoreVocabularyTerm(c *gin.Context) { setVocabularyTermRetired(c, false) }
+
+// setVocabularyTermRetired hides or restores a term in forms, terms are never deleted so existing entries keep their labels
+func setVocabularyTermRetired(c *gin.Context, retired bool) {
+	sessionCookies := c.MustGet(ContextKeySessionCookies).(SessionCookies)
+
+	if !sessionCookies.IsAdmin {
+		ThrowError(http.StatusUnauthorized, vocabularyAdminOnly, c, true)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	term, err := database.FindVocabularyTerm(uint(id))
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	userID, err := getUserkey(c)
+	if err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+
+	term.IsRetired = retired
+	term.UpdatedAt = time.Now()
+	term.UpdatedBy = userID
+
+	if err := database.UpdateVocabularyTerm(&term); err != nil {
+		ThrowError(http.StatusBadRequest, err.Error(), c, true)
+		return
+	}
+	InvalidateVocabularies()
+
+	c.Redirect(http.StatusFound, "/vocabularies")
+}
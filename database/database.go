@@ -3,11 +3,17 @@ package database
 import (
 	"fmt"
 
+	"github.com/glebarez/sqlite"
 	"github.com/nyudlts/go-medialog/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
 var db *gorm.DB
 
 type Pagination struct {
@@ -20,6 +26,16 @@ type Pagination struct {
 	Filter       string `json:"filter"`
 }
 
+// inRepositories limits a query to rows filed in some repositories, nil leaves it unlimited
+func inRepositories(column string, repositoryIDs []uint) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if repositoryIDs == nil {
+			return tx
+		}
+		return tx.Where(fmt.Sprintf("%s IN ?", column), repositoryIDs)
+	}
+}
+
 type DateRange struct {
 	StartYear    int `form:"start-year"`
 	StartMonth   int `form:"start-month"`
@@ -31,19 +47,62 @@ type DateRange struct {
 	IsRefreshed  bool
 }
 
+// Connect opens the database selected by the driver in the config, defaulting to mysql
+func Connect(dbconfig models.DatabaseConfig, gormDebug bool) error {
+	switch dbconfig.Driver {
+	case "", DriverMySQL:
+		return ConnectMySQL(dbconfig, gormDebug)
+	case DriverSQLite:
+		return ConnectSQLite(dbconfig, gormDebug)
+	default:
+		return fmt.Errorf("unsupported database driver: %s", dbconfig.Driver)
+	}
+}
+
 func ConnectMySQL(dbconfig models.DatabaseConfig, gormDebug bool) error {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", dbconfig.Username, dbconfig.Password, dbconfig.URL, dbconfig.Port, dbconfig.DatabaseName)
 	var err error
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	//translated errors let a unique index violation be told apart from other failures
+	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return err
 	}
 
 	if gormDebug {
-		db.Debug()
+		db = db.Debug()
 	}
 	return nil
 
 }
 
+// ConnectSQLite opens a sqlite database file, use `:memory:` as the path for an in-memory database
+func ConnectSQLite(dbconfig models.DatabaseConfig, gormDebug bool) error {
+	if dbconfig.Path == "" {
+		return fmt.Errorf("no path provided for sqlite database")
+	}
+
+	dsn := dbconfig.Path
+	if dsn == ":memory:" {
+		dsn = "file::memory:?cache=shared"
+	}
+
+	var err error
+	db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return err
+	}
+
+	//sqlite only allows a single writer
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if gormDebug {
+		db = db.Debug()
+	}
+	return nil
+}
+
 func GetDB() *gorm.DB { return db }
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"time"
+
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func InsertAPIKey(apiKey *models.APIKey) error {
+	if err := db.Create(apiKey).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func FindAPIKey(key string) (models.APIKey, error) {
+	apiKey := models.APIKey{}
+	if err := db.Where("key_hash = ?", models.HashAPIKey(key)).First(&apiKey).Error; err != nil {
+		return apiKey, err
+	}
+	return apiKey, nil
+}
+
+func FindAPIKeyByID(id uint) (models.APIKey, error) {
+	apiKey := models.APIKey{}
+	if err := db.Where("id = ?", id).First(&apiKey).Error; err != nil {
+		return apiKey, err
+	}
+	return apiKey, nil
+}
+
+func FindAPIKeysByUserID(userID uint) ([]models.APIKey, error) {
+	apiKeys := []models.APIKey{}
+	if err := db.Where("user_id = ?", userID).Order("created_at desc").Find(&apiKeys).Error; err != nil {
+		return apiKeys, err
+	}
+	return apiKeys, nil
+}
+
+func RevokeAPIKey(id uint) error {
+	if err := db.Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func TouchAPIKey(id uint) error {
+	if err := db.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -53,11 +53,9 @@ func UpdateAccession(accession *models.Accession) error {
 	return nil
 }
 
+// DeleteAccession deletes an accession with no children to the trash, otherwise it returns a *DependentsError
 func DeleteAccession(id uint) error {
-	if err := db.Delete(models.Accession{}, id).Error; err != nil {
-		return err
-	}
-	return nil
+	return deleteWithoutDependents(&models.Accession{}, "accession", id, findAccessionDependents)
 }
 
 func CountAccessions() int64 {
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeleteSummary counts the rows below a repository, resource or accession
+type DeleteSummary struct {
+	Resources  int64 `json:"resources"`
+	Accessions int64 `json:"accessions"`
+	Entries    int64 `json:"entries"`
+}
+
+func (s DeleteSummary) HasDependents() bool {
+	return s.Resources > 0 || s.Accessions > 0 || s.Entries > 0
+}
+
+func (s DeleteSummary) String() string {
+	counts := []string{}
+	for _, count := range []struct {
+		n    int64
+		noun string
+	}{{s.Resources, "resource"}, {s.Accessions, "accession"}, {s.Entries, "entry"}} {
+		switch {
+		case count.n == 1:
+			counts = append(counts, fmt.Sprintf("1 %s", count.noun))
+		case count.n > 1 && count.noun == "entry":
+			counts = append(counts, fmt.Sprintf("%d entries", count.n))
+		case count.n > 1:
+			counts = append(counts, fmt.Sprintf("%d %ss", count.n, count.noun))
+		}
+	}
+	if len(counts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(counts, ", ")
+}
+
+// DependentsError is returned when deleting a row that still has children without cascading
+type DependentsError struct {
+	Object  string
+	ID      uint
+	Summary DeleteSummary
+}
+
+func (e *DependentsError) Error() string {
+	return fmt.Sprintf("%s %d still has %s, delete them first or cascade the delete", e.Object, e.ID, e.Summary.String())
+}
+
+func repositoryResources(tx *gorm.DB, id uint) *gorm.DB {
+	return tx.Model(&models.Resource{}).Select("id").Where("repository_id = ?", id)
+}
+
+func FindRepositoryDependents(id uint) (DeleteSummary, error) {
+	return findRepositoryDependents(db, id)
+}
+
+func findRepositoryDependents(tx *gorm.DB, id uint) (DeleteSummary, error) {
+	summary := DeleteSummary{}
+	if err := tx.Model(&models.Resource{}).Where("repository_id = ?", id).Count(&summary.Resources).Error; err != nil {
+		return summary, err
+	}
+	if err := tx.Model(&models.Accession{}).Where("resource_id IN (?)", repositoryResources(tx, id)).Count(&summary.Accessions).Error; err != nil {
+		return summary, err
+	}
+	if err := tx.Model(&models.Entry{}).Where("repository_id = ? OR resource_id IN (?)", id, repositoryResources(tx, id)).Count(&summary.Entries).Error; err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+func FindResourceDependents(id uint) (DeleteSummary, error) { return findResourceDependents(db, id) }
+
+func findResourceDependents(tx *gorm.DB, id uint) (DeleteSummary, error) {
+	summary := DeleteSummary{}
+	if err := tx.Model(&models.Accession{}).Where("resource_id = ?", id).Count(&summary.Accessions).Error; err != nil {
+		return summary, err
+	}
+	if err := tx.Model(&models.Entry{}).Where("resource_id = ?", id).Count(&summary.Entries).Error; err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+func FindAccessionDependents(id uint) (DeleteSummary, error) { return findAccessionDependents(db, id) }
+
+func findAccessionDependents(tx *gorm.DB, id uint) (DeleteSummary, error) {
+	summary := DeleteSummary{}
+	if err := tx.Model(&models.Entry{}).Where("accession_id = ?", id).Count(&summary.Entries).Error; err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// deleteWithoutDependents moves a row with no children to the trash, otherwise it returns a *DependentsError. The
+// row is locked while its children are counted so none can be added under it before it is trashed.
+func deleteWithoutDependents(model interface{}, object string, id uint, dependents func(*gorm.DB, uint) (DeleteSummary, error)) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := lockRow(tx, model, id); err != nil {
+			return err
+		}
+		summary, err := dependents(tx, id)
+		if err != nil {
+			return err
+		}
+		if summary.HasDependents() {
+			return &DependentsError{Object: object, ID: id, Summary: summary}
+		}
+		return trashRow(tx, model, id, trashTime())
+	})
+}
+
+// lockRow locks a row that is not in the trash until the end of the transaction
+func lockRow(tx *gorm.DB, model interface{}, id uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", id).Take(model).Error
+}
+
+// subtreeLevel selects the rows of one table below a repository, resource or accession
+type subtreeLevel struct {
+	model interface{}
+	query string
+	args  []interface{}
+	count *int64
+}
+
+// subtree lists the levels below an object from the bottom up, tx decides whether trashed rows are included
+func subtree(tx *gorm.DB, model interface{}, id uint, summary *DeleteSummary) []subtreeLevel {
+	switch model.(type) {
+	case *models.Repository:
+		return []subtreeLevel{
+			{&models.Entry{}, "repository_id = ? OR resource_id IN (?)", []interface{}{id, repositoryResources(tx, id)}, &summary.Entries},
+			{&models.Accession{}, "resource_id IN (?)", []interface{}{repositoryResources(tx, id)}, &summary.Accessions},
+			{&models.Resource{}, "repository_id = ?", []interface{}{id}, &summary.Resources},
+		}
+	case *models.Resource:
+		return []subtreeLevel{
+			{&models.Entry{}, "resource_id = ?", []interface{}{id}, &summary.Entries},
+			{&models.Accession{}, "resource_id = ?", []interface{}{id}, &summary.Accessions},
+		}
+	case *models.Accession:
+		return []subtreeLevel{
+			{&models.Entry{}, "accession_id = ?", []interface{}{id}, &summary.Entries},
+		}
+	}
+	return []subtreeLevel{}
+}
+
+// trashTime is the deletion time stamped on every row of a cascade, restoring the parent restores the rows trashed with it
+func trashTime() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// deleteCascade moves an object and everything below it to the trash in one transaction
+func deleteCascade(model interface{}, id uint) (DeleteSummary, error) {
+	summary := DeleteSummary{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockRow(tx, model, id); err != nil {
+			return err
+		}
+		deletedAt := trashTime()
+		for _, level := range subtree(tx, model, id, &summary) {
+			result := tx.Model(level.model).Where(level.query, level.args...).UpdateColumn("deleted_at", deletedAt)
+			if result.Error != nil {
+				return result.Error
+			}
+			*level.count = result.RowsAffected
+		}
+		return trashRow(tx, model, id, deletedAt)
+	})
+	return summary, err
+}
+
+// DeleteRepositoryCascade moves a repository with all of its resources, accessions and entries to the trash
+func DeleteRepositoryCascade(id uint) (DeleteSummary, error) {
+	return deleteCascade(&models.Repository{}, id)
+}
+
+// DeleteResourceCascade moves a resource with all of its accessions and entries to the trash
+func DeleteResourceCascade(id uint) (DeleteSummary, error) {
+	return deleteCascade(&models.Resource{}, id)
+}
+
+// DeleteAccessionCascade moves an accession with all of its entries to the trash
+func DeleteAccessionCascade(id uint) (DeleteSummary, error) {
+	return deleteCascade(&models.Accession{}, id)
+}
+
+// trashRow soft deletes a single row, it fails if the row does not exist or is already in the trash
+func trashRow(tx *gorm.DB, model interface{}, id interface{}, deletedAt time.Time) error {
+	result := tx.Model(model).Where("id = ?", id).UpdateColumn("deleted_at", deletedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
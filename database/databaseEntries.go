@@ -1,68 +1,80 @@
 package database
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 
 	"github.com/google/uuid"
 	"github.com/nyudlts/bytemath"
 	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// InsertEntry creates an entry and its search json in one transaction
 func InsertEntry(entry *models.Entry) error {
-	if err := db.Create(&entry).Error; err != nil {
-		return err
-	}
-
-	if err := InsertEntryJSON(*entry); err != nil {
-		return err
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entry).Error; err != nil {
+			return mediaIDError(err, *entry)
+		}
 
-	return nil
+		return insertEntryJSON(tx, *entry)
+	})
 }
 
-func DeleteEntry(id uuid.UUID) error {
-	ej, err := FindEntryJSONByEntryID(id)
-	if err != nil {
-		return err
-	}
+// InsertEntryWithNextMediaID creates an entry numbered with the next free media id in its resource
+func InsertEntryWithNextMediaID(entry *models.Entry) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		mediaID, err := allocateMediaIDs(tx, entry.ResourceID, 1)
+		if err != nil {
+			return err
+		}
 
-	if err := DeleteEntryJSON(ej.ID); err != nil {
-		return err
-	}
+		entry.MediaID = mediaID
+		if err := tx.Create(&entry).Error; err != nil {
+			return mediaIDError(err, *entry)
+		}
 
-	if err := db.Delete(models.Entry{}, id).Error; err != nil {
-		return err
-	}
+		return insertEntryJSON(tx, *entry)
+	})
+}
 
-	return nil
+// DeleteEntry moves an entry to the trash, its search json is kept so it can be restored
+func DeleteEntry(id uuid.UUID) error {
+	return trashRow(db, &models.Entry{}, id, trashTime())
 }
 
+// UpdateEntry saves an entry, records a revision of the changed fields and refreshes its search json in one transaction
 func UpdateEntry(entry *models.Entry) error {
-	if err := db.Save(entry).Error; err != nil {
-		return err
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return updateEntry(tx, entry)
+	})
+}
 
-	ej, err := FindEntryJSONByEntryID(entry.ID)
-	if err != nil {
+func updateEntry(tx *gorm.DB, entry *models.Entry) error {
+	original := models.Entry{}
+	if err := tx.Where("id = ?", entry.ID).First(&original).Error; err != nil {
 		return err
 	}
 
-	em := entry.Minimal()
-	emBytes, err := json.Marshal(em)
-	if err != nil {
-		return err
+	if err := tx.Save(entry).Error; err != nil {
+		return mediaIDError(err, *entry)
 	}
-	ej.JSON = string(emBytes)
-	ej.EntryID = entry.ID
 
-	if err := UpdateEntryJSON(ej); err != nil {
-		return err
+	//record the changed fields
+	changes := original.Diff(*entry)
+	if len(changes) > 0 {
+		revision := models.EntryRevision{
+			EntryID:   entry.ID,
+			CreatedBy: entry.UpdatedBy,
+			Changes:   changes,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return updateEntryJSON(tx, *entry)
 }
 
 func FindEntries() ([]models.Entry, error) {
@@ -73,21 +85,23 @@ func FindEntries() ([]models.Entry, error) {
 	return entries, nil
 }
 
-func FindEntriesFiltered(filter string) ([]models.Entry, error) {
+// FindEntriesFiltered returns the entries of a mediatype in some repositories, an empty filter returns every
+// mediatype and nil every repository
+func FindEntriesFiltered(filter string, repositoryIDs []uint) ([]models.Entry, error) {
 	entries := []models.Entry{}
-	if filter == "" {
-		return FindEntries()
-	} else {
-		if err := db.Table("entries").Where("mediatype = ?", filter).Find(&entries).Error; err != nil {
-			return entries, err
-		}
-		return entries, nil
+	tx := db.Model(&models.Entry{}).Scopes(inRepositories("repository_id", repositoryIDs))
+	if filter != "" {
+		tx = tx.Where("mediatype = ?", filter)
+	}
+	if err := tx.Find(&entries).Error; err != nil {
+		return entries, err
 	}
+	return entries, nil
 }
 
 func FindEntryIDsByResourceID(id uint) ([]string, error) {
 	entries := []string{}
-	if err := db.Table("entries").Where("resource_id = ?", id).Select("id").Find(&entries).Error; err != nil {
+	if err := db.Model(&models.Entry{}).Where("resource_id = ?", id).Select("id").Find(&entries).Error; err != nil {
 		return entries, err
 	}
 	return entries, nil
@@ -129,7 +143,7 @@ func FindEntriesByResourceIDPaginated(id uint, pagination Pagination) ([]models.
 
 func FindEntryIDsByAccessionID(id uint) ([]string, error) {
 	ids := []string{}
-	if err := db.Table("entries").Where("accession_id = ?", id).Select("id").Find(&ids).Error; err != nil {
+	if err := db.Model(&models.Entry{}).Where("accession_id = ?", id).Select("id").Find(&ids).Error; err != nil {
 		return []string{}, err
 	}
 	return ids, nil
@@ -196,7 +210,7 @@ func FindEntriesByRepositoryIDPaginated(repositoryID uint, pagination Pagination
 
 func FindEntryIDsByRepositoryID(repositoryID uint) ([]string, error) {
 	ids := []string{}
-	if err := db.Table("entries").Where("repository_id = ?", repositoryID).Select("id").Find(&ids).Error; err != nil {
+	if err := db.Model(&models.Entry{}).Where("repository_id = ?", repositoryID).Select("id").Find(&ids).Error; err != nil {
 		return []string{}, err
 	}
 	return ids, nil
@@ -220,20 +234,21 @@ func FindEntriesSorted(numRecords int) ([]models.Entry, error) {
 
 func FindMaxMediaIDInResource(resourceID uint) int {
 	var maxMediaID int
-	db.Table("entries").Where("resource_id = ?", resourceID).Order("media_id desc").Select("media_id").Limit(1).Find(&maxMediaID)
+	db.Unscoped().Model(&models.Entry{}).Where("resource_id = ?", resourceID).Order("media_id desc").Select("media_id").Limit(1).Find(&maxMediaID)
 	return maxMediaID
 }
 
-func FindPaginatedEntries(pagination Pagination) ([]models.Entry, error) {
+// FindPaginatedEntries returns a page of the entries in some repositories, nil pages through every repository
+func FindPaginatedEntries(pagination Pagination, repositoryIDs []uint) ([]models.Entry, error) {
 	entries := []models.Entry{}
 
 	if pagination.Filter == "" {
-		if err := db.Preload(clause.Associations).Limit(pagination.Limit).Offset(pagination.Offset).Order(pagination.Sort).Find(&entries).Error; err != nil {
+		if err := db.Preload(clause.Associations).Scopes(inRepositories("repository_id", repositoryIDs)).Limit(pagination.Limit).Offset(pagination.Offset).Order(pagination.Sort).Find(&entries).Error; err != nil {
 			return entries, err
 		}
 	} else {
 		log.Println("FILTER", pagination.Filter)
-		if err := db.Preload(clause.Associations).Where("mediatype = ?", pagination.Filter).Limit(pagination.Limit).Offset(pagination.Offset).Order(pagination.Sort).Find(&entries).Error; err != nil {
+		if err := db.Preload(clause.Associations).Scopes(inRepositories("repository_id", repositoryIDs)).Where("mediatype = ?", pagination.Filter).Limit(pagination.Limit).Offset(pagination.Offset).Order(pagination.Sort).Find(&entries).Error; err != nil {
 			return entries, err
 		}
 	}
@@ -242,7 +257,7 @@ func FindPaginatedEntries(pagination Pagination) ([]models.Entry, error) {
 
 func GetNumberPagesInResource(resourceID uint) (int, error) {
 	entryIDs := []uuid.UUID{}
-	if err := db.Table("entries").Where("resource_id = ?", resourceID).Select("id").Find(&entryIDs).Error; err != nil {
+	if err := db.Model(&models.Entry{}).Where("resource_id = ?", resourceID).Select("id").Find(&entryIDs).Error; err != nil {
 		return 0, err
 	}
 
@@ -258,13 +273,15 @@ func GetCountOfEntriesInDB() int64 {
 	return count
 }
 
-func GetCountOfEntriesInDBPaginated(pagination *Pagination) int64 {
+// GetCountOfEntriesInDBPaginated counts the entries matching a pagination's filter in some repositories, nil counts
+// every repository
+func GetCountOfEntriesInDBPaginated(pagination *Pagination, repositoryIDs []uint) int64 {
 	var count int64
 	if pagination.Filter != "" {
-		db.Table("entries").Where("mediatype = ?", pagination.Filter).Count(&count)
+		db.Model(&models.Entry{}).Scopes(inRepositories("repository_id", repositoryIDs)).Where("mediatype = ?", pagination.Filter).Count(&count)
 		return count
 	} else {
-		return GetCountOfEntriesInDB()
+		return GetCountOfEntriesInRepositories(repositoryIDs)
 	}
 }
 
@@ -277,7 +294,7 @@ func GetCountOfEntriesInAccession(accessionID uint) int64 {
 func GetCountOfEntriesInAccessionPaginated(accessionID uint, pagination *Pagination) int64 {
 	var count int64
 	if pagination.Filter != "" {
-		db.Table("entries").Where("mediatype = ? AND accession_id = ?", pagination.Filter, accessionID).Count(&count)
+		db.Model(&models.Entry{}).Where("mediatype = ? AND accession_id = ?", pagination.Filter, accessionID).Count(&count)
 		return count
 	} else {
 		return GetCountOfEntriesInAccession(accessionID)
@@ -293,7 +310,7 @@ func GetCountOfEntriesInResource(resourceID uint) int64 {
 func GetCountOfEntriesInResourcePaginated(resourceID uint, pagination Pagination) int64 {
 	if pagination.Filter != "" {
 		var count int64
-		db.Table("entries").Where("mediatype = ? AND resource_id = ?", pagination.Filter, resourceID).Count(&count)
+		db.Model(&models.Entry{}).Where("mediatype = ? AND resource_id = ?", pagination.Filter, resourceID).Count(&count)
 		return count
 	} else {
 		return GetCountOfEntriesInResource(resourceID)
@@ -468,35 +485,68 @@ func FindEntryByMediaIDAndCollectionID(mediaID uint, ResourceID uint) (uuid.UUID
 }
 
 func FindNextMediaCollectionInResource(resourceID uint) (uint, error) {
+	return findNextMediaIDInResource(db, resourceID)
+}
 
-	var entries = []models.Entry{}
+// findNextMediaIDInResource returns the media id after the highest one used or allocated in a resource without reserving it
+func findNextMediaIDInResource(tx *gorm.DB, resourceID uint) (uint, error) {
 
-	if err := db.Where("resource_id = ?", resourceID).Order("media_id desc").Find(&entries).Error; err != nil {
+	var maxMediaID uint
+
+	//entries in the trash keep their media ids, so they can be restored without a clash
+	if err := tx.Unscoped().Model(&models.Entry{}).Where("resource_id = ?", resourceID).Select("COALESCE(MAX(media_id), 0)").Scan(&maxMediaID).Error; err != nil {
 		return 0, err
 	}
 
-	if (len(entries)) == 0 {
-		return 1, nil
+	//the counter also remembers media ids of entries that were purged
+	counters := []models.MediaIDCounter{}
+	if err := tx.Where("resource_id = ?", resourceID).Find(&counters).Error; err != nil {
+		return 0, err
+	}
+	if len(counters) > 0 && counters[0].LastMediaID > maxMediaID {
+		maxMediaID = counters[0].LastMediaID
 	}
 
-	return entries[0].MediaID + 1, nil
+	return maxMediaID + 1, nil
 }
 
-func IsMediaIDUniqueInResource(mediaID uint, resourceID uint) (bool, error) {
+// InsertEntries creates a batch of entries in one transaction, numbering them sequentially from the next media id in the resource
+func InsertEntries(resourceID uint, entries []models.Entry) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		mediaID, err := allocateMediaIDs(tx, resourceID, uint(len(entries)))
+		if err != nil {
+			return err
+		}
 
-	entries := []models.Entry{}
+		for i := range entries {
+			entries[i].MediaID = mediaID + uint(i)
+			if err := tx.Create(&entries[i]).Error; err != nil {
+				return mediaIDError(err, entries[i])
+			}
+			if err := insertEntryJSON(tx, entries[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
-	if err := db.Where("resource_id = ?", int(resourceID)).Find(&entries).Error; err != nil {
+// IsMediaIDUniqueInResource reports whether a media id is unused in a resource, including by entries in the trash
+func IsMediaIDUniqueInResource(mediaID uint, resourceID uint) (bool, error) {
+	free, err := mediaIDIsFree(db, resourceID, mediaID)
+	if err != nil {
 		return false, err
 	}
+	return free, nil
+}
 
-	for _, entry := range entries {
-		if entry.MediaID == mediaID {
-			return false, nil
-		}
+// FindEntriesByImageFilename returns the entries whose image filename is one of the names
+func FindEntriesByImageFilename(names []string) ([]models.Entry, error) {
+	entries := []models.Entry{}
+	if err := db.Preload(clause.Associations).Where("image_filename IN ? AND image_filename <> ''", names).Find(&entries).Error; err != nil {
+		return entries, err
 	}
-
-	return true, nil
+	return entries, nil
 }
 
 func FindEntryInResource(resourceID int, mediaID int) (string, error) {
@@ -507,9 +557,10 @@ func FindEntryInResource(resourceID int, mediaID int) (string, error) {
 	return entry.ID.String(), nil
 }
 
-func GetEntryIDs() ([]string, error) {
+// GetEntryIDs lists the ids of the entries in some repositories, nil lists every entry
+func GetEntryIDs(repositoryIDs []uint) ([]string, error) {
 	ids := []string{}
-	if err := db.Table("entries").Select("id").Find(&ids).Error; err != nil {
+	if err := db.Model(&models.Entry{}).Scopes(inRepositories("repository_id", repositoryIDs)).Select("id").Find(&ids).Error; err != nil {
 		return []string{}, err
 	}
 	return ids, nil
@@ -517,23 +568,31 @@ func GetEntryIDs() ([]string, error) {
 
 func GetEntryIDsPaginated(pagination Pagination) ([]string, error) {
 	ids := []string{}
-	if err := db.Table("entries").Select("id").Limit(pagination.Limit).Offset(pagination.Offset).Find(&ids).Error; err != nil {
+	if err := db.Model(&models.Entry{}).Select("id").Limit(pagination.Limit).Offset(pagination.Offset).Find(&ids).Error; err != nil {
 		return []string{}, err
 	}
 	return ids, nil
 }
 
-func FindEntriesPaginated(pagination Pagination) ([]models.Entry, error) {
+// FindEntriesPaginated returns a page of the entries in some repositories, nil pages through every entry
+func FindEntriesPaginated(pagination Pagination, repositoryIDs []uint) ([]models.Entry, error) {
 	entries := []models.Entry{}
-	if err := db.Preload(clause.Associations).Limit(pagination.Limit).Offset(pagination.Offset).Find(&entries).Error; err != nil {
+	if err := db.Preload(clause.Associations).Scopes(inRepositories("repository_id", repositoryIDs)).Limit(pagination.Limit).Offset(pagination.Offset).Find(&entries).Error; err != nil {
 		return entries, err
 	}
 	return entries, nil
 }
 
+// GetCountOfEntriesInRepositories counts the entries in some repositories, nil counts every entry
+func GetCountOfEntriesInRepositories(repositoryIDs []uint) int64 {
+	var count int64
+	db.Model(&models.Entry{}).Scopes(inRepositories("repository_id", repositoryIDs)).Count(&count)
+	return count
+}
+
 func getEntryIDs() ([]uuid.UUID, error) {
 	entryIDs := []uuid.UUID{}
-	if err := db.Table("entries").Select("id").Scan(&entryIDs).Error; err != nil {
+	if err := db.Model(&models.Entry{}).Select("id").Scan(&entryIDs).Error; err != nil {
 		return entryIDs, err
 	}
 	return entryIDs, nil
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+// FindFormatProfileByEntryID returns an entry's format profile with its formats, most common first. An entry without reports gets a profile with no ID.
+func FindFormatProfileByEntryID(entryID uuid.UUID) (models.FormatProfile, error) {
+	profiles := []models.FormatProfile{}
+	if err := db.Preload("Formats", func(tx *gorm.DB) *gorm.DB { return tx.Order("count desc, puid") }).Where("entry_id = ?", entryID).Limit(1).Find(&profiles).Error; err != nil {
+		return models.FormatProfile{}, err
+	}
+	if len(profiles) == 0 {
+		return models.FormatProfile{EntryID: entryID, Filesystems: []string{}, Reports: []string{}, Formats: []models.FormatCount{}}, nil
+	}
+	return profiles[0], nil
+}
+
+// SaveFormatProfile stores a format profile, replacing the entry's formats with the profile's
+func SaveFormatProfile(profile *models.FormatProfile) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entry_id = ?", profile.EntryID).Delete(&models.FormatCount{}).Error; err != nil {
+			return err
+		}
+		for i := range profile.Formats {
+			profile.Formats[i].ID = 0
+			profile.Formats[i].EntryID = profile.EntryID
+		}
+		return tx.Save(profile).Error
+	})
+}
+
+// ResourceFormatCount is a format's file count and size across the entries of a resource
+type ResourceFormatCount struct {
+	PUID    string `json:"puid" gorm:"column:puid"`
+	Format  string `json:"format"`
+	Version string `json:"version"`
+	Entries int    `json:"entries"`
+	Count   int    `json:"count"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// FormatSummary aggregates the format profiles of the entries in a resource
+type FormatSummary struct {
+	Entries     int                   `json:"entries"`
+	FileCount   int                   `json:"file_count"`
+	TotalBytes  int64                 `json:"total_bytes"`
+	Filesystems map[string]int        `json:"filesystems"`
+	Formats     []ResourceFormatCount `json:"formats"`
+}
+
+// GetFormatSummaryByResource totals the format profiles of a resource's entries, entries in the trash are left out
+func GetFormatSummaryByResource(resourceID uint) (FormatSummary, error) {
+	summary := FormatSummary{Filesystems: map[string]int{}, Formats: []ResourceFormatCount{}}
+
+	profiles := []models.FormatProfile{}
+	if err := db.Joins("JOIN entries ON entries.id = format_profiles.entry_id AND entries.deleted_at IS NULL").
+		Where("entries.resource_id = ?", resourceID).Find(&profiles).Error; err != nil {
+		return summary, err
+	}
+	for _, profile := range profiles {
+		summary.Entries++
+		summary.FileCount += profile.FileCount
+		summary.TotalBytes += profile.TotalBytes
+		for _, filesystem := range profile.Filesystems {
+			summary.Filesystems[filesystem]++
+		}
+	}
+
+	if err := db.Model(&models.FormatCount{}).
+		Select("format_counts.puid, format_counts.format, format_counts.version, COUNT(DISTINCT format_counts.entry_id) AS entries, SUM(format_counts.count) AS count, SUM(format_counts.bytes) AS bytes").
+		Joins("JOIN entries ON entries.id = format_counts.entry_id AND entries.deleted_at IS NULL").
+		Where("entries.resource_id = ?", resourceID).
+		Group("format_counts.puid, format_counts.format, format_counts.version").
+		Order("count desc, format_counts.puid").
+		Scan(&summary.Formats).Error; err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,244 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fullTextColumns are the entry columns covered by the full-text index
+var fullTextColumns = []string{"label_text", "manufacturer", "manufacturer_serial", "media_note", "disposition_note", "imaging_note", "image_filename", "box_number", "original_id", "imaged_by", "mediatype"}
+
+const (
+	fullTextIndex = "idx_entries_fulltext" //mysql FULLTEXT index on entries
+	fullTextTable = "entries_fts"          //sqlite FTS5 table holding a copy of entries keyed by entry id
+)
+
+// mysqlStopwords are innodb's default full-text stopwords, they are not indexed
+var mysqlStopwords = map[string]bool{
+	"a": true, "about": true, "an": true, "are": true, "as": true, "at": true, "be": true, "by": true, "com": true,
+	"de": true, "en": true, "for": true, "from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"la": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"what": true, "when": true, "where": true, "who": true, "will": true, "with": true, "und": true, "www": true,
+}
+
+// mysqlMinTokenSize is innodb_ft_min_token_size, shorter words are not indexed
+var mysqlMinTokenSize int
+
+func minTokenSize() int {
+	if mysqlMinTokenSize == 0 {
+		size := 3
+		if err := db.Raw("SELECT @@innodb_ft_min_token_size").Scan(&size).Error; err != nil || size < 1 {
+			size = 3
+		}
+		mysqlMinTokenSize = size
+	}
+	return mysqlMinTokenSize
+}
+
+// hasFullText is set once the full-text index has been found, until then searches fall back to LIKE
+var hasFullText bool
+
+func fullTextAvailable() bool {
+	if hasFullText {
+		return true
+	}
+	switch db.Dialector.Name() {
+	case DriverMySQL:
+		hasFullText = db.Migrator().HasIndex(&models.Entry{}, fullTextIndex)
+	case DriverSQLite:
+		hasFullText = db.Migrator().HasTable(fullTextTable)
+	}
+	return hasFullText
+}
+
+// createFullTextIndex adds the full-text index for the connection's driver, it is a no-op if the index exists
+func createFullTextIndex(tx *gorm.DB) error {
+	columns := strings.Join(fullTextColumns, ", ")
+
+	switch tx.Dialector.Name() {
+	case DriverMySQL:
+		if tx.Migrator().HasIndex(&models.Entry{}, fullTextIndex) {
+			return nil
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE entries ADD FULLTEXT INDEX %s (%s)", fullTextIndex, columns)).Error
+
+	case DriverSQLite:
+		//entries has a uuid primary key, so its rowids can be renumbered by VACUUM and the table is keyed by entry id
+		var keyedByRowid int64
+		if err := tx.Raw("SELECT count(*) FROM sqlite_master WHERE name = ? AND sql LIKE ?", fullTextTable, "%content_rowid%").Scan(&keyedByRowid).Error; err != nil {
+			return err
+		}
+		if keyedByRowid > 0 {
+			if err := dropFullTextIndex(tx); err != nil {
+				return err
+			}
+		}
+		if tx.Migrator().HasTable(fullTextTable) {
+			return nil
+		}
+
+		newValues := "new.id, new." + strings.Join(fullTextColumns, ", new.")
+		statements := []string{
+			fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(id UNINDEXED, %s)", fullTextTable, columns),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_ai AFTER INSERT ON entries BEGIN INSERT INTO %[1]s(id, %[2]s) VALUES (%[3]s); END", fullTextTable, columns, newValues),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_ad AFTER DELETE ON entries BEGIN DELETE FROM %[1]s WHERE id = old.id; END", fullTextTable),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_au AFTER UPDATE ON entries BEGIN DELETE FROM %[1]s WHERE id = old.id; INSERT INTO %[1]s(id, %[2]s) VALUES (%[3]s); END", fullTextTable, columns, newValues),
+			fmt.Sprintf("INSERT INTO %s(id, %s) SELECT id, %s FROM entries", fullTextTable, columns, columns),
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("full-text search is not supported for %s", tx.Dialector.Name())
+	}
+}
+
+func dropFullTextIndex(tx *gorm.DB) error {
+	switch tx.Dialector.Name() {
+	case DriverMySQL:
+		if !tx.Migrator().HasIndex(&models.Entry{}, fullTextIndex) {
+			return nil
+		}
+		return tx.Migrator().DropIndex(&models.Entry{}, fullTextIndex)
+
+	case DriverSQLite:
+		for _, statement := range []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ai", fullTextTable),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_ad", fullTextTable),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_au", fullTextTable),
+			fmt.Sprintf("DROP TABLE IF EXISTS %s", fullTextTable),
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// RebuildSearchIndex regenerates the search json of every entry and rebuilds the full-text index
+func RebuildSearchIndex() (JSONDrift, error) {
+	drift, err := VerifyJSON(true)
+	if err != nil {
+		return drift, err
+	}
+
+	hasFullText = false
+	if err := dropFullTextIndex(db); err != nil {
+		return drift, err
+	}
+	if err := createFullTextIndex(db); err != nil {
+		return drift, err
+	}
+
+	return drift, nil
+}
+
+// fullTextTerms splits search terms into those the full-text index can match and those it cannot. Innodb does not
+// index stopwords or words shorter than innodb_ft_min_token_size, a term with such a word is matched with LIKE
+// instead. Without the index every term is matched with LIKE.
+func fullTextTerms(terms []string) (indexed []string, unindexed []string) {
+	if !fullTextAvailable() {
+		return []string{}, terms
+	}
+	if db.Dialector.Name() != DriverMySQL {
+		return terms, []string{}
+	}
+
+	indexed, unindexed = []string{}, []string{}
+	for _, term := range terms {
+		matchable := true
+		for _, word := range fullTextWords(term) {
+			if utf8.RuneCountInString(word) < minTokenSize() || mysqlStopwords[strings.ToLower(word)] {
+				matchable = false
+				break
+			}
+		}
+		if matchable {
+			indexed = append(indexed, term)
+		} else {
+			unindexed = append(unindexed, term)
+		}
+	}
+	return indexed, unindexed
+}
+
+// fullTextWords splits a term on the characters both engines treat as separators, which drops any boolean or fts5
+// operators
+func fullTextWords(term string) []string {
+	return strings.FieldsFunc(term, func(r rune) bool { return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)) })
+}
+
+// fullTextQuery turns search terms into a query where every term must match, single words match as a prefix
+func fullTextQuery(terms []string) string {
+	parts := []string{}
+	for _, term := range terms {
+		words := fullTextWords(term)
+		switch {
+		case len(words) == 0:
+			continue
+		case len(words) == 1:
+			if db.Dialector.Name() == DriverMySQL {
+				parts = append(parts, fmt.Sprintf("+%s*", words[0]))
+			} else {
+				parts = append(parts, fmt.Sprintf("\"%s\"*", words[0]))
+			}
+		default:
+			if db.Dialector.Name() == DriverMySQL {
+				parts = append(parts, fmt.Sprintf("+\"%s\"", strings.Join(words, " ")))
+			} else {
+				parts = append(parts, fmt.Sprintf("\"%s\"", strings.Join(words, " ")))
+			}
+		}
+	}
+	if db.Dialector.Name() == DriverMySQL {
+		return strings.Join(parts, " ")
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// fullTextScope restricts a query on entries to the full-text matches of the terms
+func fullTextScope(terms []string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		query := fullTextQuery(terms)
+		if query == "" {
+			return tx
+		}
+		switch db.Dialector.Name() {
+		case DriverMySQL:
+			return tx.Where(fmt.Sprintf("MATCH (%s) AGAINST (? IN BOOLEAN MODE)", strings.Join(fullTextColumns, ", ")), query)
+		default:
+			return tx.Where(fmt.Sprintf("entries.id IN (SELECT id FROM %[1]s WHERE %[1]s MATCH ?)", fullTextTable), query)
+		}
+	}
+}
+
+// fullTextRelevance orders full-text matches with the most relevant first
+func fullTextRelevance(terms []string) clause.Expression {
+	query := fullTextQuery(terms)
+	switch db.Dialector.Name() {
+	case DriverMySQL:
+		return clause.OrderBy{Expression: clause.Expr{
+			SQL:                fmt.Sprintf("MATCH (%s) AGAINST (? IN BOOLEAN MODE) DESC", strings.Join(fullTextColumns, ", ")),
+			Vars:               []interface{}{query},
+			WithoutParentheses: true,
+		}}
+	default:
+		return clause.OrderBy{Expression: clause.Expr{
+			SQL:                fmt.Sprintf("(SELECT bm25(%[1]s) FROM %[1]s WHERE %[1]s MATCH ? AND %[1]s.id = entries.id)", fullTextTable),
+			Vars:               []interface{}{query},
+			WithoutParentheses: true,
+		}}
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RegisterImageFile records an image file, registering a path again replaces its entry, size and checksums
+func RegisterImageFile(imageFile *models.ImageFile) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"entry_id", "size", "md5", "sha256", "status", "registered_at", "registered_by", "last_checked_at"}),
+	}).Create(imageFile).Error
+}
+
+func FindImageFile(id uint) (models.ImageFile, error) {
+	imageFile := models.ImageFile{}
+	if err := db.Where("id = ?", id).First(&imageFile).Error; err != nil {
+		return imageFile, err
+	}
+	return imageFile, nil
+}
+
+func FindImageFiles() ([]models.ImageFile, error) {
+	imageFiles := []models.ImageFile{}
+	if err := db.Order("id").Find(&imageFiles).Error; err != nil {
+		return imageFiles, err
+	}
+	return imageFiles, nil
+}
+
+func FindImageFilesByEntryID(entryID uuid.UUID) ([]models.ImageFile, error) {
+	imageFiles := []models.ImageFile{}
+	if err := db.Where("entry_id = ?", entryID).Order("path").Find(&imageFiles).Error; err != nil {
+		return imageFiles, err
+	}
+	return imageFiles, nil
+}
+
+// ImageFileProblem is an image file that failed its last fixity check, with the entry it belongs to
+type ImageFileProblem struct {
+	models.ImageFile
+	CollectionCode string `json:"collection_code"`
+	MediaID        uint   `json:"media_id"`
+}
+
+// FindImageFileProblems lists the image files whose last fixity check found them missing or changed, limited to the
+// entries in some repositories, nil lists every repository
+func FindImageFileProblems(repositoryIDs []uint) ([]ImageFileProblem, error) {
+	problems := []ImageFileProblem{}
+	if err := db.Model(&models.ImageFile{}).
+		Select("image_files.*, resources.collection_code, entries.media_id").
+		Joins("LEFT JOIN entries ON entries.id = image_files.entry_id").
+		Joins("LEFT JOIN resources ON resources.id = entries.resource_id").
+		Where("image_files.status IN ?", []string{models.ImageFileMissing, models.ImageFileChanged}).
+		Scopes(inRepositories("entries.repository_id", repositoryIDs)).
+		Order("resources.collection_code, entries.media_id, image_files.path").
+		Scan(&problems).Error; err != nil {
+		return problems, err
+	}
+	return problems, nil
+}
+
+// RecordFixityEvent stores the outcome of a fixity check and updates the file's status in one transaction
+func RecordFixityEvent(imageFile *models.ImageFile, status string, detail string) (models.FixityEvent, error) {
+	checkedAt := time.Now()
+	event := models.FixityEvent{
+		ImageFileID: imageFile.ID,
+		EntryID:     imageFile.EntryID,
+		Outcome:     models.FixityPass,
+		Detail:      detail,
+		CheckedAt:   checkedAt,
+	}
+	if status != models.ImageFileOK {
+		event.Outcome = models.FixityFail
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		imageFile.Status = status
+		imageFile.LastCheckedAt = &checkedAt
+		return tx.Model(imageFile).Select("status", "last_checked_at").Updates(imageFile).Error
+	})
+	return event, err
+}
+
+func FindFixityEventsByEntryID(entryID uuid.UUID, limit int) ([]models.FixityEvent, error) {
+	events := []models.FixityEvent{}
+	if err := db.Where("entry_id = ?", entryID).Order("checked_at desc, id desc").Limit(limit).Find(&events).Error; err != nil {
+		return events, err
+	}
+	return events, nil
+}
+
+// purgeEntryRecords permanently deletes the search json, image files, fixity events, format profiles and imaging attempts of purged entries
+func purgeEntryRecords(tx *gorm.DB, entryIDs interface{}) error {
+	for _, model := range []interface{}{&models.EntryJSON{}, &models.FixityEvent{}, &models.ImageFile{}, &models.FormatCount{}, &models.FormatProfile{}, &models.ImagingAttempt{}} {
+		if err := tx.Unscoped().Where("entry_id IN (?)", entryIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
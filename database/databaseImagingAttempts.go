@@ -0,0 +1,106 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+// InsertImagingAttempt records an imaging attempt and derives the entry's imaging fields from its attempts
+func InsertImagingAttempt(attempt *models.ImagingAttempt) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return applyImagingAttempts(tx, attempt.EntryID, attempt.CreatedBy)
+	})
+}
+
+// DeleteImagingAttempt removes an attempt recorded in error and derives the entry's imaging fields from the attempts left
+func DeleteImagingAttempt(entryID uuid.UUID, id uint, userID int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND entry_id = ?", id, entryID).Delete(&models.ImagingAttempt{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return applyImagingAttempts(tx, entryID, userID)
+	})
+}
+
+// FindImagingAttemptsByEntryID returns an entry's imaging attempts, latest first
+func FindImagingAttemptsByEntryID(entryID uuid.UUID) ([]models.ImagingAttempt, error) {
+	return findImagingAttempts(db, entryID)
+}
+
+func findImagingAttempts(tx *gorm.DB, entryID uuid.UUID) ([]models.ImagingAttempt, error) {
+	attempts := []models.ImagingAttempt{}
+	if err := tx.Where("entry_id = ?", entryID).Order("attempted_at desc, id desc").Find(&attempts).Error; err != nil {
+		return attempts, err
+	}
+	return attempts, nil
+}
+
+// applyImagingAttempts sets the entry's imaging success, interface, software, encoding scheme and technician from its latest successful
+// attempt. When every attempt failed the entry is marked unsuccessful and keeps its other imaging fields.
+func applyImagingAttempts(tx *gorm.DB, entryID uuid.UUID, userID int) error {
+	attempts, err := findImagingAttempts(tx, entryID)
+	if err != nil || len(attempts) == 0 {
+		return err
+	}
+
+	entry := models.Entry{}
+	if err := tx.Where("id = ?", entryID).First(&entry).Error; err != nil {
+		return err
+	}
+
+	original := entry
+	entry.ImagingSuccess = models.ImageSuccessNo
+	for _, attempt := range attempts {
+		if attempt.Succeeded() {
+			entry.ImagingSuccess = models.ImageSuccessYes
+			entry.Interface = attempt.Interface
+			entry.ImagingSoftware = attempt.ImagingSoftware
+			entry.EncodingScheme = attempt.EncodingScheme
+			entry.ImagedBy = attempt.ImagedBy
+			break
+		}
+	}
+
+	if len(original.Diff(entry)) == 0 {
+		return nil
+	}
+
+	entry.UpdatedBy = userID
+	entry.UpdatedAt = time.Now()
+	return updateEntry(tx, &entry)
+}
+
+// backfillImagingAttempts records the imaging fields of existing entries as their first attempt
+func backfillImagingAttempts(tx *gorm.DB) error {
+	entries := []models.Entry{}
+	return tx.Unscoped().
+		Where("imaging_success <> '' OR interface <> '' OR imaging_software <> '' OR imaged_by <> ''").
+		FindInBatches(&entries, 500, func(batch *gorm.DB, _ int) error {
+			attempts := []models.ImagingAttempt{}
+			for _, entry := range entries {
+				attempts = append(attempts, models.ImagingAttempt{
+					EntryID:         entry.ID,
+					AttemptedAt:     entry.UpdatedAt,
+					ImagedBy:        entry.ImagedBy,
+					Interface:       entry.Interface,
+					ImagingSoftware: entry.ImagingSoftware,
+					EncodingScheme:  entry.EncodingScheme,
+					ImagingSuccess:  entry.ImagingSuccess,
+					Note:            "recorded from the entry when imaging attempts were added",
+					CreatedAt:       time.Now(),
+					CreatedBy:       entry.UpdatedBy,
+				})
+			}
+			return tx.Session(&gorm.Session{NewDB: true}).Create(&attempts).Error
+		}).Error
+}
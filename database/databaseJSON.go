@@ -2,10 +2,12 @@ package database
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
 )
 
 func GetJSONs() ([]models.EntryJSON, error) {
@@ -24,42 +26,50 @@ func GetJSON(id uint) (models.EntryJSON, error) {
 	return j, nil
 }
 
-func CreateJSON() error {
-	entryIDs, err := getEntryIDs()
+func InsertEntryJSON(entry models.Entry) error {
+	return insertEntryJSON(db, entry)
+}
+
+func insertEntryJSON(tx *gorm.DB, entry models.Entry) error {
+	entryJson := models.EntryJSON{}
+	entryJson.EntryID = entry.ID
+	ebBytes, err := entryJSONBytes(entry)
 	if err != nil {
 		return err
 	}
-
-	for _, entryID := range entryIDs {
-		log.Printf("Creating JSON for: %s", entryID.String())
-		entry, err := FindEntry(entryID)
-		if err != nil {
-			return err
-		}
-
-		if err := InsertEntryJSON(entry); err != nil {
-			return err
-		}
+	entryJson.JSON = string(ebBytes)
+	if err := tx.Create(&entryJson).Error; err != nil {
+		return err
 	}
-	log.Println("Success")
 	return nil
 }
 
-func InsertEntryJSON(entry models.Entry) error {
-	entryJson := models.EntryJSON{}
-	entryJson.EntryID = entry.ID
-	em := entry.Minimal()
-	ebBytes, err := json.Marshal(em)
+// updateEntryJSON refreshes the search json of an entry, creating it if it is missing
+func updateEntryJSON(tx *gorm.DB, entry models.Entry) error {
+	ej, err := findEntryJSONByEntryID(tx, entry.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return insertEntryJSON(tx, entry)
+	} else if err != nil {
+		return err
+	}
+
+	ebBytes, err := entryJSONBytes(entry)
 	if err != nil {
 		return err
 	}
-	entryJson.JSON = string(ebBytes)
-	if err := db.Create(&entryJson).Error; err != nil {
+	ej.JSON = string(ebBytes)
+	ej.EntryID = entry.ID
+
+	if err := tx.Save(&ej).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
+func entryJSONBytes(entry models.Entry) ([]byte, error) {
+	return json.Marshal(entry.Minimal())
+}
+
 func UpdateEntryJSON(ej models.EntryJSON) error {
 
 	if err := db.Save(&ej).Error; err != nil {
@@ -76,9 +86,116 @@ func DeleteEntryJSON(id uint) error {
 }
 
 func FindEntryJSONByEntryID(u uuid.UUID) (models.EntryJSON, error) {
+	return findEntryJSONByEntryID(db, u)
+}
+
+func findEntryJSONByEntryID(tx *gorm.DB, u uuid.UUID) (models.EntryJSON, error) {
 	var ej models.EntryJSON
-	if err := db.First(&ej).Where("entry_id = ?", u).Error; err != nil {
+	if err := tx.Where("entry_id = ?", u).Order("id").First(&ej).Error; err != nil {
 		return ej, err
 	}
 	return ej, nil
 }
+
+type JSONDrift struct {
+	Entries    int         `json:"entries"`
+	Missing    []uuid.UUID `json:"missing"`    //entries without a search json
+	Stale      []uuid.UUID `json:"stale"`      //search json that no longer matches its entry
+	Duplicates []uint      `json:"duplicates"` //extra search json rows for an entry
+	Orphaned   []uint      `json:"orphaned"`   //search json rows whose entry no longer exists
+}
+
+func (d JSONDrift) HasDrift() bool {
+	return len(d.Missing)+len(d.Stale)+len(d.Duplicates)+len(d.Orphaned) > 0
+}
+
+func (d JSONDrift) String() string {
+	return fmt.Sprintf("entries: %d, missing: %d, stale: %d, duplicates: %d, orphaned: %d",
+		d.Entries, len(d.Missing), len(d.Stale), len(d.Duplicates), len(d.Orphaned))
+}
+
+// VerifyJSON compares every entry with its search json, when repair is true the drift is fixed in a single transaction
+func VerifyJSON(repair bool) (JSONDrift, error) {
+	drift := JSONDrift{Missing: []uuid.UUID{}, Stale: []uuid.UUID{}, Duplicates: []uint{}, Orphaned: []uint{}}
+
+	entryJSONs, err := GetJSONs()
+	if err != nil {
+		return drift, err
+	}
+
+	//keep the first json row of each entry, any others are duplicates
+	byEntry := map[uuid.UUID]models.EntryJSON{}
+	for _, ej := range entryJSONs {
+		if first, ok := byEntry[ej.EntryID]; ok {
+			if ej.ID < first.ID {
+				byEntry[ej.EntryID] = ej
+				ej = first
+			}
+			drift.Duplicates = append(drift.Duplicates, ej.ID)
+			continue
+		}
+		byEntry[ej.EntryID] = ej
+	}
+
+	//entries in the trash keep their json so they can be restored
+	entries := []models.Entry{}
+	var checkErr error
+	result := db.Unscoped().FindInBatches(&entries, 500, func(tx *gorm.DB, batch int) error {
+		for _, entry := range entries {
+			drift.Entries++
+			ej, ok := byEntry[entry.ID]
+			if !ok {
+				drift.Missing = append(drift.Missing, entry.ID)
+				continue
+			}
+			delete(byEntry, entry.ID)
+
+			ebBytes, err := entryJSONBytes(entry)
+			if err != nil {
+				checkErr = err
+				return err
+			}
+			if ej.JSON != string(ebBytes) {
+				drift.Stale = append(drift.Stale, entry.ID)
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return drift, result.Error
+	}
+	if checkErr != nil {
+		return drift, checkErr
+	}
+
+	//json rows left over have no entry
+	for _, ej := range byEntry {
+		drift.Orphaned = append(drift.Orphaned, ej.ID)
+	}
+
+	if !repair || !drift.HasDrift() {
+		return drift, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		stray := append(append([]uint{}, drift.Duplicates...), drift.Orphaned...)
+		if len(stray) > 0 {
+			if err := tx.Unscoped().Delete(&models.EntryJSON{}, stray).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, id := range append(append([]uuid.UUID{}, drift.Missing...), drift.Stale...) {
+			entry := models.Entry{}
+			if err := tx.Where("id = ?", id).First(&entry).Error; err != nil {
+				return err
+			}
+			if err := updateEntryJSON(tx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return drift, err
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"time"
+
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+func InsertLoginAttempt(attempt *models.LoginAttempt) error {
+	return db.Create(attempt).Error
+}
+
+// CountFailedLoginsByIP counts the failed logins made from an address since a time
+func CountFailedLoginsByIP(ipAddress string, since time.Time) (int64, error) {
+	var count int64
+	if err := db.Model(&models.LoginAttempt{}).Where("ip_address = ? AND success = ? AND created_at > ?", ipAddress, false, since).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindLoginAttemptsByUserID returns a user's most recent logins, newest first
+func FindLoginAttemptsByUserID(userID uint, limit int) ([]models.LoginAttempt, error) {
+	attempts := []models.LoginAttempt{}
+	if err := db.Where("user_id = ?", userID).Order("created_at desc, id desc").Limit(limit).Find(&attempts).Error; err != nil {
+		return attempts, err
+	}
+	return attempts, nil
+}
+
+// RecordLoginFailure counts a wrong password against a user and locks the account once maxFailures is reached,
+// returning when the lock ends or nil if the account was not locked
+func RecordLoginFailure(userID uint, maxFailures int, lockout time.Duration) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("failed_logins", gorm.Expr("failed_logins + 1")).Error; err != nil {
+			return err
+		}
+		user := models.User{}
+		if err := tx.Select("failed_logins").Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.FailedLogins < maxFailures {
+			return nil
+		}
+		until := time.Now().Add(lockout)
+		lockedUntil = &until
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{"failed_logins": 0, "locked_until": until}).Error
+	})
+	return lockedUntil, err
+}
+
+// UnlockUser clears a user's failed logins and any lockout
+func UnlockUser(userID uint) error {
+	result := db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{"failed_logins": 0, "locked_until": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
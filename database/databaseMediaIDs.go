@@ -0,0 +1,137 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mediaIDIndex makes a media id unique within a resource, entries in the trash keep their media ids and are covered too
+const mediaIDIndex = "idx_entries_resource_media"
+
+// MediaIDTakenError is returned when an entry is saved with a media id already used in its resource
+type MediaIDTakenError struct {
+	ResourceID uint
+	MediaID    uint
+}
+
+func (e *MediaIDTakenError) Error() string {
+	return fmt.Sprintf("media id %d is already used in resource %d", e.MediaID, e.ResourceID)
+}
+
+// mediaIDError replaces a unique index violation on an entry with a MediaIDTakenError
+func mediaIDError(err error, entry models.Entry) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return &MediaIDTakenError{ResourceID: entry.ResourceID, MediaID: entry.MediaID}
+	}
+	return err
+}
+
+// MediaIDDuplicate is a media id shared by more than one entry in a resource
+type MediaIDDuplicate struct {
+	ResourceID uint        `json:"resource_id"`
+	MediaID    uint        `json:"media_id"`
+	EntryIDs   []uuid.UUID `json:"entry_ids"`
+}
+
+// DuplicateMediaIDsError is returned when the unique media id index cannot be created because of existing duplicates
+type DuplicateMediaIDsError struct {
+	Duplicates []MediaIDDuplicate
+}
+
+func (e *DuplicateMediaIDsError) Error() string {
+	ids := []string{}
+	for _, duplicate := range e.Duplicates {
+		ids = append(ids, fmt.Sprintf("%d in resource %d", duplicate.MediaID, duplicate.ResourceID))
+	}
+	return fmt.Sprintf("%d media ids are used more than once, renumber the entries before migrating: %s", len(e.Duplicates), strings.Join(ids, ", "))
+}
+
+// createMediaIDIndexOrWarn creates the unique media id index when it can, duplicates only leave it out with a warning
+// so the rest of an automigration still runs
+func createMediaIDIndexOrWarn(tx *gorm.DB) error {
+	err := createMediaIDIndex(tx)
+	var duplicatesError *DuplicateMediaIDsError
+	if errors.As(err, &duplicatesError) {
+		log.Printf("[WARNING] the unique media id index was not created, %d duplicates found; run --report-duplicates and renumber them, then migrate", len(duplicatesError.Duplicates))
+		return nil
+	}
+	return err
+}
+
+// FindDuplicateMediaIDs lists the media ids used by more than one entry in a resource, including entries in the trash
+func FindDuplicateMediaIDs() ([]MediaIDDuplicate, error) {
+	return findDuplicateMediaIDs(db)
+}
+
+func findDuplicateMediaIDs(tx *gorm.DB) ([]MediaIDDuplicate, error) {
+	duplicates := []MediaIDDuplicate{}
+	if err := tx.Unscoped().Model(&models.Entry{}).Select("resource_id, media_id").Group("resource_id, media_id").Having("COUNT(*) > 1").Order("resource_id, media_id").Scan(&duplicates).Error; err != nil {
+		return duplicates, err
+	}
+
+	for i, duplicate := range duplicates {
+		if err := tx.Unscoped().Model(&models.Entry{}).Where("resource_id = ? AND media_id = ?", duplicate.ResourceID, duplicate.MediaID).Order("created_at").Pluck("id", &duplicates[i].EntryIDs).Error; err != nil {
+			return duplicates, err
+		}
+	}
+	return duplicates, nil
+}
+
+// HasMediaIDIndex reports whether the unique media id index has been created
+func HasMediaIDIndex() bool {
+	return db.Migrator().HasIndex(&models.Entry{}, mediaIDIndex)
+}
+
+// createMediaIDIndex adds the unique media id index, it refuses while duplicates exist and is a no-op if the index exists
+func createMediaIDIndex(tx *gorm.DB) error {
+	if tx.Migrator().HasIndex(&models.Entry{}, mediaIDIndex) {
+		return nil
+	}
+
+	duplicates, err := findDuplicateMediaIDs(tx)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return &DuplicateMediaIDsError{Duplicates: duplicates}
+	}
+
+	return tx.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON entries (resource_id, media_id)", mediaIDIndex)).Error
+}
+
+func dropMediaIDIndex(tx *gorm.DB) error {
+	if !tx.Migrator().HasIndex(&models.Entry{}, mediaIDIndex) {
+		return nil
+	}
+	return tx.Migrator().DropIndex(&models.Entry{}, mediaIDIndex)
+}
+
+// allocateMediaIDs reserves n consecutive media ids in a resource and returns the first. The resource's counter row
+// stays locked until tx commits, so concurrent allocations in the same resource wait for each other.
+func allocateMediaIDs(tx *gorm.DB, resourceID uint, n uint) (uint, error) {
+	counter := models.MediaIDCounter{ResourceID: resourceID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&counter).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("resource_id = ?", resourceID).First(&counter).Error; err != nil {
+		return 0, err
+	}
+
+	mediaID, err := findNextMediaIDInResource(tx, resourceID)
+	if err != nil {
+		return 0, err
+	}
+
+	counter.LastMediaID = mediaID + n - 1
+	if err := tx.Save(&counter).Error; err != nil {
+		return 0, err
+	}
+	return mediaID, nil
+}
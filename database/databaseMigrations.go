@@ -5,22 +5,49 @@ import (
 
 	"github.com/go-gormigrate/gormigrate/v2"
 	"github.com/nyudlts/go-medialog/models"
+	"github.com/nyudlts/go-medialog/passwords"
 	"gorm.io/gorm"
 )
 
 func AutoMigrate(dbc models.DatabaseConfig) error {
-	if err := ConnectMySQL(dbc, true); err != nil {
+	if err := Connect(dbc, true); err != nil {
 		return err
 	}
 
-	if err := db.AutoMigrate(&models.Repository{}, &models.Resource{}, &models.Accession{}, &models.Entry{}, &models.User{}, &models.Token{}, &models.EntryJSON{}); err != nil {
+	return MigrateModels()
+}
+
+// MigrateModels creates or updates the tables for every model on the current connection
+func MigrateModels() error {
+	hadImagingAttempts := db.Migrator().HasTable(&models.ImagingAttempt{})
+	hadRoleGrants := db.Migrator().HasTable(&models.RoleGrant{})
+	hadEncodingScheme := db.Migrator().HasColumn(&models.Entry{}, "EncodingScheme")
+	if err := db.AutoMigrate(&models.Repository{}, &models.Resource{}, &models.Accession{}, &models.Entry{}, &models.User{}, &models.Token{}, &models.EntryJSON{}, &models.EntryRevision{}, &models.VocabularyTerm{}, &models.APIKey{}, &models.EntryMove{}, &models.MediaIDCounter{}, &models.ImageFile{}, &models.FixityEvent{}, &models.FormatProfile{}, &models.FormatCount{}, &models.ImagingAttempt{}, &models.RoleGrant{}, &models.LoginAttempt{}, &models.RecoveryCode{}); err != nil {
 		return err
 	}
-	return nil
+	if !hadImagingAttempts {
+		if err := backfillImagingAttempts(db); err != nil {
+			return err
+		}
+	}
+	if !hadRoleGrants {
+		if err := backfillRoleGrants(db); err != nil {
+			return err
+		}
+	}
+	if !hadEncodingScheme {
+		if err := mapLegacyEncodingSchemes(db); err != nil {
+			return err
+		}
+	}
+	if err := createMediaIDIndexOrWarn(db); err != nil {
+		return err
+	}
+	return createFullTextIndex(db)
 }
 
 func MigrateDatabase(rollback bool, dbc models.DatabaseConfig) error {
-	if err := ConnectMySQL(dbc, true); err != nil {
+	if err := Connect(dbc, true); err != nil {
 		return err
 	}
 
@@ -61,6 +88,165 @@ func MigrateDatabase(rollback bool, dbc models.DatabaseConfig) error {
 			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().AddColumn(&models.Entry{}, "Status") },
 			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropColumn(&models.Entry{}, "Status") },
 		},
+		{
+			ID:       "20261018 - Tagging legacy password hashes",
+			Migrate:  tagLegacyPasswords,
+			Rollback: untagLegacyPasswords,
+		},
+		{
+			ID:       "20261018 - Adding entry revisions table",
+			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&models.EntryRevision{}) },
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropTable(&models.EntryRevision{}) },
+		},
+		{
+			ID:       "20261018 - Adding vocabulary terms table",
+			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&models.VocabularyTerm{}) },
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropTable(&models.VocabularyTerm{}) },
+		},
+		{
+			ID:       "20261018 - Adding entry full-text index",
+			Migrate:  createFullTextIndex,
+			Rollback: dropFullTextIndex,
+		},
+		{
+			ID:       "20261018 - Adding API keys table",
+			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&models.APIKey{}) },
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropTable(&models.APIKey{}) },
+		},
+		{
+			ID:       "20261018 - Adding soft deletes to repositories, resources, accessions and entries",
+			Migrate:  addSoftDeletes,
+			Rollback: dropSoftDeletes,
+		},
+		{
+			ID:       "20261018 - Adding entry moves table",
+			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&models.EntryMove{}) },
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropTable(&models.EntryMove{}) },
+		},
+		{
+			ID:       "20261018 - Adding media id counters and unique media id index",
+			Migrate:  addMediaIDIndex,
+			Rollback: dropMediaIDCounters,
+		},
+		{
+			ID:       "20261018 - Adding image files and fixity events tables",
+			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&models.ImageFile{}, &models.FixityEvent{}) },
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropTable(&models.FixityEvent{}, &models.ImageFile{}) },
+		},
+		{
+			ID: "20261018 - Adding format profiles tables",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Migrator().CreateTable(&models.FormatProfile{}, &models.FormatCount{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.FormatCount{}, &models.FormatProfile{})
+			},
+		},
+		{
+			ID: "20261018 - Adding imaging attempts table",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Migrator().CreateTable(&models.ImagingAttempt{}); err != nil {
+					return err
+				}
+				return backfillImagingAttempts(tx)
+			},
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropTable(&models.ImagingAttempt{}) },
+		},
+		{
+			ID:       "20261018 - Adding filesystems to entry",
+			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().AddColumn(&models.Entry{}, "Filesystems") },
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropColumn(&models.Entry{}, "Filesystems") },
+		},
+		{
+			ID:       "20261018 - Adding encoding scheme to entry",
+			Migrate:  func(tx *gorm.DB) error { return tx.Migrator().AddColumn(&models.Entry{}, "EncodingScheme") },
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropColumn(&models.Entry{}, "EncodingScheme") },
+		},
+		{
+			ID: "20261018 - Adding role grants table",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.Migrator().CreateTable(&models.RoleGrant{}); err != nil {
+					return err
+				}
+				return backfillRoleGrants(tx)
+			},
+			Rollback: func(tx *gorm.DB) error { return tx.Migrator().DropTable(&models.RoleGrant{}) },
+		},
+		{
+			ID: "20261018 - Adding session id, created at and ip address to tokens",
+			Migrate: func(tx *gorm.DB) error {
+				for _, column := range []string{"SessionID", "CreatedAt", "IPAddress"} {
+					if err := tx.Migrator().AddColumn(&models.Token{}, column); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().CreateIndex(&models.Token{}, "SessionID")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, column := range []string{"SessionID", "CreatedAt", "IPAddress"} {
+					if err := tx.Migrator().DropColumn(&models.Token{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "20261018 - Adding login attempts table and user lockouts",
+			Migrate: func(tx *gorm.DB) error {
+				for _, column := range []string{"FailedLogins", "LockedUntil"} {
+					if err := tx.Migrator().AddColumn(&models.User{}, column); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().CreateTable(&models.LoginAttempt{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable(&models.LoginAttempt{}); err != nil {
+					return err
+				}
+				for _, column := range []string{"FailedLogins", "LockedUntil"} {
+					if err := tx.Migrator().DropColumn(&models.User{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "20261018 - Adding two-factor authentication to users and recovery codes table",
+			Migrate: func(tx *gorm.DB) error {
+				for _, column := range []string{"TOTPSecret", "TOTPEnabled", "TOTPLastStep"} {
+					if err := tx.Migrator().AddColumn(&models.User{}, column); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().CreateTable(&models.RecoveryCode{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable(&models.RecoveryCode{}); err != nil {
+					return err
+				}
+				for _, column := range []string{"TOTPSecret", "TOTPEnabled", "TOTPLastStep"} {
+					if err := tx.Migrator().DropColumn(&models.User{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			//imaging attempts recorded before encoding schemes were a vocabulary hold free text
+			ID:       "20261018 - Mapping free-text encoding schemes to vocabulary terms",
+			Migrate:  mapLegacyEncodingSchemes,
+			Rollback: func(tx *gorm.DB) error { return nil },
+		},
+		{
+			//the sqlite full-text table was keyed by rowid, which VACUUM can renumber on a table with a uuid primary key
+			ID:       "20261018 - Keying the sqlite full-text index on entry ids",
+			Migrate:  createFullTextIndex,
+			Rollback: func(tx *gorm.DB) error { return nil },
+		},
 	}
 
 	m := gormigrate.New(db, gormigrate.DefaultOptions, migrations)
@@ -87,3 +273,91 @@ func MigrateDatabase(rollback bool, dbc models.DatabaseConfig) error {
 		return nil
 	}
 }
+
+// tagLegacyPasswords moves each user's salt into an algorithm-tagged sha512 hash so it can be verified and upgraded on the next login
+func tagLegacyPasswords(tx *gorm.DB) error {
+	users := []models.User{}
+	if err := tx.Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if user.EncryptedPassword == "" || passwords.IsTagged(user.EncryptedPassword) {
+			continue
+		}
+		encryptedPassword := passwords.EncodeLegacy(user.Salt, user.EncryptedPassword)
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{"encrypted_password": encryptedPassword, "salt": ""}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untagLegacyPasswords restores legacy hashes, passwords already upgraded to argon2id or bcrypt cannot be rolled back
+func untagLegacyPasswords(tx *gorm.DB) error {
+	users := []models.User{}
+	if err := tx.Where("encrypted_password LIKE ?", "$sha512$%").Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		salt, digest, err := passwords.DecodeLegacy(user.EncryptedPassword)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{"encrypted_password": digest, "salt": salt}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMediaIDIndex creates the media id counters and the unique media id index, run --report-duplicates first to find entries to renumber
+func addMediaIDIndex(tx *gorm.DB) error {
+	if err := tx.Migrator().CreateTable(&models.MediaIDCounter{}); err != nil {
+		return err
+	}
+	return createMediaIDIndex(tx)
+}
+
+func dropMediaIDCounters(tx *gorm.DB) error {
+	if err := dropMediaIDIndex(tx); err != nil {
+		return err
+	}
+	return tx.Migrator().DropTable(&models.MediaIDCounter{})
+}
+
+// softDeleteModels are the archival records that are moved to the trash instead of being deleted
+var softDeleteModels = []interface{}{&models.Repository{}, &models.Resource{}, &models.Accession{}, &models.Entry{}}
+
+func addSoftDeletes(tx *gorm.DB) error {
+	for _, model := range softDeleteModels {
+		if tx.Migrator().HasColumn(model, "DeletedAt") {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(model, "DeletedAt"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateIndex(model, "DeletedAt"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropSoftDeletes(tx *gorm.DB) error {
+	for _, model := range softDeleteModels {
+		if !tx.Migrator().HasColumn(model, "DeletedAt") {
+			continue
+		}
+		if tx.Migrator().HasIndex(model, "DeletedAt") {
+			if err := tx.Migrator().DropIndex(model, "DeletedAt"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Migrator().DropColumn(model, "DeletedAt"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
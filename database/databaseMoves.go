@@ -0,0 +1,97 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MoveEntries re-files entries into an accession in one transaction. An entry keeps its media id when it is
+// free in the target resource, otherwise it is given the next media id there. Each move is recorded.
+func MoveEntries(entryIDs []uuid.UUID, accessionID uint, userID int) ([]models.EntryMove, error) {
+	moves := []models.EntryMove{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		accession := models.Accession{}
+		if err := tx.Preload(clause.Associations).Where("id = ?", accessionID).First(&accession).Error; err != nil {
+			return fmt.Errorf("accession %d: %w", accessionID, err)
+		}
+
+		for _, entryID := range entryIDs {
+			entry := models.Entry{}
+			if err := tx.Where("id = ?", entryID).First(&entry).Error; err != nil {
+				return fmt.Errorf("entry %s: %w", entryID, err)
+			}
+
+			if entry.AccessionID == accession.ID {
+				return fmt.Errorf("entry %s is already in accession %s", entryID, accession.AccessionNum)
+			}
+
+			move := models.EntryMove{
+				EntryID:          entry.ID,
+				FromRepositoryID: entry.RepositoryID,
+				FromResourceID:   entry.ResourceID,
+				FromAccessionID:  entry.AccessionID,
+				FromMediaID:      entry.MediaID,
+				ToRepositoryID:   accession.Resource.RepositoryID,
+				ToResourceID:     accession.ResourceID,
+				ToAccessionID:    accession.ID,
+				ToMediaID:        entry.MediaID,
+				CreatedBy:        userID,
+			}
+
+			if entry.ResourceID != accession.ResourceID {
+				free, err := mediaIDIsFree(tx, accession.ResourceID, entry.MediaID)
+				if err != nil {
+					return err
+				}
+				if !free {
+					if move.ToMediaID, err = allocateMediaIDs(tx, accession.ResourceID, 1); err != nil {
+						return err
+					}
+				}
+			}
+
+			entry.RepositoryID = move.ToRepositoryID
+			entry.ResourceID = move.ToResourceID
+			entry.AccessionID = move.ToAccessionID
+			entry.MediaID = move.ToMediaID
+			entry.UpdatedBy = userID
+			entry.UpdatedAt = time.Now()
+
+			if err := updateEntry(tx, &entry); err != nil {
+				return err
+			}
+
+			if err := tx.Create(&move).Error; err != nil {
+				return err
+			}
+			moves = append(moves, move)
+		}
+		return nil
+	})
+	if err != nil {
+		return []models.EntryMove{}, err
+	}
+	return moves, nil
+}
+
+// mediaIDIsFree reports whether no entry in a resource, including the trash, uses a media id
+func mediaIDIsFree(tx *gorm.DB, resourceID uint, mediaID uint) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&models.Entry{}).Where("resource_id = ? AND media_id = ?", resourceID, mediaID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+func FindEntryMoves(entryID uuid.UUID) ([]models.EntryMove, error) {
+	moves := []models.EntryMove{}
+	if err := db.Where("entry_id = ?", entryID).Order("created_at desc, id desc").Find(&moves).Error; err != nil {
+		return moves, err
+	}
+	return moves, nil
+}
@@ -17,6 +17,15 @@ func FindRepositories() ([]models.Repository, error) {
 	return repositories, nil
 }
 
+// FindRepositoryIDs lists the ids of every repository
+func FindRepositoryIDs() ([]uint, error) {
+	ids := []uint{}
+	if err := db.Model(&models.Repository{}).Pluck("id", &ids).Error; err != nil {
+		return ids, err
+	}
+	return ids, nil
+}
+
 func FindRepository(id uint) (models.Repository, error) {
 	repository := models.Repository{}
 	if err := db.Where("id = ?", id).First(&repository).Error; err != nil {
@@ -44,11 +53,9 @@ func UpdateRepository(repository *models.Repository) error {
 	return nil
 }
 
+// DeleteRepository deletes a repository with no children to the trash, otherwise it returns a *DependentsError
 func DeleteRepository(id uint) error {
-	if err := db.Delete(models.Repository{}, id).Error; err != nil {
-		return err
-	}
-	return nil
+	return deleteWithoutDependents(&models.Repository{}, "repository", id, findRepositoryDependents)
 }
 
 func CountRepositories() int64 {
@@ -44,11 +44,9 @@ func InsertResource(resource *models.Resource) (uint, error) {
 	return resource.ID, nil
 }
 
+// DeleteResource deletes a resource with no children to the trash, otherwise it returns a *DependentsError
 func DeleteResource(id uint) error {
-	if err := db.Delete(models.Resource{}, id).Error; err != nil {
-		return err
-	}
-	return nil
+	return deleteWithoutDependents(&models.Resource{}, "resource", id, findResourceDependents)
 }
 
 func UpdateResource(resource *models.Resource) error {
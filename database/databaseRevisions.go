@@ -0,0 +1,21 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func InsertEntryRevision(revision *models.EntryRevision) error {
+	if err := db.Create(revision).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func FindEntryRevisions(entryID uuid.UUID) ([]models.EntryRevision, error) {
+	revisions := []models.EntryRevision{}
+	if err := db.Where("entry_id = ?", entryID).Order("created_at desc, id desc").Find(&revisions).Error; err != nil {
+		return revisions, err
+	}
+	return revisions, nil
+}
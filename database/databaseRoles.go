@@ -0,0 +1,83 @@
+package database
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FindRoleGrantsByUserID lists a user's roles, the global role first
+func FindRoleGrantsByUserID(userID uint) ([]models.RoleGrant, error) {
+	grants := []models.RoleGrant{}
+	if err := db.Where("user_id = ?", userID).Order("repository_id").Find(&grants).Error; err != nil {
+		return grants, err
+	}
+	return grants, nil
+}
+
+// GrantRole gives a user a role in a repository or globally, replacing the role they had there
+func GrantRole(grant *models.RoleGrant) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "repository_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "created_at", "created_by"}),
+	}).Create(grant).Error
+}
+
+// RevokeRole removes one of a user's roles
+func RevokeRole(userID uint, id uint) error {
+	result := db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.RoleGrant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindRepositoryIDByEntryID returns the repository an entry is filed in
+func FindRepositoryIDByEntryID(id uuid.UUID) (uint, error) {
+	entry := models.Entry{}
+	if err := db.Select("repository_id").Where("id = ?", id).First(&entry).Error; err != nil {
+		return 0, err
+	}
+	return entry.RepositoryID, nil
+}
+
+// FindRepositoryIDByAccessionID returns the repository of an accession's resource
+func FindRepositoryIDByAccessionID(id uint) (uint, error) {
+	accession := models.Accession{}
+	if err := db.Select("resource_id").Where("id = ?", id).First(&accession).Error; err != nil {
+		return 0, err
+	}
+	return FindRepositoryIDByResourceID(accession.ResourceID)
+}
+
+// FindRepositoryIDByResourceID returns the repository a resource belongs to
+func FindRepositoryIDByResourceID(id uint) (uint, error) {
+	resource := models.Resource{}
+	if err := db.Select("repository_id").Where("id = ?", id).First(&resource).Error; err != nil {
+		return 0, err
+	}
+	return resource.RepositoryID, nil
+}
+
+// backfillRoleGrants gives existing users who are not admins a global curator role, so they keep the access they had
+// before roles were introduced
+func backfillRoleGrants(tx *gorm.DB) error {
+	users := []models.User{}
+	if err := tx.Where("is_admin = ?", false).Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		grant := models.RoleGrant{UserID: user.ID, Role: models.RoleCurator, CreatedAt: time.Now()}
+		if err := tx.Session(&gorm.Session{NewDB: true}).Clauses(clause.OnConflict{DoNothing: true}).Create(&grant).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,14 +1,19 @@
 package database
 
 import (
-	"github.com/google/uuid"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 func SearchRepositories(query string) ([]models.Repository, error) {
 	repositories := []models.Repository{}
-	if err := db.Where("title LIKE ?", "%"+query+"%").Find(&repositories).Error; err != nil {
+	if err := db.Where(likeCondition(db, "title"), likePattern(query)).Find(&repositories).Error; err != nil {
 		return repositories, err
 	}
 	return repositories, nil
@@ -16,7 +21,7 @@ func SearchRepositories(query string) ([]models.Repository, error) {
 
 func SearchResources(query string) ([]models.Resource, error) {
 	resources := []models.Resource{}
-	if err := db.Preload(clause.Associations).Table("resources").Where("title LIKE ? OR collection_code LIKE ?", "%"+query+"%", "%"+query+"%").Scan(&resources).Error; err != nil {
+	if err := db.Preload(clause.Associations).Where(likeCondition(db, "title")+" OR "+likeCondition(db, "collection_code"), likePattern(query), likePattern(query)).Find(&resources).Error; err != nil {
 		return resources, err
 	}
 	return resources, nil
@@ -24,25 +29,224 @@ func SearchResources(query string) ([]models.Resource, error) {
 
 func SearchAccessions(query string) ([]models.Accession, error) {
 	accessions := []models.Accession{}
-	if err := db.Preload(clause.Associations).Where("accession_num LIKE ?", "%"+query+"%").Find(&accessions).Error; err != nil {
+	if err := db.Preload(clause.Associations).Where(likeCondition(db, "accession_num"), likePattern(query)).Find(&accessions).Error; err != nil {
 		return accessions, err
 	}
 	return accessions, nil
 }
 
+// SearchEntries returns every entry matching a query, see ParseEntryQuery for the query syntax
 func SearchEntries(query string) ([]models.Entry, error) {
-	hitIds := []uuid.UUID{}
-	entries := []models.Entry{}
-	if err := db.Table("entry_jsons").Select("entry_id").Where("json LIKE ?", "%"+query+"%").Scan(&hitIds).Error; err != nil {
-		return entries, err
+	search := ParseEntryQuery(query)
+	result, err := SearchEntryPage(search, Pagination{})
+	if err != nil {
+		return []models.Entry{}, err
+	}
+	return result.Results, nil
+}
+
+// likeEscaper escapes the wildcards of user input matched with LIKE, backslash is the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern matches a value anywhere in a column, the value's own % and _ are matched literally
+func likePattern(value string) string { return "%" + likeEscaper.Replace(value) + "%" }
+
+// likeCondition is a LIKE condition on a column for a pattern from likePattern
+func likeCondition(tx *gorm.DB, column string) string {
+	if tx.Dialector.Name() == DriverMySQL {
+		//mysql reads a backslash in a string literal as an escape, so it is written twice
+		return column + ` LIKE ? ESCAPE '\\'`
 	}
+	return column + ` LIKE ? ESCAPE '\'`
+}
+
+// SearchFields are the entry columns that can be targeted with field:value terms, exact fields must match the whole value
+var SearchFields = map[string]bool{
+	"label_text":          false,
+	"box_number":          true,
+	"manufacturer":        false,
+	"manufacturer_serial": false,
+	"original_id":         false,
+	"media_note":          false,
+	"imaged_by":           false,
+}
+
+// EntrySearch holds a parsed query and the filters applied to an entry search, zero values are not filtered on.
+// RepositoryIDs limits the search to the repositories a user can view, nil searches every repository.
+type EntrySearch struct {
+	Terms         []string          `json:"terms"`
+	Fields        map[string]string `json:"fields"`
+	RepositoryIDs []uint            `json:"-"`
+	RepositoryID  uint              `json:"repository_id"`
+	ResourceID    uint              `json:"resource_id"`
+	AccessionID   uint              `json:"accession_id"`
+	Mediatype     string            `json:"mediatype"`
+	Status        string            `json:"status"`
+	Location      string            `json:"location"`
+	CreatedFrom   time.Time         `json:"created_from"`
+	CreatedTo     time.Time         `json:"created_to"`
+	UpdatedFrom   time.Time         `json:"updated_from"`
+	UpdatedTo     time.Time         `json:"updated_to"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type EntrySearchResult struct {
+	Pagination Pagination              `json:"pagination"`
+	Results    []models.Entry          `json:"results"`
+	Facets     map[string][]FacetCount `json:"facets"`
+}
 
-	for _, hitID := range hitIds {
-		entry, err := FindEntry(hitID)
-		if err != nil {
-			return entries, err
+// searchFacets are the columns counted for every search
+var searchFacets = []string{"mediatype", "status"}
+
+// ParseEntryQuery splits a query into free text terms and field:value terms, double quotes group words into one term
+func ParseEntryQuery(query string) EntrySearch {
+	search := EntrySearch{Terms: []string{}, Fields: map[string]string{}}
+
+	tokens := []string{}
+	var token strings.Builder
+	inQuotes := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case unicode.IsSpace(r) && !inQuotes:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(r)
+		}
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+
+	for _, t := range tokens {
+		if field, value, ok := strings.Cut(t, ":"); ok {
+			if _, searchable := SearchFields[strings.ToLower(field)]; searchable && value != "" {
+				search.Fields[strings.ToLower(field)] = value
+				continue
+			}
+		}
+		search.Terms = append(search.Terms, t)
+	}
+
+	return search
+}
+
+// scope applies the terms and filters of a search to a query on the entries table
+func (s EntrySearch) scope(tx *gorm.DB) *gorm.DB {
+	indexed, unindexed := fullTextTerms(s.Terms)
+	tx = tx.Scopes(fullTextScope(indexed))
+	//terms the full-text index cannot match fall back to a substring match on the search json
+	for _, term := range unindexed {
+		tx = tx.Where("id IN (?)", db.Table("entry_jsons").Select("entry_id").Where("deleted_at IS NULL AND "+likeCondition(db, "json"), likePattern(term)))
+	}
+
+	for field, value := range s.Fields {
+		if exact, ok := SearchFields[field]; !ok {
+			continue
+		} else if exact {
+			tx = tx.Where(fmt.Sprintf("%s = ?", field), value)
+		} else {
+			tx = tx.Where(likeCondition(tx, field), likePattern(value))
 		}
-		entries = append(entries, entry)
 	}
-	return entries, nil
+
+	tx = tx.Scopes(inRepositories("repository_id", s.RepositoryIDs))
+	if s.RepositoryID > 0 {
+		tx = tx.Where("repository_id = ?", s.RepositoryID)
+	}
+	if s.ResourceID > 0 {
+		tx = tx.Where("resource_id = ?", s.ResourceID)
+	}
+	if s.AccessionID > 0 {
+		tx = tx.Where("accession_id = ?", s.AccessionID)
+	}
+	if s.Mediatype != "" {
+		tx = tx.Where("mediatype = ?", s.Mediatype)
+	}
+	if s.Status != "" {
+		tx = tx.Where("status = ?", s.Status)
+	}
+	if s.Location != "" {
+		tx = tx.Where("location = ?", s.Location)
+	}
+	if !s.CreatedFrom.IsZero() {
+		tx = tx.Where("created_at >= ?", s.CreatedFrom)
+	}
+	if !s.CreatedTo.IsZero() {
+		tx = tx.Where("created_at < ?", s.CreatedTo.AddDate(0, 0, 1))
+	}
+	if !s.UpdatedFrom.IsZero() {
+		tx = tx.Where("updated_at >= ?", s.UpdatedFrom)
+	}
+	if !s.UpdatedTo.IsZero() {
+		tx = tx.Where("updated_at < ?", s.UpdatedTo.AddDate(0, 0, 1))
+	}
+
+	return tx
+}
+
+// searchSorts are the accepted sort orders for a search besides relevance, anything else falls back to relevance
+// when the query has free text terms, or to the most recently updated
+var searchSorts = map[string]string{
+	"updated_at desc": "updated_at desc",
+	"updated_at":      "updated_at",
+	"created_at desc": "created_at desc",
+	"created_at":      "created_at",
+	"media_id":        "resource_id, media_id",
+	"media_id desc":   "resource_id desc, media_id desc",
+}
+
+// SearchEntryPage runs a search and returns one page of results with the facet counts of the whole result set, a zero limit returns every result
+func SearchEntryPage(search EntrySearch, pagination Pagination) (EntrySearchResult, error) {
+	result := EntrySearchResult{Results: []models.Entry{}, Facets: map[string][]FacetCount{}}
+
+	if err := db.Model(&models.Entry{}).Scopes(search.scope).Count(&pagination.TotalRecords).Error; err != nil {
+		return result, err
+	}
+
+	if pagination.Limit > 0 {
+		pagination.TotalPages = int(pagination.TotalRecords / int64(pagination.Limit))
+		if pagination.TotalRecords%int64(pagination.Limit) > 0 {
+			pagination.TotalPages++
+		}
+	}
+
+	for _, facet := range searchFacets {
+		counts := []FacetCount{}
+		if err := db.Model(&models.Entry{}).Scopes(search.scope).
+			Select(fmt.Sprintf("%s AS value, COUNT(*) AS count", facet)).
+			Group(facet).Order("count desc").Scan(&counts).Error; err != nil {
+			return result, err
+		}
+		result.Facets[facet] = counts
+	}
+
+	tx := db.Preload(clause.Associations).Scopes(search.scope)
+	if sort, ok := searchSorts[pagination.Sort]; ok {
+		tx = tx.Order(sort)
+	} else if indexed, _ := fullTextTerms(search.Terms); fullTextQuery(indexed) != "" {
+		pagination.Sort = "relevance"
+		tx = tx.Order(fullTextRelevance(indexed)).Order(searchSorts["updated_at desc"])
+	} else {
+		pagination.Sort = "updated_at desc"
+		tx = tx.Order(searchSorts[pagination.Sort])
+	}
+	if pagination.Limit > 0 {
+		tx = tx.Limit(pagination.Limit).Offset(pagination.Offset)
+	}
+	if err := tx.Find(&result.Results).Error; err != nil {
+		return result, err
+	}
+
+	result.Pagination = pagination
+	return result, nil
 }
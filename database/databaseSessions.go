@@ -1,8 +1,60 @@
 package database
 
+import (
+	"time"
+
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
 func DeleteSessions() error {
 	if err := db.Exec("delete from sessions").Error; err != nil {
 		return err
 	}
 	return nil
 }
+
+// DeleteExpiredSessions removes the web sessions whose cookie has expired and returns how many were removed
+func DeleteExpiredSessions() (int64, error) {
+	result := db.Exec("delete from sessions where expires_at <= ?", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// ActiveSession is a valid login token with its user and, for web sessions, when the session was last saved
+type ActiveSession struct {
+	models.Token
+	Email    string     `json:"email"`
+	LastSeen *time.Time `json:"last_seen"`
+}
+
+// FindActiveSessions lists the unexpired login tokens of every user, ordered by user
+func FindActiveSessions() ([]ActiveSession, error) {
+	activeSessions := []ActiveSession{}
+	if err := db.Model(&models.Token{}).
+		Select("tokens.*, users.email, sessions.updated_at AS last_seen").
+		Joins("JOIN users ON users.id = tokens.user_id").
+		Joins("LEFT JOIN sessions ON sessions.id = tokens.session_id").
+		Where("tokens.is_valid = ? AND tokens.expires > ?", true, time.Now()).
+		Order("users.email, tokens.created_at desc").
+		Scan(&activeSessions).Error; err != nil {
+		return activeSessions, err
+	}
+	return activeSessions, nil
+}
+
+// EndSession invalidates a login token and deletes the web session it was issued to
+func EndSession(tokenID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		token := models.Token{}
+		if err := tx.Where("id = ?", tokenID).First(&token).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&token).Update("is_valid", false).Error; err != nil {
+			return err
+		}
+		if token.SessionID == "" {
+			return nil
+		}
+		return tx.Exec("delete from sessions where id = ?", token.SessionID).Error
+	})
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"strings"
+
 	"github.com/nyudlts/go-medialog/models"
 	"gorm.io/gorm/clause"
 )
@@ -138,6 +140,14 @@ func DeleteToken(tkn string) error {
 }
 
 func FindUserIDByToken(token string) (uint, error) {
+	if strings.HasPrefix(token, models.APIKeyPrefix) {
+		apiKey, err := FindAPIKey(token)
+		if err != nil {
+			return uint(0), err
+		}
+		return apiKey.UserID, nil
+	}
+
 	sessionToken := models.Token{}
 	if err := db.Where("token = ?", token).Find(&sessionToken).Error; err != nil {
 		return uint(0), err
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TrashRepositories = "repositories"
+	TrashResources    = "resources"
+	TrashAccessions   = "accessions"
+	TrashEntries      = "entries"
+)
+
+// Trash holds the deleted objects whose parent is not deleted, everything deleted with a parent is restored or purged with it
+type Trash struct {
+	Repositories []models.Repository `json:"repositories"`
+	Resources    []models.Resource   `json:"resources"`
+	Accessions   []models.Accession  `json:"accessions"`
+	Entries      []models.Entry      `json:"entries"`
+}
+
+// TrashError is returned when an object can not be restored or purged in its current state
+type TrashError struct {
+	Message string
+}
+
+func (e *TrashError) Error() string {
+	return e.Message
+}
+
+func trashed(model interface{}) *gorm.DB {
+	return db.Unscoped().Model(model).Select("id").Where("deleted_at IS NOT NULL")
+}
+
+func FindTrash() (Trash, error) {
+	trash := Trash{}
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&trash.Repositories).Error; err != nil {
+		return trash, err
+	}
+	if err := db.Unscoped().Preload("Repository").Where("deleted_at IS NOT NULL AND repository_id NOT IN (?)", trashed(&models.Repository{})).Order("deleted_at desc").Find(&trash.Resources).Error; err != nil {
+		return trash, err
+	}
+	if err := db.Unscoped().Preload("Resource").Where("deleted_at IS NOT NULL AND resource_id NOT IN (?)", trashed(&models.Resource{})).Order("deleted_at desc").Find(&trash.Accessions).Error; err != nil {
+		return trash, err
+	}
+	if err := db.Unscoped().Preload(clause.Associations).Where("deleted_at IS NOT NULL AND accession_id NOT IN (?) AND resource_id NOT IN (?)", trashed(&models.Accession{}), trashed(&models.Resource{})).Order("deleted_at desc").Find(&trash.Entries).Error; err != nil {
+		return trash, err
+	}
+	return trash, nil
+}
+
+// trashModel returns an empty model and the parsed id for a trash type
+func trashModel(kind string, id string) (interface{}, interface{}, error) {
+	if kind == TrashEntries {
+		entryID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &models.Entry{}, entryID, nil
+	}
+
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 1 {
+		return nil, nil, fmt.Errorf("`%s` is not a valid id", id)
+	}
+
+	switch kind {
+	case TrashRepositories:
+		return &models.Repository{}, uint(i), nil
+	case TrashResources:
+		return &models.Resource{}, uint(i), nil
+	case TrashAccessions:
+		return &models.Accession{}, uint(i), nil
+	}
+	return nil, nil, fmt.Errorf("`%s` is not a trash type", kind)
+}
+
+// findTrashed loads a deleted object and checks whether its parent is deleted too
+func findTrashed(tx *gorm.DB, model interface{}, id interface{}) (time.Time, bool, error) {
+	if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(model).Error; err != nil {
+		return time.Time{}, false, err
+	}
+
+	var parentTrashed int64
+	switch m := model.(type) {
+	case *models.Repository:
+		return m.DeletedAt.Time, false, nil
+	case *models.Resource:
+		tx.Unscoped().Model(&models.Repository{}).Where("id = ? AND deleted_at IS NOT NULL", m.RepositoryID).Count(&parentTrashed)
+		return m.DeletedAt.Time, parentTrashed > 0, nil
+	case *models.Accession:
+		tx.Unscoped().Model(&models.Resource{}).Where("id = ? AND deleted_at IS NOT NULL", m.ResourceID).Count(&parentTrashed)
+		return m.DeletedAt.Time, parentTrashed > 0, nil
+	case *models.Entry:
+		tx.Unscoped().Model(&models.Accession{}).Where("id = ? AND deleted_at IS NOT NULL", m.AccessionID).Count(&parentTrashed)
+		return m.DeletedAt.Time, parentTrashed > 0, nil
+	}
+	return time.Time{}, false, fmt.Errorf("unsupported trash model %T", model)
+}
+
+// RestoreFromTrash restores a deleted object along with everything that was deleted with it
+func RestoreFromTrash(kind string, id string) (DeleteSummary, error) {
+	summary := DeleteSummary{}
+	model, modelID, err := trashModel(kind, id)
+	if err != nil {
+		return summary, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		deletedAt, parentTrashed, err := findTrashed(tx, model, modelID)
+		if err != nil {
+			return err
+		}
+		if parentTrashed {
+			return &TrashError{Message: fmt.Sprintf("%s %s is in a deleted parent, restore the parent instead", kind, id)}
+		}
+
+		if parentID, ok := modelID.(uint); ok {
+			//rows deleted before the object was deleted stay in the trash
+			for _, level := range subtree(tx.Unscoped(), model, parentID, &summary) {
+				result := tx.Unscoped().Model(level.model).Where(level.query, level.args...).Where("deleted_at >= ?", deletedAt.UTC()).UpdateColumn("deleted_at", nil)
+				if result.Error != nil {
+					return result.Error
+				}
+				*level.count = result.RowsAffected
+			}
+		}
+
+		return tx.Unscoped().Model(model).Where("id = ?", modelID).UpdateColumn("deleted_at", nil).Error
+	})
+	return summary, err
+}
+
+// PurgeFromTrash permanently deletes a deleted object with everything below it and the records kept for its entries
+func PurgeFromTrash(kind string, id string) (DeleteSummary, error) {
+	summary := DeleteSummary{}
+	model, modelID, err := trashModel(kind, id)
+	if err != nil {
+		return summary, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if _, _, err := findTrashed(tx, model, modelID); err != nil {
+			return err
+		}
+
+		if parentID, ok := modelID.(uint); ok {
+			live := DeleteSummary{}
+			for _, level := range subtree(tx, model, parentID, &live) {
+				if err := tx.Model(level.model).Where(level.query, level.args...).Count(level.count).Error; err != nil {
+					return err
+				}
+			}
+			if live.HasDependents() {
+				return &TrashError{Message: fmt.Sprintf("%s %s still has %s that are not deleted", kind, id, live.String())}
+			}
+
+			for _, level := range subtree(tx.Unscoped(), model, parentID, &summary) {
+				if _, ok := level.model.(*models.Entry); ok {
+					entryIDs := tx.Unscoped().Model(&models.Entry{}).Select("id").Where(level.query, level.args...)
+					if err := purgeEntryRecords(tx, entryIDs); err != nil {
+						return err
+					}
+				}
+				if _, ok := level.model.(*models.Resource); ok {
+					resourceIDs := tx.Unscoped().Model(&models.Resource{}).Select("id").Where(level.query, level.args...)
+					if err := tx.Where("resource_id IN (?)", resourceIDs).Delete(&models.MediaIDCounter{}).Error; err != nil {
+						return err
+					}
+				}
+				result := tx.Unscoped().Where(level.query, level.args...).Delete(level.model)
+				if result.Error != nil {
+					return result.Error
+				}
+				*level.count = result.RowsAffected
+			}
+			if _, ok := model.(*models.Resource); ok {
+				if err := tx.Where("resource_id = ?", parentID).Delete(&models.MediaIDCounter{}).Error; err != nil {
+					return err
+				}
+			}
+			if _, ok := model.(*models.Repository); ok {
+				if err := tx.Where("repository_id = ?", parentID).Delete(&models.RoleGrant{}).Error; err != nil {
+					return err
+				}
+			}
+		} else {
+			if err := purgeEntryRecords(tx, modelID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Where("id = ?", modelID).Delete(model).Error
+	})
+	return summary, err
+}
+
+// PurgeTrash permanently deletes everything that was moved to the trash before a cutoff
+func PurgeTrash(before time.Time) (int, error) {
+	trash, err := FindTrash()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	purge := func(kind string, id string, deletedAt gorm.DeletedAt) error {
+		if !deletedAt.Time.Before(before) {
+			return nil
+		}
+		if _, err := PurgeFromTrash(kind, id); err != nil {
+			return err
+		}
+		purged++
+		return nil
+	}
+
+	for _, repository := range trash.Repositories {
+		if err := purge(TrashRepositories, strconv.Itoa(int(repository.ID)), repository.DeletedAt); err != nil {
+			return purged, err
+		}
+	}
+	for _, resource := range trash.Resources {
+		if err := purge(TrashResources, strconv.Itoa(int(resource.ID)), resource.DeletedAt); err != nil {
+			return purged, err
+		}
+	}
+	for _, accession := range trash.Accessions {
+		if err := purge(TrashAccessions, strconv.Itoa(int(accession.ID)), accession.DeletedAt); err != nil {
+			return purged, err
+		}
+	}
+	for _, entry := range trash.Entries {
+		if err := purge(TrashEntries, entry.ID.String(), entry.DeletedAt); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
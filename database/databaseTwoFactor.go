@@ -0,0 +1,65 @@
+package database
+
+import (
+	"time"
+
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+// SetTOTPSecret stores a new secret for a user who is setting up two-factor authentication, it is not used to log
+// in until EnableTOTP is called
+func SetTOTPSecret(userID uint, secret string) error {
+	return db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": false, "totp_last_step": 0}).Error
+}
+
+// EnableTOTP turns on two-factor authentication for a user, step is the time step of the code they confirmed it with
+func EnableTOTP(userID uint, step int64) error {
+	return db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error
+}
+
+// UseTOTPStep marks a time step's code as used, it reports false when a code from that step or a later one was
+// already accepted
+func UseTOTPStep(userID uint, step int64) (bool, error) {
+	result := db.Model(&models.User{}).Where("id = ? AND totp_last_step < ?", userID, step).Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ResetTwoFactor turns off a user's two-factor authentication and deletes their recovery codes
+func ResetTwoFactor(userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{"totp_secret": "", "totp_enabled": false, "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes deletes a user's recovery codes and stores the hashes of new ones
+func ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := []models.RecoveryCode{}
+		for _, hash := range codeHashes {
+			codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks a user's unused recovery code as used, it reports false when there is no such code
+func UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := db.Model(&models.RecoveryCode{}).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// CountUnusedRecoveryCodes counts the recovery codes a user has left
+func CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	if err := db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
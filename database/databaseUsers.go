@@ -1,6 +1,9 @@
 package database
 
-import "github.com/nyudlts/go-medialog/models"
+import (
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
 
 func FindUserByID(id int) (models.User, error) {
 	user := models.User{}
@@ -117,10 +120,15 @@ func FindEntryUsers(createUserID int, modUserID int) (EntryUsers, error) {
 }
 
 func DeleteUser(id uint) error {
-	if err := db.Delete(models.User{}, id).Error; err != nil {
-		return err
-	}
-	return nil
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.RoleGrant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(models.User{}, id).Error
+	})
 }
 
 func CountUsers() int64 {
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nyudlts/go-medialog/models"
+	"gorm.io/gorm"
+)
+
+func FindVocabularyTerms() ([]models.VocabularyTerm, error) {
+	terms := []models.VocabularyTerm{}
+	if err := db.Order("vocabulary, label").Find(&terms).Error; err != nil {
+		return terms, err
+	}
+	return terms, nil
+}
+
+func FindVocabularyTermsByVocabulary(vocabulary string) ([]models.VocabularyTerm, error) {
+	terms := []models.VocabularyTerm{}
+	if err := db.Where("vocabulary = ?", vocabulary).Order("label").Find(&terms).Error; err != nil {
+		return terms, err
+	}
+	return terms, nil
+}
+
+func FindVocabularyTerm(id uint) (models.VocabularyTerm, error) {
+	term := models.VocabularyTerm{}
+	if err := db.Where("id = ?", id).First(&term).Error; err != nil {
+		return term, err
+	}
+	return term, nil
+}
+
+func InsertVocabularyTerm(term *models.VocabularyTerm) error {
+	if err := db.Create(term).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func UpdateVocabularyTerm(term *models.VocabularyTerm) error {
+	if err := db.Save(term).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// SeedVocabulary inserts the default terms of a vocabulary that has no rows yet
+func SeedVocabulary(vocabulary string, defaults map[string]string) error {
+	return db.Transaction(func(tx *gorm.DB) error { return seedVocabulary(tx, vocabulary, defaults) })
+}
+
+func seedVocabulary(tx *gorm.DB, vocabulary string, defaults map[string]string) error {
+	var count int64
+	if err := tx.Model(&models.VocabularyTerm{}).Where("vocabulary = ?", vocabulary).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for key, label := range defaults {
+		term := models.VocabularyTerm{Vocabulary: vocabulary, Key: key, Label: label, CreatedAt: now, UpdatedAt: now}
+		if err := tx.Create(&term).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyEncodingSchemes are the encoding scheme terms as they were when the field became a vocabulary
+var legacyEncodingSchemes = map[string]string{
+	"":                              "",
+	"encoding_scheme_mfm":           "MFM",
+	"encoding_scheme_apple_400_800": "Apple 400/800",
+}
+
+var nonKeyCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// mapLegacyEncodingSchemes replaces the free-text encoding schemes of imaging attempts and entries with vocabulary
+// keys. Text that matches a term's key or label is mapped to that term, any other text is added as a retired term so
+// the records it is on can still be saved.
+func mapLegacyEncodingSchemes(tx *gorm.DB) error {
+	const vocabulary = "encoding_schemes"
+	tables := []string{"imaging_attempts", "entries"}
+
+	values := []string{}
+	for _, table := range tables {
+		found := []string{}
+		if err := tx.Table(table).Where("encoding_scheme <> ''").Distinct().Pluck("encoding_scheme", &found).Error; err != nil {
+			return err
+		}
+		values = append(values, found...)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	if err := seedVocabulary(tx, vocabulary, legacyEncodingSchemes); err != nil {
+		return err
+	}
+	terms := []models.VocabularyTerm{}
+	if err := tx.Where("vocabulary = ?", vocabulary).Find(&terms).Error; err != nil {
+		return err
+	}
+	keys := map[string]string{}
+	matches := map[string]string{}
+	for _, term := range terms {
+		keys[term.Key] = term.Key
+		matches[strings.ToLower(strings.TrimSpace(term.Label))] = term.Key
+	}
+
+	now := time.Now()
+	for _, value := range values {
+		if _, ok := keys[value]; ok {
+			continue
+		}
+		label := strings.TrimSpace(value)
+		key, ok := matches[strings.ToLower(label)]
+		if !ok {
+			slug := strings.Trim(nonKeyCharacters.ReplaceAllString(strings.ToLower(label), "_"), "_")
+			key = fmt.Sprintf("encoding_scheme_%.100s", slug)
+			for i := 2; keys[key] != ""; i++ {
+				key = fmt.Sprintf("encoding_scheme_%.100s_%d", slug, i)
+			}
+			term := models.VocabularyTerm{Vocabulary: vocabulary, Key: key, Label: label, IsRetired: true, CreatedAt: now, UpdatedAt: now}
+			if err := tx.Create(&term).Error; err != nil {
+				return err
+			}
+			keys[key] = key
+			matches[strings.ToLower(label)] = key
+		}
+
+		for _, table := range tables {
+			if err := tx.Table(table).Where("encoding_scheme = ?", value).Update("encoding_scheme", key).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -25,6 +25,12 @@ func init() {
 func TestDatabase(t *testing.T) {
 
 	t.Run("Test get the environment", func(t *testing.T) {
+		//run against an in-memory sqlite database when no config is provided
+		if configuration == "" {
+			env = models.Environment{DatabaseConfig: models.DatabaseConfig{Driver: database.DriverSQLite, Path: ":memory:"}}
+			return
+		}
+
 		var err error
 		env, err = router.GetEnvironment(configuration, environment)
 		if err != nil {
@@ -33,11 +39,19 @@ func TestDatabase(t *testing.T) {
 	})
 
 	t.Run("Test Connect Database", func(t *testing.T) {
-		if err := database.ConnectMySQL(env.DatabaseConfig, true); err != nil {
+		if err := database.Connect(env.DatabaseConfig, true); err != nil {
 			t.Error(err)
 		}
 	})
 
+	if env.DatabaseConfig.Driver == database.DriverSQLite {
+		t.Run("Test migrate the database", func(t *testing.T) {
+			if err := database.MigrateModels(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
 	t.Run("Test get the database", func(t *testing.T) {
 		db = database.GetDB()
 		t.Logf("%v", db)
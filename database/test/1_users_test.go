@@ -4,6 +4,7 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -71,6 +72,34 @@ func TestUsers(t *testing.T) {
 		}
 	})
 
+	t.Run("Test upgrade a legacy password hash", func(t *testing.T) {
+		ok, err := controllers.CheckPassword(&user, password)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if !ok {
+			t.Errorf("legacy password did not verify")
+		}
+
+		if !strings.HasPrefix(user.EncryptedPassword, "$argon2id$") {
+			t.Errorf("Wanted an argon2id hash, Got %s", user.EncryptedPassword)
+		}
+
+		user2, err := database.FindUser(userID)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if ok, err := controllers.CheckPassword(&user2, password); err != nil || !ok {
+			t.Errorf("upgraded password did not verify: %v", err)
+		}
+
+		if ok, _ := controllers.CheckPassword(&user2, "not-the-password"); ok {
+			t.Errorf("wrong password verified")
+		}
+	})
+
 	t.Run("Test update a user", func(t *testing.T) {
 		user.IsActive = false
 		if err := database.UpdateUser(&user); err != nil {
@@ -2,6 +2,7 @@ package test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -147,4 +148,66 @@ func TestEntries(t *testing.T) {
 			t.Errorf("Wanted: %s, Got %s", entry.DispositionNote, entry2.DispositionNote)
 		}
 	})
+
+	t.Run("test entry revision recorded", func(t *testing.T) {
+		revisions, err := database.FindEntryRevisions(entryID)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if len(revisions) != 1 {
+			t.Fatalf("Wanted 1 revision, Got %d", len(revisions))
+		}
+
+		change, ok := revisions[0].Changes["disposition_note"]
+		if !ok {
+			t.Fatalf("disposition_note not recorded in %v", revisions[0].Changes)
+		}
+
+		if change.From != "" || change.To != "To Be Deleted" {
+			t.Errorf("Wanted `` -> `To Be Deleted`, Got `%v` -> `%v`", change.From, change.To)
+		}
+	})
+
+	t.Run("test entry json follows the entry", func(t *testing.T) {
+		ej, err := database.FindEntryJSONByEntryID(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ej.EntryID != entryID {
+			t.Errorf("Wanted json for %s, Got %s", entryID, ej.EntryID)
+		}
+
+		if !strings.Contains(ej.JSON, "Rusty Buckles") {
+			t.Errorf("json not refreshed: %s", ej.JSON)
+		}
+	})
+
+	t.Run("test verify and repair entry json", func(t *testing.T) {
+		ej, err := database.FindEntryJSONByEntryID(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ej.JSON = "{}"
+		if err := database.UpdateEntryJSON(ej); err != nil {
+			t.Fatal(err)
+		}
+
+		drift, err := database.VerifyJSON(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(drift.Stale) != 1 || drift.Stale[0] != entryID {
+			t.Errorf("Wanted %s reported as stale, Got %s", entryID, drift.String())
+		}
+
+		drift, err = database.VerifyJSON(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if drift.HasDrift() {
+			t.Errorf("Wanted no drift after repair, Got %s", drift.String())
+		}
+	})
 }
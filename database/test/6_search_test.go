@@ -21,4 +21,95 @@ func TestSearch(t *testing.T) {
 			t.Errorf("Wanted %d entry, Got %d", want, got)
 		}
 	})
+
+	t.Run("Test parse a fielded query", func(t *testing.T) {
+		search := database.ParseEntryQuery(`floppy label_text:"rusty buckles" box_number:3 other:thing`)
+
+		if len(search.Terms) != 2 || search.Terms[0] != "floppy" || search.Terms[1] != "other:thing" {
+			t.Errorf("unexpected terms %v", search.Terms)
+		}
+
+		if search.Fields["label_text"] != "rusty buckles" || search.Fields["box_number"] != "3" {
+			t.Errorf("unexpected fields %v", search.Fields)
+		}
+	})
+
+	t.Run("Test fielded search with filters and facets", func(t *testing.T) {
+		search := database.ParseEntryQuery(`label_text:"Rusty Buck"`)
+		search.ResourceID = resourceID
+
+		result, err := database.SearchEntryPage(search, database.Pagination{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Pagination.TotalRecords != 1 || len(result.Results) != 1 {
+			t.Fatalf("Wanted 1 entry, Got %d", result.Pagination.TotalRecords)
+		}
+
+		if len(result.Facets["mediatype"]) != 1 || result.Facets["mediatype"][0].Value != "stuff" || result.Facets["mediatype"][0].Count != 1 {
+			t.Errorf("unexpected mediatype facets %v", result.Facets["mediatype"])
+		}
+
+		search.Fields["manufacturer"] = "nobody"
+		result, err = database.SearchEntryPage(search, database.Pagination{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Pagination.TotalRecords != 0 {
+			t.Errorf("Wanted 0 entries, Got %d", result.Pagination.TotalRecords)
+		}
+	})
+
+	t.Run("Test wildcards in a fielded search are matched literally", func(t *testing.T) {
+		for _, query := range []string{`label_text:%`, `label_text:"Rusty_Buck"`, `label_text:\`} {
+			result, err := database.SearchEntryPage(database.ParseEntryQuery(query), database.Pagination{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Pagination.TotalRecords != 0 {
+				t.Errorf("Wanted 0 entries for %s, Got %d", query, result.Pagination.TotalRecords)
+			}
+		}
+
+		repositories, err := database.SearchRepositories("%")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(repositories) != 0 {
+			t.Errorf("Wanted 0 repositories, Got %d", len(repositories))
+		}
+	})
+
+	t.Run("Test full-text search orders by relevance", func(t *testing.T) {
+		result, err := database.SearchEntryPage(database.ParseEntryQuery("buck"), database.Pagination{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Pagination.TotalRecords != 1 {
+			t.Errorf("Wanted 1 prefix match, Got %d", result.Pagination.TotalRecords)
+		}
+
+		if result.Pagination.Sort != "relevance" {
+			t.Errorf("Wanted relevance ordering, Got %s", result.Pagination.Sort)
+		}
+	})
+	t.Run("Test full-text search finds entries by id after a vacuum", func(t *testing.T) {
+		if env.DatabaseConfig.Driver != database.DriverSQLite {
+			t.Skip("vacuum only renumbers rowids on sqlite")
+		}
+		if err := db.Exec("VACUUM").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := database.SearchEntryPage(database.ParseEntryQuery("buck"), database.Pagination{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Results) != 1 || result.Results[0].ID != entryID {
+			t.Errorf("Wanted entry %s, Got %v", entryID, result.Results)
+		}
+	})
 }
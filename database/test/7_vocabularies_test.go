@@ -0,0 +1,65 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestVocabularies(t *testing.T) {
+
+	t.Run("Test seed the vocabularies", func(t *testing.T) {
+		if err := controllers.LoadVocabularies(); err != nil {
+			t.Fatal(err)
+		}
+
+		terms, err := database.FindVocabularyTermsByVocabulary(controllers.VocabularyStorageLocations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(terms) == 0 {
+			t.Errorf("expected storage locations to be seeded")
+		}
+
+		if label := controllers.GetStorageLocation("sl_rsw_acm_born_digital"); label != "RW ACM Born Digital" {
+			t.Errorf("unexpected storage location label %s", label)
+		}
+	})
+
+	term := models.VocabularyTerm{
+		Vocabulary: controllers.VocabularyStorageLocations,
+		Key:        fmt.Sprintf("sl_test_%d", time.Now().UnixNano()),
+		Label:      "Test Workstation",
+	}
+
+	t.Run("Test add a vocabulary term", func(t *testing.T) {
+		if err := database.InsertVocabularyTerm(&term); err != nil {
+			t.Fatal(err)
+		}
+		controllers.InvalidateVocabularies()
+
+		if _, ok := controllers.GetStorageLocations()[term.Key]; !ok {
+			t.Errorf("expected %s in the storage location options", term.Key)
+		}
+	})
+
+	t.Run("Test retire a vocabulary term", func(t *testing.T) {
+		term.IsRetired = true
+		if err := database.UpdateVocabularyTerm(&term); err != nil {
+			t.Fatal(err)
+		}
+		controllers.InvalidateVocabularies()
+
+		if _, ok := controllers.GetStorageLocations()[term.Key]; ok {
+			t.Errorf("retired term %s should not be offered", term.Key)
+		}
+
+		if label := controllers.GetStorageLocation(term.Key); label != term.Label {
+			t.Errorf("retired term %s should still resolve, got %s", term.Key, label)
+		}
+	})
+}
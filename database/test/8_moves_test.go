@@ -0,0 +1,87 @@
+package test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestMoves(t *testing.T) {
+
+	t.Run("Test move entries between resources", func(t *testing.T) {
+		repoID, err := database.CreateRepository(&models.Repository{Slug: "moves", Title: "Moves"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		accessionIDs := []uint{}
+		entries := []models.Entry{}
+		for _, code := range []string{"moves-a", "moves-b"} {
+			resID, err := database.InsertResource(&models.Resource{RepositoryID: repoID, CollectionCode: code})
+			if err != nil {
+				t.Fatal(err)
+			}
+			accID, err := database.InsertAccession(&models.Accession{ResourceID: resID, AccessionNum: code})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := models.Entry{ID: uuid.New(), MediaID: 1, RepositoryID: repoID, ResourceID: resID, AccessionID: accID, Mediatype: "stuff"}
+			if err := database.InsertEntry(&entry); err != nil {
+				t.Fatal(err)
+			}
+			accessionIDs = append(accessionIDs, accID)
+			entries = append(entries, entry)
+		}
+
+		//media id 1 is taken in the target resource so the entry is renumbered
+		moves, err := database.MoveEntries([]uuid.UUID{entries[0].ID}, accessionIDs[1], int(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(moves) != 1 || moves[0].FromMediaID != 1 || moves[0].ToMediaID != 2 || moves[0].FromAccessionID != accessionIDs[0] {
+			t.Errorf("Unexpected move %v", moves)
+		}
+
+		moved, err := database.FindEntry(entries[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if moved.AccessionID != accessionIDs[1] || moved.ResourceID != entries[1].ResourceID || moved.MediaID != 2 {
+			t.Errorf("Entry not moved: %v", moved)
+		}
+
+		//media id 2 is free in the original resource so it is kept on the way back
+		if moves, err = database.MoveEntries([]uuid.UUID{entries[0].ID}, accessionIDs[0], int(userID)); err != nil {
+			t.Fatal(err)
+		}
+		if moves[0].ToMediaID != 2 {
+			t.Errorf("Wanted media id 2 kept, got %d", moves[0].ToMediaID)
+		}
+
+		history, err := database.FindEntryMoves(entries[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 {
+			t.Errorf("Wanted 2 recorded moves, got %d", len(history))
+		}
+
+		//moving into the current accession fails and nothing in the batch moves
+		if _, err := database.MoveEntries([]uuid.UUID{entries[1].ID, entries[0].ID}, accessionIDs[0], int(userID)); err == nil {
+			t.Error("Wanted an error moving an entry into its own accession")
+		}
+		if unmoved, _ := database.FindEntry(entries[1].ID); unmoved.AccessionID != accessionIDs[1] {
+			t.Errorf("Entry %s moved by a failed batch", entries[1].ID)
+		}
+
+		if _, err := database.DeleteRepositoryCascade(repoID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := database.PurgeFromTrash(database.TrashRepositories, strconv.Itoa(int(repoID))); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
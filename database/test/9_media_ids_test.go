@@ -0,0 +1,110 @@
+package test
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestMediaIDs(t *testing.T) {
+
+	repoID, err := database.CreateRepository(&models.Repository{Slug: "media-ids", Title: "Media IDs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resID, err := database.InsertResource(&models.Resource{RepositoryID: repoID, CollectionCode: "media-ids"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accID, err := database.InsertAccession(&models.Accession{ResourceID: resID, AccessionNum: "media-ids"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newEntry := func(mediaID uint) models.Entry {
+		return models.Entry{ID: uuid.New(), MediaID: mediaID, RepositoryID: repoID, ResourceID: resID, AccessionID: accID, Mediatype: "stuff"}
+	}
+
+	t.Run("Test duplicate media id is refused", func(t *testing.T) {
+		first := newEntry(5)
+		if err := database.InsertEntry(&first); err != nil {
+			t.Fatal(err)
+		}
+
+		duplicate := newEntry(5)
+		var takenError *database.MediaIDTakenError
+		if err := database.InsertEntry(&duplicate); !errors.As(err, &takenError) {
+			t.Errorf("Wanted a media id taken error, got %v", err)
+		}
+
+		//trashed entries keep their media ids
+		if err := database.DeleteEntry(first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if free, _ := database.IsMediaIDUniqueInResource(5, resID); free {
+			t.Error("Wanted the media id of a trashed entry to stay taken")
+		}
+	})
+
+	t.Run("Test concurrent media id allocation", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				entry := newEntry(0)
+				errs <- database.InsertEntryWithNextMediaID(&entry)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+
+		if next, _ := database.FindNextMediaCollectionInResource(resID); next != 16 {
+			t.Errorf("Wanted next media id 16, got %d", next)
+		}
+
+		duplicates, err := database.FindDuplicateMediaIDs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(duplicates) > 0 {
+			t.Errorf("Wanted no duplicate media ids, got %v", duplicates)
+		}
+	})
+
+	t.Run("Test purged media ids are not reused", func(t *testing.T) {
+		last, err := database.FindEntryInResource(int(resID), 15)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.DeleteEntry(uuid.MustParse(last)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := database.PurgeFromTrash(database.TrashEntries, last); err != nil {
+			t.Fatal(err)
+		}
+		if next, _ := database.FindNextMediaCollectionInResource(resID); next != 16 {
+			t.Errorf("Wanted next media id 16 after purge, got %d", next)
+		}
+
+		if _, err := database.DeleteRepositoryCascade(repoID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := database.PurgeFromTrash(database.TrashRepositories, strconv.Itoa(int(repoID))); err != nil {
+			t.Fatal(err)
+		}
+		if next, _ := database.FindNextMediaCollectionInResource(resID); next != 1 {
+			t.Errorf("Wanted the counter of a purged resource removed, got next media id %d", next)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/fixity"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestImageFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "disk.img")
+	if err := os.WriteFile(path, []byte("disk image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imageFile := models.ImageFile{}
+
+	t.Run("Test register an image file", func(t *testing.T) {
+		paths, err := fixity.ListFiles(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != 1 || paths[0] != path {
+			t.Fatalf("Wanted [%s], got %v", path, paths)
+		}
+
+		checksums, err := fixity.HashFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fixity.Checksums{Size: 10, MD5: "76917fc3f6bf85eb9367d8a411174320", SHA256: "0bb2f0f3ed953c47d835a7adaefd95afa328e30a5c80fdce417dd12b014ad602"}
+		if !checksums.Matches(want) {
+			t.Errorf("Unexpected checksums %v", checksums)
+		}
+
+		imageFile = models.ImageFile{EntryID: entryID, Path: path, Size: checksums.Size, MD5: checksums.MD5, SHA256: checksums.SHA256, Status: models.ImageFileUnchecked, RegisteredAt: time.Now()}
+		if err := database.RegisterImageFile(&imageFile); err != nil {
+			t.Fatal(err)
+		}
+
+		imageFiles, err := database.FindImageFilesByEntryID(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(imageFiles) != 1 || imageFiles[0].SHA256 != checksums.SHA256 {
+			t.Errorf("Wanted the registered file, got %v", imageFiles)
+		}
+	})
+
+	t.Run("Test record fixity events", func(t *testing.T) {
+		if _, err := database.RecordFixityEvent(&imageFile, models.ImageFileOK, "checksums match"); err != nil {
+			t.Fatal(err)
+		}
+		problems, err := database.FindImageFileProblems(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(problems) != 0 {
+			t.Errorf("Wanted no problems, got %v", problems)
+		}
+
+		event, err := database.RecordFixityEvent(&imageFile, models.ImageFileMissing, "file not found")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Outcome != models.FixityFail {
+			t.Errorf("Wanted a failed event, got %s", event.Outcome)
+		}
+
+		problems, err = database.FindImageFileProblems(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(problems) != 1 || problems[0].Path != path || problems[0].CollectionCode == "" {
+			t.Errorf("Wanted %s reported missing, got %v", path, problems)
+		}
+
+		events, err := database.FindFixityEventsByEntryID(entryID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2 || events[0].Outcome != models.FixityFail {
+			t.Errorf("Wanted the failed event first, got %v", events)
+		}
+	})
+}
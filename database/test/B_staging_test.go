@@ -0,0 +1,57 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+func TestStaging(t *testing.T) {
+
+	t.Run("Test scan a staging directory", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"mss.1000_0789.E01", "mss.1000_0789.E02", "mss.1000_0789.E01.txt", "mss.999_1.img", "mss.10000789.img", "notes.txt"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		controllers.SetStagingRoots(map[string]string{"sl_rsw_amatica_staging": dir})
+		defer controllers.SetStagingRoots(map[string]string{})
+
+		report, err := controllers.ScanStaging(int(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		//mss.10000789 is not media id 789 of mss.1000, the collection code must end at a separator
+		if report.Scanned != 6 || len(report.Matched) != 3 || len(report.Unmatched) != 2 || len(report.Errors) != 0 {
+			t.Errorf("Unexpected report %s: %v", report.String(), report)
+		}
+		for _, file := range report.Unmatched {
+			if name := filepath.Base(file.Path); name != "mss.999_1.img" && name != "mss.10000789.img" {
+				t.Errorf("Wanted %s matched", name)
+			}
+		}
+
+		entry, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.ImageFilename != "mss.1000_0789.E01" || entry.ImageFormat != "image_format_e01" || entry.Location != "sl_rsw_amatica_staging" {
+			t.Errorf("Entry not updated from staging: %s %s %s", entry.ImageFilename, entry.ImageFormat, entry.Location)
+		}
+
+		//a second scan matches by image filename and changes nothing
+		report, err = controllers.ScanStaging(int(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range report.Matched {
+			if len(file.Updated) > 0 {
+				t.Errorf("Wanted no updates on a rescan, %s updated %v", file.Path, file.Updated)
+			}
+		}
+	})
+}
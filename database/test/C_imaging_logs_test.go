@@ -0,0 +1,114 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+const ftkImagerLog = `Created By AccessData® FTK® Imager 4.2.0.13
+
+Case Information: 
+Acquired using: ADI4.2.0.13
+Case Number: mss.1000
+Evidence Number: 789
+
+Information for D:\staging\mss.1000_0789:
+
+Physical Evidentiary Item (Source) Information:
+[Device Info]
+ Source Type: Physical
+[Drive Geometry]
+ Bytes per Sector: 512
+ Sector Count: 2,880
+[Physical Drive Information]
+ Drive Model: Tableau T8-R2 Forensic USB Bridge USB Device
+[Computed Hashes]
+ MD5 checksum:    c1b6b3a5c4b8b0b0c58f2e5a3a4f0e1d
+ SHA1 checksum:   2fd4e1c67a2d28fced849ee1bb76e7391b93eb12
+
+Image Information:
+ Acquisition started:   Mon Oct 12 10:00:00 2026
+ Acquisition finished:  Mon Oct 12 10:01:00 2026
+ Segment list:
+  D:\staging\mss.1000_0789.E01
+
+Image Verification Results:
+ Verification started:  Mon Oct 12 10:01:00 2026
+ Verification finished: Mon Oct 12 10:01:30 2026
+ MD5 checksum:    c1b6b3a5c4b8b0b0c58f2e5a3a4f0e1d : verified
+ SHA1 checksum:   2fd4e1c67a2d28fced849ee1bb76e7391b93eb12 : verified
+`
+
+const kryofluxLog = `KryoFlux DiskTool Console, v3.00_Win32, built Jan 10 2018, 12:00:00
+(c) 2009-2018 KryoFlux Products & Services Ltd.
+dtc -fD:\staging\mss.1000_0789.img -i4 -e79
+00.0    : MFM: OK, trk: 000, sec: 9
+00.1    : MFM: OK, trk: 000, sec: 9
+01.0    : MFM: OK, trk: 001, sec: 9
+01.1    : MFM: <missing sectors>
+`
+
+func TestImagingLogs(t *testing.T) {
+
+	t.Run("Test read an FTK Imager log", func(t *testing.T) {
+		entry, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proposal, err := controllers.ReadImagingLog(entry, strings.NewReader(ftkImagerLog))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proposal.Log.ImageSize != 2880*512 || proposal.Log.ImageFilename != "mss.1000_0789.E01" || proposal.Log.Verified == nil || !*proposal.Log.Verified {
+			t.Errorf("Unexpected log %v", proposal.Log)
+		}
+
+		proposal.Apply(&entry)
+		if entry.ImagingSoftware != "imaging_software_ftk_imager_v42013" || entry.ImageFormat != "image_format_e01" || entry.ImagingSuccess != "image_success_yes" || entry.Interface != "interface_tableau_t8r2" {
+			t.Errorf("Unexpected proposal %v", proposal.Values)
+		}
+		if !strings.Contains(entry.ImagingNote, "MD5 c1b6b3a5c4b8b0b0c58f2e5a3a4f0e1d") || !strings.Contains(entry.ImagingNote, "1474560 bytes") {
+			t.Errorf("Hashes and size missing from the imaging note: %s", entry.ImagingNote)
+		}
+
+		//nothing is saved until the entry is updated
+		saved, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.ImagingSoftware == entry.ImagingSoftware {
+			t.Errorf("Wanted the proposal not to be saved")
+		}
+	})
+
+	t.Run("Test read a KryoFlux log", func(t *testing.T) {
+		entry, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proposal, err := controllers.ReadImagingLog(entry, strings.NewReader(kryofluxLog))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proposal.Log.GoodTracks != 3 || proposal.Log.BadTracks != 1 || len(proposal.Warnings) != 1 {
+			t.Errorf("Unexpected log %v, warnings %v", proposal.Log, proposal.Warnings)
+		}
+
+		proposal.Apply(&entry)
+		if entry.ImagingSoftware != "imaging_software_kryoflux_imager_v30" || entry.Interface != "interface_kryoflux" || entry.ImageFormat != "image_format_raw" || entry.ImagingSuccess != "image_success_no" {
+			t.Errorf("Unexpected proposal %v", proposal.Values)
+		}
+	})
+
+	t.Run("Test reject an unknown log", func(t *testing.T) {
+		if _, err := controllers.ReadImagingLog(models.Entry{}, strings.NewReader("hello")); err == nil {
+			t.Errorf("Wanted an error for a file that is not an imaging log")
+		}
+	})
+}
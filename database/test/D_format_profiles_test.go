@@ -0,0 +1,104 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+)
+
+const dfxmlReport = `<?xml version="1.0" encoding="UTF-8"?>
+<dfxml xmlns="http://www.forensicswiki.org/wiki/Category:Digital_Forensics_XML" version="1.0">
+  <creator><program>fiwalk</program></creator>
+  <volume offset="0">
+    <ftype_str>fat12</ftype_str>
+    <fileobject>
+      <filename>LETTER.DOC</filename>
+      <filesize>1000</filesize>
+      <name_type>r</name_type>
+      <alloc>1</alloc>
+    </fileobject>
+    <fileobject>
+      <filename>PHOTOS</filename>
+      <filesize>512</filesize>
+      <name_type>d</name_type>
+      <alloc>1</alloc>
+    </fileobject>
+    <fileobject>
+      <filename>PHOTOS/A.JPG</filename>
+      <filesize>2000</filesize>
+      <name_type>r</name_type>
+      <alloc>1</alloc>
+    </fileobject>
+    <fileobject>
+      <filename>_ELETED.TXT</filename>
+      <filesize>10</filesize>
+      <name_type>r</name_type>
+      <unalloc>1</unalloc>
+    </fileobject>
+  </volume>
+</dfxml>
+`
+
+const siegfriedReport = `{"siegfried":"1.11.0","scandate":"2026-10-12T10:00:00Z","identifiers":[{"name":"pronom"}],"files":[
+{"filename":"LETTER.DOC","filesize":1000,"errors":"","matches":[{"ns":"pronom","id":"fmt/39","format":"Microsoft Word Document","version":"6.0/95","mime":"application/msword"}]},
+{"filename":"PHOTOS/A.JPG","filesize":2000,"errors":"","matches":[{"ns":"pronom","id":"fmt/43","format":"JPEG File Interchange Format","version":"1.01","mime":"image/jpeg"}]},
+{"filename":"PHOTOS/B.JPG","filesize":3000,"errors":"","matches":[{"ns":"pronom","id":"fmt/43","format":"JPEG File Interchange Format","version":"1.01","mime":"image/jpeg"}]},
+{"filename":"NOTES","filesize":5,"errors":"","matches":[{"ns":"pronom","id":"UNKNOWN","format":"","version":"","mime":""}]}]}`
+
+const droidReport = `"ID","PARENT_ID","URI","FILE_PATH","NAME","METHOD","STATUS","SIZE","TYPE","EXT","LAST_MODIFIED","EXTENSION_MISMATCH","HASH","FORMAT_COUNT","PUID","MIME_TYPE","FORMAT_NAME","FORMAT_VERSION"
+"1","","file:/x/","/x","x","","Done","","Folder","","","false","","","","","",""
+"2","1","file:/x/a.pdf","/x/a.pdf","a.pdf","Signature","Done","400","File","pdf","","false","","1","fmt/276","application/pdf","Acrobat PDF 1.7 - Portable Document Format","1.7"
+"3","1","file:/x/b.pdf","/x/b.pdf","b.pdf","Signature","Done","600","File","pdf","","false","","1","fmt/276","application/pdf","Acrobat PDF 1.7 - Portable Document Format","1.7"
+`
+
+func TestFormatProfiles(t *testing.T) {
+
+	t.Run("Test attach DFXML and Siegfried reports", func(t *testing.T) {
+		profile, err := controllers.AttachFormatReport(entryID, strings.NewReader(dfxmlReport), int(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.FileCount != 2 || profile.TotalBytes != 3000 || len(profile.Filesystems) != 1 || profile.Filesystems[0] != "fat12" || len(profile.Formats) != 0 {
+			t.Errorf("Unexpected profile from DFXML %v", profile)
+		}
+
+		profile, err = controllers.AttachFormatReport(entryID, strings.NewReader(siegfriedReport), int(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.FileCount != 4 || profile.TotalBytes != 6005 || len(profile.Filesystems) != 1 || len(profile.Reports) != 2 {
+			t.Errorf("Unexpected profile after Siegfried %v", profile)
+		}
+		if len(profile.Formats) != 3 || profile.Formats[0].PUID != "fmt/43" || profile.Formats[0].Count != 2 || profile.Formats[0].Bytes != 5000 {
+			t.Errorf("Unexpected formats %v", profile.Formats)
+		}
+	})
+
+	t.Run("Test a DROID report replaces the formats", func(t *testing.T) {
+		profile, err := controllers.AttachFormatReport(entryID, strings.NewReader(droidReport), int(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.FileCount != 2 || len(profile.Formats) != 1 || profile.Formats[0].PUID != "fmt/276" || profile.Formats[0].Bytes != 1000 {
+			t.Errorf("Unexpected profile after DROID %v", profile)
+		}
+	})
+
+	t.Run("Test summarize formats by resource", func(t *testing.T) {
+		summary, err := database.GetFormatSummaryByResource(resourceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.Entries != 1 || summary.FileCount != 2 || summary.Filesystems["fat12"] != 1 || len(summary.Formats) != 1 || summary.Formats[0].PUID != "fmt/276" || summary.Formats[0].Count != 2 {
+			t.Errorf("Unexpected summary %v", summary)
+		}
+	})
+
+	t.Run("Test reject a report that is not DFXML, Siegfried or DROID", func(t *testing.T) {
+		if _, err := controllers.AttachFormatReport(entryID, strings.NewReader("a,b\n1,2\n"), int(userID)); err == nil {
+			t.Errorf("Wanted an error for an unknown csv")
+		}
+	})
+}
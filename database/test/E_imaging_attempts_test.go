@@ -0,0 +1,94 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestImagingAttempts(t *testing.T) {
+	var succeeded models.ImagingAttempt
+
+	t.Run("Test a failed attempt marks the entry unsuccessful", func(t *testing.T) {
+		attempt := models.ImagingAttempt{
+			AttemptedAt:     time.Now().Add(-48 * time.Hour),
+			ImagedBy:        "Test Technician",
+			Interface:       "interface_tableau_ultrablock",
+			ImagingSoftware: "imaging_software_ftk_imager_v42013",
+			ImagingSuccess:  models.ImageSuccessNo,
+			Note:            "drive could not read sector 0",
+		}
+		if _, err := controllers.RecordImagingAttempt(entryID, attempt, int(userID)); err != nil {
+			t.Fatal(err)
+		}
+
+		entry, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.ImagingSuccess != models.ImageSuccessNo {
+			t.Errorf("Wanted imaging success %s, got %s", models.ImageSuccessNo, entry.ImagingSuccess)
+		}
+	})
+
+	t.Run("Test the entry follows the latest successful attempt", func(t *testing.T) {
+		var err error
+		succeeded, err = controllers.RecordImagingAttempt(entryID, models.ImagingAttempt{
+			AttemptedAt:     time.Now().Add(-24 * time.Hour),
+			ImagedBy:        "Second Technician",
+			Interface:       "interface_kryoflux",
+			ImagingSoftware: "imaging_software_kryoflux_imager_v30",
+			EncodingScheme:  "encoding_scheme_mfm",
+			ImagingSuccess:  models.ImageSuccessYes,
+		}, int(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		//a later failed attempt does not replace the successful one
+		if _, err := controllers.RecordImagingAttempt(entryID, models.ImagingAttempt{ImagedBy: "Third Technician", ImagingSuccess: models.ImageSuccessNo}, int(userID)); err != nil {
+			t.Fatal(err)
+		}
+
+		entry, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.ImagingSuccess != models.ImageSuccessYes || entry.Interface != "interface_kryoflux" || entry.ImagingSoftware != "imaging_software_kryoflux_imager_v30" || entry.ImagedBy != "Second Technician" {
+			t.Errorf("Entry not derived from the successful attempt: %s %s %s %s", entry.ImagingSuccess, entry.Interface, entry.ImagingSoftware, entry.ImagedBy)
+		}
+
+		attempts, err := database.FindImagingAttemptsByEntryID(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attempts) != 3 || attempts[0].ImagedBy != "Third Technician" {
+			t.Errorf("Wanted 3 attempts latest first, got %v", attempts)
+		}
+	})
+
+	t.Run("Test deleting the successful attempt", func(t *testing.T) {
+		if err := database.DeleteImagingAttempt(entryID, succeeded.ID, int(userID)); err != nil {
+			t.Fatal(err)
+		}
+		entry, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.ImagingSuccess != models.ImageSuccessNo {
+			t.Errorf("Wanted imaging success %s after deleting the successful attempt, got %s", models.ImageSuccessNo, entry.ImagingSuccess)
+		}
+	})
+
+	t.Run("Test reject an invalid attempt", func(t *testing.T) {
+		if _, err := controllers.RecordImagingAttempt(entryID, models.ImagingAttempt{ImagingSuccess: "maybe"}, int(userID)); err == nil {
+			t.Errorf("Wanted an error for an invalid result")
+		}
+		if _, err := controllers.RecordImagingAttempt(entryID, models.ImagingAttempt{ImagingSuccess: models.ImageSuccessYes, Interface: "interface_punch_cards"}, int(userID)); err == nil {
+			t.Errorf("Wanted an error for an unknown interface")
+		}
+	})
+}
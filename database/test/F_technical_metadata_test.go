@@ -0,0 +1,75 @@
+package test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestTechnicalMetadata(t *testing.T) {
+	t.Run("Test update an entry's filesystems, encoding scheme and structure", func(t *testing.T) {
+		entry, err := database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		edited := entry
+		edited.Filesystems = []string{"filesystem_hfs", "filesystem_9660"}
+		edited.EncodingScheme = "encoding_scheme_apple_400_800"
+		edited.Structure = "structure_data"
+		if err := controllers.ValidateTechnicalMetadata(edited); err != nil {
+			t.Fatal(err)
+		}
+
+		entry.UpdateEntry(edited)
+		entry.UpdatedAt = time.Now()
+		if err := database.UpdateEntry(&entry); err != nil {
+			t.Fatal(err)
+		}
+
+		entry, err = database.FindEntry(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(entry.Filesystems, []string{"filesystem_hfs", "filesystem_9660"}) || entry.EncodingScheme != "encoding_scheme_apple_400_800" || entry.Structure != "structure_data" {
+			t.Errorf("Technical metadata not saved: %v %s %s", entry.Filesystems, entry.EncodingScheme, entry.Structure)
+		}
+
+		revisions, err := database.FindEntryRevisions(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := revisions[0].Changes["filesystems"]; !ok {
+			t.Errorf("filesystems not recorded in %v", revisions[0].Changes)
+		}
+	})
+
+	t.Run("Test reject unknown technical metadata terms", func(t *testing.T) {
+		for _, entry := range []models.Entry{
+			{Filesystems: []string{"filesystem_zfs"}},
+			{EncodingScheme: "encoding_scheme_gcr"},
+			{Structure: "structure_blu_ray"},
+		} {
+			if err := controllers.ValidateTechnicalMetadata(entry); err == nil {
+				t.Errorf("Wanted an error for %v %s %s", entry.Filesystems, entry.EncodingScheme, entry.Structure)
+			}
+		}
+	})
+
+	t.Run("Test mediatype categories", func(t *testing.T) {
+		for mediatype, want := range map[string]string{
+			"mediatype_floppy_5_25":     controllers.MediaCategoryFloppy,
+			"mediatype_dvdr":            controllers.MediaCategoryOptical,
+			"mediatype_hard_disk_drive": controllers.MediaCategoryHDD,
+			"mediatype_file_transfer":   controllers.MediaCategoryTransfer,
+		} {
+			if got := controllers.MediatypeCategory(mediatype); got != want {
+				t.Errorf("Wanted %s for %s, got %s", want, mediatype, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestRoles(t *testing.T) {
+	t.Run("Test reject invalid role grants", func(t *testing.T) {
+		for _, grant := range []models.RoleGrant{
+			{Role: "owner", RepositoryID: repositoryID},
+			{Role: models.RoleAdmin},
+			{Role: models.RoleViewer, RepositoryID: 999999},
+		} {
+			if _, err := controllers.GrantUserRole(userID, grant, int(userID)); err == nil {
+				t.Errorf("Wanted an error granting %s in repository %d", grant.Role, grant.RepositoryID)
+			}
+		}
+	})
+
+	t.Run("Test grant roles globally and in a repository", func(t *testing.T) {
+		if _, err := controllers.GrantUserRole(userID, models.RoleGrant{Role: models.RoleViewer}, int(userID)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := controllers.GrantUserRole(userID, models.RoleGrant{Role: models.RoleCurator, RepositoryID: repositoryID}, int(userID)); err != nil {
+			t.Fatal(err)
+		}
+		//granting again replaces the role held in the repository
+		if _, err := controllers.GrantUserRole(userID, models.RoleGrant{Role: models.RoleTechnician, RepositoryID: repositoryID}, int(userID)); err != nil {
+			t.Fatal(err)
+		}
+
+		user, err := database.FindUser(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.IsAdmin = false
+		permissions, err := controllers.LoadPermissions(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repoID, err := database.FindRepositoryIDByEntryID(entryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if role := permissions.Role(repoID); role != models.RoleTechnician {
+			t.Errorf("Wanted technician in repository %d, got %s", repoID, role)
+		}
+		if !permissions.Can(models.RoleViewer, repoID+1) || permissions.Can(models.RoleTechnician, repoID+1) {
+			t.Errorf("Wanted only the global viewer role outside repository %d", repoID)
+		}
+		if permissions.Can(models.RoleCurator, repoID) || permissions.CanGlobally(models.RoleTechnician) {
+			t.Errorf("Wanted no more than technician, got %v", permissions)
+		}
+	})
+
+	t.Run("Test revoke a role grant", func(t *testing.T) {
+		grants, err := database.FindRoleGrantsByUserID(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(grants) != 2 {
+			t.Fatalf("Wanted 2 grants, got %d", len(grants))
+		}
+		for _, grant := range grants {
+			if err := database.RevokeRole(userID, grant.ID); err != nil {
+				t.Error(err)
+			}
+		}
+		if err := database.RevokeRole(userID, grants[0].ID); err == nil {
+			t.Error("Wanted an error revoking a grant twice")
+		}
+	})
+}
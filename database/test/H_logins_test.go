@@ -0,0 +1,105 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nyudlts/go-medialog/controllers"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestLogins(t *testing.T) {
+	var email = "logins@medialog.dlib.nyu.edu"
+	var password = "medialog-logins"
+	var ipAddress = "192.0.2.10"
+	config := models.LoginConfig{MaxFailures: 3, IPMaxFailures: 6}
+	controllers.SetLoginConfig(config)
+	defer controllers.SetLoginConfig(models.LoginConfig{})
+
+	user := models.User{Email: email, IsActive: true}
+	if err := controllers.SetPassword(&user, password); err != nil {
+		t.Fatal(err)
+	}
+	loginUserID, err := database.InsertUser(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.DeleteUser(loginUserID)
+
+	t.Run("Test lock an account after failed logins", func(t *testing.T) {
+		for i := 1; i < config.AccountMaxFailures(); i++ {
+			if _, status, err := controllers.CheckLogin(email, "not-the-password", ipAddress, controllers.LoginViaWeb); err == nil || status != http.StatusUnauthorized {
+				t.Fatalf("Wanted a 401 for wrong password %d, got %d", i, status)
+			}
+		}
+		if _, status, _ := controllers.CheckLogin(email, "not-the-password", ipAddress, controllers.LoginViaWeb); status != http.StatusUnauthorized {
+			t.Fatalf("Wanted a 401 for the wrong password that locks the account, got %d", status)
+		}
+		if locked, err := database.FindUser(loginUserID); err != nil || !locked.IsLocked() {
+			t.Fatalf("Wanted the account locked, got %v", err)
+		}
+
+		//a locked account is refused exactly like an unknown email
+		_, unknownStatus, unknownErr := controllers.CheckLogin("nobody@medialog.test", password, "192.0.2.12", controllers.LoginViaWeb)
+		_, status, err := controllers.CheckLogin(email, password, ipAddress, controllers.LoginViaWeb)
+		if err == nil || status != unknownStatus || err.Error() != unknownErr.Error() {
+			t.Errorf("Wanted a locked account refused like an unknown email, got %d %v", status, err)
+		}
+
+		attempts, err := database.FindLoginAttemptsByUserID(loginUserID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attempts) != 4 || attempts[0].Reason != "account locked" || attempts[0].IPAddress != ipAddress {
+			t.Errorf("Wanted 4 recorded attempts, the last refused as locked, got %v", attempts)
+		}
+	})
+
+	t.Run("Test unlock an account", func(t *testing.T) {
+		if err := database.UnlockUser(loginUserID); err != nil {
+			t.Fatal(err)
+		}
+		user, status, err := controllers.CheckLogin(email, password, ipAddress, controllers.LoginViaAPI)
+		if err != nil {
+			t.Fatalf("Wanted to log in after the unlock, got %d %s", status, err.Error())
+		}
+		if user.IsLocked() || user.FailedLogins != 0 {
+			t.Errorf("Wanted the failed logins cleared, got %d", user.FailedLogins)
+		}
+	})
+
+	t.Run("Test throttle an address", func(t *testing.T) {
+		for i := 0; i < config.AddressMaxFailures(); i++ {
+			controllers.CheckLogin("nobody@medialog.test", "guess", ipAddress, controllers.LoginViaWeb)
+		}
+		if _, status, _ := controllers.CheckLogin(email, password, ipAddress, controllers.LoginViaWeb); status != http.StatusTooManyRequests {
+			t.Errorf("Wanted the address refused, got %d", status)
+		}
+		if _, _, err := controllers.CheckLogin(email, password, "192.0.2.11", controllers.LoginViaWeb); err != nil {
+			t.Errorf("Wanted another address to log in, got %s", err.Error())
+		}
+	})
+
+	t.Run("Test password policy", func(t *testing.T) {
+		policy := models.PasswordPolicy{MinLength: 10, RequireUpper: true, RequireDigit: true, RequireSymbol: true}
+		for _, password := range []string{"Short1!", "no-uppercase-1", "No-Digits-Here", "NoSymbols123"} {
+			if err := policy.Check(password); err == nil {
+				t.Errorf("Wanted %s rejected", password)
+			}
+		}
+		if err := policy.Check("Long-Enough-1"); err != nil {
+			t.Error(err)
+		}
+
+		controllers.SetPasswordPolicy(policy)
+		defer controllers.SetPasswordPolicy(models.PasswordPolicy{})
+		password, err := controllers.GeneratePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := policy.Check(password); err != nil {
+			t.Errorf("Wanted a generated password to meet the policy: %s", err.Error())
+		}
+	})
+}
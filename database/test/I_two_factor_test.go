@@ -0,0 +1,54 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/nyudlts/go-medialog/database"
+)
+
+func TestTwoFactor(t *testing.T) {
+	t.Run("Test a code's time step is only accepted once", func(t *testing.T) {
+		if err := database.SetTOTPSecret(userID, "JBSWY3DPEHPK3PXP"); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.EnableTOTP(userID, 1000); err != nil {
+			t.Fatal(err)
+		}
+		for step, want := range map[int64]bool{999: false, 1000: false, 1001: true} {
+			if ok, err := database.UseTOTPStep(userID, step); err != nil || ok != want {
+				t.Errorf("Wanted step %d accepted %t, got %t %v", step, want, ok, err)
+			}
+		}
+	})
+
+	t.Run("Test a recovery code is only accepted once", func(t *testing.T) {
+		if err := database.ReplaceRecoveryCodes(userID, []string{"hash-1", "hash-2"}); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := database.UseRecoveryCode(userID, "hash-1"); err != nil || !ok {
+			t.Errorf("Wanted the recovery code accepted, got %t %v", ok, err)
+		}
+		if ok, _ := database.UseRecoveryCode(userID, "hash-1"); ok {
+			t.Error("Wanted a used recovery code refused")
+		}
+		if count, err := database.CountUnusedRecoveryCodes(userID); err != nil || count != 1 {
+			t.Errorf("Wanted 1 recovery code left, got %d %v", count, err)
+		}
+	})
+
+	t.Run("Test reset two-factor authentication", func(t *testing.T) {
+		if err := database.ResetTwoFactor(userID); err != nil {
+			t.Fatal(err)
+		}
+		user, err := database.FindUser(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.TOTPEnabled || user.TOTPSecret != "" {
+			t.Error("Wanted two-factor authentication turned off")
+		}
+		if count, _ := database.CountUnusedRecoveryCodes(userID); count != 0 {
+			t.Errorf("Wanted the recovery codes deleted, got %d", count)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	gormsessions "github.com/gin-contrib/sessions/gorm"
+	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestSessions(t *testing.T) {
+	//the sessions table belongs to the session store, which creates it
+	gormsessions.NewStore(db, true, []byte("test-session-secret"))
+
+	insertSession := func(id string, expiresAt time.Time) {
+		t.Helper()
+		if err := db.Exec("insert into sessions (id, data, created_at, updated_at, expires_at) values (?, ?, ?, ?, ?)", id, "", time.Now(), time.Now(), expiresAt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	countSessions := func(id string) int64 {
+		t.Helper()
+		var count int64
+		if err := db.Table("sessions").Where("id = ?", id).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	t.Run("Test delete expired sessions", func(t *testing.T) {
+		insertSession("expired-session", time.Now().Add(-time.Hour))
+		insertSession("current-session", time.Now().Add(time.Hour))
+
+		deleted, err := database.DeleteExpiredSessions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != 1 {
+			t.Errorf("Wanted 1 session deleted, got %d", deleted)
+		}
+		if countSessions("expired-session") != 0 {
+			t.Error("Wanted the expired session deleted")
+		}
+		if countSessions("current-session") != 1 {
+			t.Error("Wanted the current session kept")
+		}
+	})
+
+	t.Run("Test end a session", func(t *testing.T) {
+		token := models.Token{Token: "session-token", IsValid: true, Expires: time.Now().Add(time.Hour), UserID: userID, Type: "application", SessionID: "current-session"}
+		if err := database.InsertToken(&token); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := database.EndSession(token.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := database.FindTokenByID(token.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.IsValid {
+			t.Error("Wanted the session's token invalidated")
+		}
+		if countSessions("current-session") != 0 {
+			t.Error("Wanted the session deleted")
+		}
+		if err := database.DeleteToken(token.Token); err != nil {
+			t.Error(err)
+		}
+	})
+}
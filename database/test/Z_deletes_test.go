@@ -1,13 +1,107 @@
 package test
 
 import (
+	"errors"
+	"strconv"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/models"
 )
 
 func TestDeleteObjects(t *testing.T) {
 
+	//refuse to delete a repository that still has children
+	t.Run("Test refuse to delete a repository with children", func(t *testing.T) {
+		err := database.DeleteRepository(repositoryID)
+		var dependentsError *database.DependentsError
+		if !errors.As(err, &dependentsError) {
+			t.Fatalf("Wanted a dependents error, got %v", err)
+		}
+
+		want := database.DeleteSummary{Resources: 1, Accessions: 1, Entries: 1}
+		if dependentsError.Summary != want {
+			t.Errorf("Wanted %v, got %v", want, dependentsError.Summary)
+		}
+
+		if _, err := database.FindRepository(repositoryID); err != nil {
+			t.Error(err)
+		}
+	})
+
+	//cascade the delete of a repository
+	t.Run("Test cascade delete a repository", func(t *testing.T) {
+		repoID, err := database.CreateRepository(&models.Repository{Slug: "cascade", Title: "Cascade"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resID, err := database.InsertResource(&models.Resource{RepositoryID: repoID, CollectionCode: "cascade"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		accID, err := database.InsertAccession(&models.Accession{ResourceID: resID, AccessionNum: "cascade"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := models.Entry{ID: uuid.New(), MediaID: 1, RepositoryID: repoID, ResourceID: resID, AccessionID: accID}
+		if err := database.InsertEntry(&entry); err != nil {
+			t.Fatal(err)
+		}
+
+		summary, err := database.DeleteRepositoryCascade(repoID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := database.DeleteSummary{Resources: 1, Accessions: 1, Entries: 1}
+		if summary != want {
+			t.Errorf("Wanted %v, got %v", want, summary)
+		}
+
+		if _, err := database.FindEntry(entry.ID); err == nil {
+			t.Errorf("Found cascade deleted entry %s", entry.ID)
+		}
+
+		if count := database.GetCountOfEntriesInResource(resID); count != 0 {
+			t.Errorf("Wanted 0 entries in deleted resource, got %d", count)
+		}
+
+		//restoring the repository restores everything deleted with it
+		if _, err := database.RestoreFromTrash(database.TrashRepositories, strconv.Itoa(int(repoID))); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := database.FindEntry(entry.ID); err != nil {
+			t.Errorf("Restored entry not found: %s", err)
+		}
+
+		if _, err := database.DeleteRepositoryCascade(repoID); err != nil {
+			t.Fatal(err)
+		}
+
+		//entries inside a deleted accession can only be restored with it
+		if _, err := database.RestoreFromTrash(database.TrashEntries, entry.ID.String()); err == nil {
+			t.Errorf("Restored entry %s from a deleted accession", entry.ID)
+		}
+
+		if _, err := database.PurgeFromTrash(database.TrashRepositories, strconv.Itoa(int(repoID))); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := database.FindEntryJSONByEntryID(entry.ID); err == nil {
+			t.Errorf("Found search json of purged entry %s", entry.ID)
+		}
+
+		trash, err := database.FindTrash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash.Repositories) != 0 {
+			t.Errorf("Wanted an empty trash, got %d repositories", len(trash.Repositories))
+		}
+	})
+
 	//delete the Entry
 	t.Run("Test delete an entry", func(t *testing.T) {
 		if err := database.DeleteEntry(entryID); err != nil {
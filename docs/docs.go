@@ -42,7 +42,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns a list of all accessions.",
+                "description": "Returns a list of the accessions in repositories the user can view.",
                 "produces": [
                     "application/json"
                 ],
@@ -193,7 +193,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes an accession by its ID.",
+                "description": "Moves an accession to the trash by its ID. The delete is refused with 409 and a summary of the dependents while the accession still has entries, unless an admin sets cascade=true to move them to the trash too.",
                 "produces": [
                     "application/json"
                 ],
@@ -208,6 +208,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also delete the accession's entries (admin only)",
+                        "name": "cascade",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -226,7 +232,34 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.DeleteConflict"
                         }
                     },
                     "500": {
@@ -311,6 +344,71 @@ const docTemplate = `{
                 }
             }
         },
+        "/accessions/{id}/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Parses a csv of entries for an accession and returns a per-row validation report. The first row names the columns, which match the entry json fields (mediatype, stock_size_num, stock_unit, box_number, label_text, manufacturer, ...). Nothing is saved unless commit=true, in which case every row is inserted in one transaction with sequential media IDs, or none are if any row is invalid. The csv may be sent as the request body or as a multipart file named \"file\".",
+                "consumes": [
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accessions"
+                ],
+                "summary": "Import entries from csv",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Accession ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Insert the entries instead of a dry run",
+                        "name": "commit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EntryImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EntryImportReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/accessions/{id}/summary": {
             "get": {
                 "security": [
@@ -410,7 +508,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns paginated entries across all accessions. Use all_ids=true to return only UUIDs.",
+                "description": "Returns paginated entries across all accessions in repositories the user can view. Use all_ids=true to return only UUIDs.",
                 "produces": [
                     "application/json"
                 ],
@@ -468,7 +566,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Creates a new media entry within an accession.",
+                "description": "Creates a new media entry within an accession. An entry without a media_id is given the next free one in its resource, a media_id already used in the resource is a conflict.",
                 "consumes": [
                     "application/json"
                 ],
@@ -509,6 +607,72 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/entries/move": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Re-files several entries into an accession in one transaction, numbering them like a single move. Nothing is moved if any entry fails.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "entries"
+                ],
+                "summary": "Move entries",
+                "parameters": [
+                    {
+                        "description": "Entries and target accession",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.MoveRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.EntryMove"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -569,7 +733,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes a media entry by its UUID.",
+                "description": "Moves a media entry to the trash by its UUID, an admin can restore or purge it from the trash.",
                 "produces": [
                     "application/json"
                 ],
@@ -605,6 +769,15 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -614,24 +787,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/entries/{id}/update": {
-            "post": {
+        "/entries/{id}/format_profile": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Replaces all updatable fields of an entry by UUID.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the file count, content size, filesystems and PRONOM format breakdown read from the DFXML, Siegfried and DROID reports attached to an entry.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "entries"
                 ],
-                "summary": "Update entry",
+                "summary": "Get entry format profile",
                 "parameters": [
                     {
                         "type": "string",
@@ -639,22 +809,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Updated entry data",
-                        "name": "entry",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Entry"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.FormatProfile"
                         }
                     },
                     "400": {
@@ -669,6 +830,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -676,23 +843,27 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/entries/{id}/update_location": {
-            "patch": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Updates the storage location for a given entry by UUID.",
+                "description": "Parses a fiwalk DFXML file, a Siegfried json or csv report, or a DROID csv export and merges it into the entry's format profile. File count and content size come from the latest report, filesystems from DFXML and the PRONOM format breakdown from Siegfried or DROID. The report may be sent as the request body or as a multipart file named \"report\".",
+                "consumes": [
+                    "application/xml",
+                    "application/json",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "entries"
                 ],
-                "summary": "Update entry location",
+                "summary": "Attach a format report",
                 "parameters": [
                     {
                         "type": "string",
@@ -700,20 +871,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Storage location code",
-                        "name": "location",
-                        "in": "query",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.FormatProfile"
                         }
                     },
                     "400": {
@@ -737,24 +901,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/logout": {
-            "delete": {
+        "/entries/{id}/history": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Invalidates the current API token.",
+                "description": "Returns the recorded revisions of an entry, newest first. Each revision lists the changed fields with their previous and new values.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "entries"
+                ],
+                "summary": "Get entry history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Entry UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Logout",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.EntryRevision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "string"
                         }
@@ -762,10 +944,7 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -777,54 +956,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/reports/range": {
+        "/entries/{id}/image_files": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns total counts and sizes of media ingested within a date range. Dates must be in YYYYMMDD format.",
+                "description": "Returns the image files registered for an entry, with their checksums and status, and the latest fixity events.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "reports"
+                    "entries"
                 ],
-                "summary": "Date range summary",
+                "summary": "Get entry image files",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Start date (YYYYMMDD)",
-                        "name": "start_date",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "End date (YYYYMMDD)",
-                        "name": "end_date",
-                        "in": "query",
+                        "description": "Entry UUID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Filter by repository ID (0 = all)",
-                        "name": "repository_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Filter by refreshed status",
-                        "name": "is_refreshed",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.SummaryAndTotals"
+                            "$ref": "#/definitions/api.EntryImageFiles"
                         }
                     },
                     "400": {
@@ -846,75 +1006,39 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/repositories": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns a list of all repositories.",
+                "description": "Hashes every file below a directory on the server and registers it for the entry with its size, MD5 and SHA-256. The directory must be inside one of the configured image_roots. Registering a path again replaces its checksums.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "repositories"
+                    "entries"
                 ],
-                "summary": "List repositories",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Repository"
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            },
-            "post": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "Creates a new repository record.",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "repositories"
-                ],
-                "summary": "Create repository",
+                "summary": "Register entry image files",
                 "parameters": [
                     {
-                        "description": "Repository data",
-                        "name": "repository",
+                        "type": "string",
+                        "description": "Entry UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Directory to register",
+                        "name": "files",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Repository"
+                            "$ref": "#/definitions/api.ImageFilesRequest"
                         }
                     }
                 ],
@@ -922,7 +1046,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Repository"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ImageFile"
+                            }
                         }
                     },
                     "400": {
@@ -934,10 +1061,13 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -949,25 +1079,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/repositories/{id}": {
+        "/entries/{id}/imaging_attempts": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns a single repository by its ID.",
+                "description": "Returns the imaging attempts recorded for an entry, latest first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "repositories"
+                    "entries"
                 ],
-                "summary": "Get repository",
+                "summary": "Get entry imaging attempts",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Repository ID",
+                        "type": "string",
+                        "description": "Entry UUID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -977,7 +1107,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Repository"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ImagingAttempt"
+                            }
                         }
                     },
                     "400": {
@@ -989,10 +1122,7 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -1003,34 +1133,46 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes a repository by its ID.",
+                "description": "Records an attempt at imaging an entry. imaging_success is image_success_yes or image_success_no, interface and imaging_software are vocabulary keys, and attempted_at defaults to now. The entry's imaging_success, interface, imaging_software and imaged_by are then set from its latest successful attempt.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "repositories"
+                    "entries"
                 ],
-                "summary": "Delete repository",
+                "summary": "Record an imaging attempt",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Repository ID",
+                        "type": "string",
+                        "description": "Entry UUID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Imaging attempt",
+                        "name": "attempt",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ImagingAttempt"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.ImagingAttempt"
                         }
                     },
                     "400": {
@@ -1042,10 +1184,7 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -1057,53 +1196,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/repositories/{id}/entries": {
-            "get": {
+        "/entries/{id}/imaging_attempts/{attempt_id}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns paginated entries for a given repository. Use all_ids=true to return only entry UUIDs.",
+                "description": "Deletes an imaging attempt, the entry's imaging fields are derived again from the attempts left.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "repositories"
+                    "entries"
                 ],
-                "summary": "Get repository entries",
+                "summary": "Delete an imaging attempt",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Repository ID",
+                        "type": "string",
+                        "description": "Entry UUID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
-                    {
-                        "type": "boolean",
-                        "description": "Return all entry IDs (no pagination)",
-                        "name": "all_ids",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "description": "Results per page (default 25)",
-                        "name": "page_size",
-                        "in": "query"
+                        "description": "Imaging attempt ID",
+                        "name": "attempt_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.EntryResultSet"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -1115,10 +1243,7 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -1130,25 +1255,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/repositories/{id}/summary": {
-            "get": {
+        "/entries/{id}/imaging_log": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns media type totals and per-type summaries for a given repository.",
+                "description": "Parses an FTK Imager .txt report or a KryoFlux DiskTool Console log and returns the values it proposes for the entry's imaging_software, image_format, image_filename, imaging_success and interface, mapped to vocabulary keys, with the hashes and image size added to imaging_note. Nothing is saved, review the values and send them to /entries/{id}/update. The log may be sent as the request body or as a multipart file named \"log\".",
+                "consumes": [
+                    "text/plain",
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "repositories"
+                    "entries"
                 ],
-                "summary": "Get repository summary",
+                "summary": "Read an imaging log",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Repository ID",
+                        "type": "string",
+                        "description": "Entry UUID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1158,7 +1287,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.SummaryTotalsRepo"
+                            "$ref": "#/definitions/controllers.ImagingLogProposal"
                         }
                     },
                     "400": {
@@ -1170,10 +1299,7 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -1185,38 +1311,54 @@ const docTemplate = `{
                 }
             }
         },
-        "/resources": {
-            "get": {
+        "/entries/{id}/move": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns a list of all resources.",
+                "description": "Re-files an entry into another accession, updating its repository, resource and accession. The entry keeps its media ID if it is free in the target resource, otherwise it gets the next media ID there. The move is recorded and returned.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "resources"
+                    "entries"
+                ],
+                "summary": "Move entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Entry UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Target accession ID",
+                        "name": "accession_id",
+                        "in": "query",
+                        "required": true
+                    }
                 ],
-                "summary": "List resources",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Resource"
-                            }
+                            "$ref": "#/definitions/models.EntryMove"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -1226,14 +1368,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/entries/{id}/update": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Creates a new resource (collection) within a repository.",
+                "description": "Replaces the editable fields of an entry by UUID. An id in the body must match the one in the path. The media id, repository, resource, accession and creation fields are left unchanged, use the move endpoints to reparent an entry.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1241,17 +1385,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "resources"
+                    "entries"
                 ],
-                "summary": "Create resource",
+                "summary": "Update entry",
                 "parameters": [
                     {
-                        "description": "Resource data",
-                        "name": "resource",
+                        "type": "string",
+                        "description": "Entry UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated entry data",
+                        "name": "entry",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Resource"
+                            "$ref": "#/definitions/models.Entry"
                         }
                     }
                 ],
@@ -1259,7 +1410,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Resource"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -1271,10 +1422,19 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -1286,35 +1446,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/resources/{id}": {
-            "get": {
+        "/entries/{id}/update_location": {
+            "patch": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns a single resource by its ID.",
+                "description": "Updates the storage location for a given entry by UUID.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "resources"
+                    "entries"
                 ],
-                "summary": "Get resource",
+                "summary": "Update entry location",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Resource ID",
+                        "type": "string",
+                        "description": "Entry UUID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.Resource"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Storage location code",
+                        "name": "location",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -1326,10 +1493,7 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -1339,30 +1503,23 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/logout": {
             "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes a resource by its ID.",
+                "description": "Invalidates the current API token. Long-lived API keys can not be logged out, they are revoked from the user's page.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "resources"
-                ],
-                "summary": "Delete resource",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Resource ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "auth"
                 ],
+                "summary": "Logout",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -1373,7 +1530,10 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "401": {
@@ -1394,45 +1554,86 @@ const docTemplate = `{
                 }
             }
         },
-        "/resources/{id}/entries": {
+        "/reports/fixity": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns paginated entries for a given resource. Use all_ids=true to return only entry UUIDs.",
+                "description": "Returns every registered image file that was missing or changed at its last fixity check, with the collection code and media ID of its entry, for the repositories the user can view.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "resources"
+                    "reports"
                 ],
-                "summary": "Get resource entries",
+                "summary": "Fixity report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/database.ImageFileProblem"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/range": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns total counts and sizes of media ingested within a date range. Dates must be in YYYYMMDD format. A summary of all repositories needs the viewer role in every repository.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Date range summary",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Resource ID",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Start date (YYYYMMDD)",
+                        "name": "start_date",
+                        "in": "query",
                         "required": true
                     },
                     {
-                        "type": "boolean",
-                        "description": "Return all entry IDs (no pagination)",
-                        "name": "all_ids",
-                        "in": "query"
+                        "type": "string",
+                        "description": "End date (YYYYMMDD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
+                        "description": "Filter by repository ID (0 = all)",
+                        "name": "repository_id",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Results per page (default 25)",
-                        "name": "page_size",
+                        "type": "boolean",
+                        "description": "Filter by refreshed status",
+                        "name": "is_refreshed",
                         "in": "query"
                     }
                 ],
@@ -1440,7 +1641,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.EntryResultSet"
+                            "$ref": "#/definitions/api.SummaryAndTotals"
                         }
                     },
                     "400": {
@@ -1449,6 +1650,83 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/staging": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the outcome of the last staging scan since the server started: the files matched to entries, the fields filled in, and the images that matched no entry.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Staging report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.StagingReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/repositories": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a list of the repositories the user holds a role in, or of all repositories for a global role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "repositories"
+                ],
+                "summary": "List repositories",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Repository"
+                            }
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -1465,37 +1743,40 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/resources/{id}/summary": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns media type totals and per-type summaries for a given resource.",
+                "description": "Creates a new repository record.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "resources"
+                    "repositories"
                 ],
-                "summary": "Get resource summary",
+                "summary": "Create repository",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Resource ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Repository data",
+                        "name": "repository",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Repository"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.SummaryTotalsResource"
+                            "$ref": "#/definitions/models.Repository"
                         }
                     },
                     "400": {
@@ -1522,49 +1803,50 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{user}/login": {
-            "post": {
-                "description": "Authenticates a user by email and password, returning a session token valid for 3 hours.",
+        "/repositories/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a single repository by its ID.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "repositories"
                 ],
-                "summary": "Login",
+                "summary": "Get repository",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User email address",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "Repository ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "User password",
-                        "name": "password",
-                        "in": "query",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Token"
+                            "$ref": "#/definitions/models.Repository"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/api.APIError"
+                            "type": "string"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
@@ -1574,14 +1856,1154 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "api.APIError": {
-            "type": "object",
-            "properties": {
-                "error": {
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Moves a repository to the trash by its ID. The delete is refused with 409 and a summary of the dependents while the repository still has resources, accessions or entries, unless an admin sets cascade=true to move them to the trash too.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "repositories"
+                ],
+                "summary": "Delete repository",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Repository ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also delete the repository's resources, accessions and entries (admin only)",
+                        "name": "cascade",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.DeleteConflict"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/repositories/{id}/entries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns paginated entries for a given repository. Use all_ids=true to return only entry UUIDs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "repositories"
+                ],
+                "summary": "Get repository entries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Repository ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return all entry IDs (no pagination)",
+                        "name": "all_ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Results per page (default 25)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.EntryResultSet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/repositories/{id}/summary": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns media type totals and per-type summaries for a given repository.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "repositories"
+                ],
+                "summary": "Get repository summary",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Repository ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.SummaryTotalsRepo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/resources": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a list of the resources in repositories the user can view.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resources"
+                ],
+                "summary": "List resources",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Resource"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a new resource (collection) within a repository.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resources"
+                ],
+                "summary": "Create resource",
+                "parameters": [
+                    {
+                        "description": "Resource data",
+                        "name": "resource",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Resource"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Resource"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/resources/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a single resource by its ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resources"
+                ],
+                "summary": "Get resource",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Resource ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Resource"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Moves a resource to the trash by its ID. The delete is refused with 409 and a summary of the dependents while the resource still has accessions or entries, unless an admin sets cascade=true to move them to the trash too.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resources"
+                ],
+                "summary": "Delete resource",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Resource ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also delete the resource's accessions and entries (admin only)",
+                        "name": "cascade",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.DeleteConflict"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/resources/{id}/entries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns paginated entries for a given resource. Use all_ids=true to return only entry UUIDs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resources"
+                ],
+                "summary": "Get resource entries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Resource ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return all entry IDs (no pagination)",
+                        "name": "all_ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Results per page (default 25)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.EntryResultSet"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/resources/{id}/summary": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns media type totals and per-type summaries for a given resource, and the file formats totalled from the format profiles of its entries.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "resources"
+                ],
+                "summary": "Get resource summary",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Resource ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.SummaryTotalsResource"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/search/entries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Searches entries and returns one page of results with facet counts per mediatype and status for the whole result set. The query matches free text anywhere in an entry and accepts field-qualified terms such as label_text:letters, box_number:3 or manufacturer:\"Sony\"; quote a value to search for a phrase. Dates use YYYY-MM-DD and pages start at 0. Only entries in repositories the user can view are searched.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "entries"
+                ],
+                "summary": "Search entries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Repository ID",
+                        "name": "repository_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resource ID",
+                        "name": "resource_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Accession ID",
+                        "name": "accession_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Mediatype key",
+                        "name": "mediatype",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entry status key",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Storage location key",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created on or after",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created on or before",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Updated on or after",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Updated on or before",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "updated_at desc, updated_at, created_at desc, created_at, media_id or media_id desc; defaults to relevance when the query has free text, otherwise updated_at desc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 0",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Results per page (default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/database.EntrySearchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/staging/scan": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Matches the files in every configured staging directory to entries by image filename or by collection code and media ID, fills in the entries' image filename, image format and location, and returns the report.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Scan staging directories",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.StagingReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the deleted repositories, resources, accessions and entries. Objects deleted together with their parent are listed under the parent only. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "List trash",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/database.Trash"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/trash/{type}/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Permanently deletes a deleted object with everything below it. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Purge from trash",
+                "parameters": [
+                    {
+                        "enum": [
+                            "repositories",
+                            "resources",
+                            "accessions",
+                            "entries"
+                        ],
+                        "type": "string",
+                        "description": "Object type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object ID, a UUID for entries",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/trash/{type}/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Restores a deleted object along with everything that was deleted with it. Objects inside a deleted parent can not be restored on their own. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Restore from trash",
+                "parameters": [
+                    {
+                        "enum": [
+                            "repositories",
+                            "resources",
+                            "accessions",
+                            "entries"
+                        ],
+                        "type": "string",
+                        "description": "Object type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object ID, a UUID for entries",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/login": {
+            "post": {
+                "description": "Authenticates a user by email and password, returning a session token valid for 3 hours. Repeated failures lock the account or the client address for a while.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User email address",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User password",
+                        "name": "password",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Token"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.APIError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/vocabularies": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the terms of every controlled vocabulary keyed by vocabulary name. Retired terms are omitted unless include_retired=true.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vocabularies"
+                ],
+                "summary": "List vocabularies",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include retired terms",
+                        "name": "include_retired",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.VocabularyTerm"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/vocabularies/{vocabulary}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the terms of a single controlled vocabulary, e.g. mediatypes or storage_locations. Retired terms are omitted unless include_retired=true.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "vocabularies"
+                ],
+                "summary": "Get vocabulary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Vocabulary name",
+                        "name": "vocabulary",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include retired terms",
+                        "name": "include_retired",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.VocabularyTerm"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.APIError": {
+            "type": "object",
+            "properties": {
+                "error": {
                     "type": "object",
                     "additionalProperties": {
                         "type": "array",
@@ -1592,103 +3014,464 @@ const docTemplate = `{
                 }
             }
         },
-        "api.EntryResultSet": {
+        "api.DeleteConflict": {
+            "type": "object",
+            "properties": {
+                "dependents": {
+                    "$ref": "#/definitions/database.DeleteSummary"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.EntryImageFiles": {
+            "type": "object",
+            "properties": {
+                "fixity_events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FixityEvent"
+                    }
+                },
+                "image_files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImageFile"
+                    }
+                }
+            }
+        },
+        "api.EntryResultSet": {
+            "type": "object",
+            "properties": {
+                "first_page": {
+                    "type": "integer"
+                },
+                "last_page": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Entry"
+                    }
+                },
+                "this_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ImageFilesRequest": {
+            "type": "object",
+            "properties": {
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.MoveRequest": {
+            "type": "object",
+            "properties": {
+                "accession_id": {
+                    "type": "integer"
+                },
+                "entry_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.SummaryAndTotals": {
+            "type": "object",
+            "properties": {
+                "repository": {
+                    "type": "string"
+                },
+                "repository_id": {
+                    "type": "integer"
+                },
+                "summaries": {
+                    "$ref": "#/definitions/database.Summaries"
+                },
+                "total_Size": {
+                    "type": "integer"
+                },
+                "total_count": {
+                    "type": "integer"
+                },
+                "total_human_size": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.SummaryTotalsAccession": {
+            "type": "object",
+            "properties": {
+                "accession_identifier": {
+                    "type": "string"
+                },
+                "summaries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/database.Summary"
+                    }
+                },
+                "totals": {
+                    "$ref": "#/definitions/database.Totals"
+                }
+            }
+        },
+        "api.SummaryTotalsRepo": {
+            "type": "object",
+            "properties": {
+                "repository": {
+                    "type": "string"
+                },
+                "summaries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/database.Summary"
+                    }
+                },
+                "totals": {
+                    "$ref": "#/definitions/database.Totals"
+                }
+            }
+        },
+        "api.SummaryTotalsResource": {
+            "type": "object",
+            "properties": {
+                "formats": {
+                    "$ref": "#/definitions/database.FormatSummary"
+                },
+                "resource_identifier": {
+                    "type": "string"
+                },
+                "resource_title": {
+                    "type": "string"
+                },
+                "summaries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/database.Summary"
+                    }
+                },
+                "totals": {
+                    "$ref": "#/definitions/database.Totals"
+                }
+            }
+        },
+        "controllers.EntryImportReport": {
+            "type": "object",
+            "properties": {
+                "accession_id": {
+                    "type": "integer"
+                },
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "committed": {
+                    "type": "boolean"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.EntryImportRow"
+                    }
+                },
+                "valid": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.EntryImportRow": {
             "type": "object",
             "properties": {
-                "first_page": {
-                    "type": "integer"
+                "entry": {
+                    "$ref": "#/definitions/models.Entry"
                 },
-                "last_page": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "row": {
                     "type": "integer"
+                }
+            }
+        },
+        "controllers.ImagingLogProposal": {
+            "type": "object",
+            "properties": {
+                "log": {
+                    "$ref": "#/definitions/imaging.Log"
                 },
-                "results": {
+                "values": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Entry"
+                        "$ref": "#/definitions/controllers.ProposedValue"
                     }
                 },
-                "this_page": {
-                    "type": "integer"
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controllers.ProposedValue": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "string"
                 },
-                "total": {
-                    "type": "integer"
+                "current_label": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "proposed": {
+                    "type": "string"
+                },
+                "proposed_label": {
+                    "type": "string"
                 }
             }
         },
-        "api.SummaryAndTotals": {
+        "controllers.StagingFile": {
             "type": "object",
             "properties": {
-                "repository": {
+                "entry_id": {
                     "type": "string"
                 },
-                "repository_id": {
-                    "type": "integer"
+                "label": {
+                    "type": "string"
                 },
-                "summaries": {
-                    "$ref": "#/definitions/database.Summaries"
+                "location": {
+                    "type": "string"
                 },
-                "total_Size": {
-                    "type": "integer"
+                "matched_by": {
+                    "type": "string"
                 },
-                "total_count": {
-                    "type": "integer"
+                "path": {
+                    "type": "string"
                 },
-                "total_human_size": {
+                "reason": {
                     "type": "string"
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "api.SummaryTotalsAccession": {
+        "controllers.StagingReport": {
             "type": "object",
             "properties": {
-                "accession_identifier": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "matched": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.StagingFile"
+                    }
+                },
+                "scanned": {
+                    "type": "integer"
+                },
+                "scanned_at": {
                     "type": "string"
                 },
-                "summaries": {
+                "unmatched": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/database.Summary"
+                        "$ref": "#/definitions/controllers.StagingFile"
                     }
+                }
+            }
+        },
+        "database.DeleteSummary": {
+            "type": "object",
+            "properties": {
+                "accessions": {
+                    "type": "integer"
                 },
-                "totals": {
-                    "$ref": "#/definitions/database.Totals"
+                "entries": {
+                    "type": "integer"
+                },
+                "resources": {
+                    "type": "integer"
                 }
             }
         },
-        "api.SummaryTotalsRepo": {
+        "database.EntrySearchResult": {
             "type": "object",
             "properties": {
-                "repository": {
-                    "type": "string"
+                "facets": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/definitions/database.FacetCount"
+                        }
+                    }
                 },
-                "summaries": {
+                "pagination": {
+                    "$ref": "#/definitions/database.Pagination"
+                },
+                "results": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/database.Summary"
+                        "$ref": "#/definitions/models.Entry"
                     }
-                },
-                "totals": {
-                    "$ref": "#/definitions/database.Totals"
                 }
             }
         },
-        "api.SummaryTotalsResource": {
+        "database.FacetCount": {
             "type": "object",
             "properties": {
-                "resource_identifier": {
-                    "type": "string"
+                "count": {
+                    "type": "integer"
                 },
-                "resource_title": {
+                "value": {
                     "type": "string"
+                }
+            }
+        },
+        "database.FormatSummary": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "integer"
                 },
-                "summaries": {
+                "file_count": {
+                    "type": "integer"
+                },
+                "filesystems": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "formats": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/database.Summary"
+                        "$ref": "#/definitions/database.ResourceFormatCount"
                     }
                 },
-                "totals": {
-                    "$ref": "#/definitions/database.Totals"
+                "total_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "database.ImageFileProblem": {
+            "type": "object",
+            "properties": {
+                "collection_code": {
+                    "type": "string"
+                },
+                "entry_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_checked_at": {
+                    "type": "string"
+                },
+                "md5": {
+                    "type": "string"
+                },
+                "media_id": {
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "registered_at": {
+                    "type": "string"
+                },
+                "registered_by": {
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "database.Pagination": {
+            "type": "object",
+            "properties": {
+                "filter": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "sort": {
+                    "type": "string"
+                },
+                "total_pages": {
+                    "type": "integer"
+                },
+                "total_records": {
+                    "type": "integer"
+                }
+            }
+        },
+        "database.ResourceFormatCount": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "integer"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "puid": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
                 }
             }
         },
@@ -1704,30 +3487,107 @@ const docTemplate = `{
                 "count": {
                     "type": "integer"
                 },
-                "humanSize": {
+                "humanSize": {
+                    "type": "string"
+                },
+                "mediatype": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "number",
+                    "format": "float64"
+                }
+            }
+        },
+        "database.Totals": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "humanSize": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "number",
+                    "format": "float64"
+                }
+            }
+        },
+        "database.Trash": {
+            "type": "object",
+            "properties": {
+                "accessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Accession"
+                    }
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Entry"
+                    }
+                },
+                "repositories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Repository"
+                    }
+                },
+                "resources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Resource"
+                    }
+                }
+            }
+        },
+        "imaging.Log": {
+            "type": "object",
+            "properties": {
+                "bad_tracks": {
+                    "type": "integer"
+                },
+                "drive_model": {
+                    "type": "string"
+                },
+                "good_tracks": {
+                    "type": "integer"
+                },
+                "image_filename": {
+                    "type": "string"
+                },
+                "image_size": {
+                    "type": "integer"
+                },
+                "md5": {
                     "type": "string"
                 },
-                "mediatype": {
+                "segments": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sha1": {
                     "type": "string"
                 },
-                "size": {
-                    "type": "number",
-                    "format": "float64"
-                }
-            }
-        },
-        "database.Totals": {
-            "type": "object",
-            "properties": {
-                "count": {
-                    "type": "integer"
+                "sha256": {
+                    "type": "string"
                 },
-                "humanSize": {
+                "software": {
                     "type": "string"
                 },
-                "size": {
-                    "type": "number",
-                    "format": "float64"
+                "source_type": {
+                    "type": "string"
+                },
+                "verified": {
+                    "description": "nil when the log has no verification result",
+                    "type": "boolean"
+                },
+                "version": {
+                    "type": "string"
                 }
             }
         },
@@ -1751,6 +3611,10 @@ const docTemplate = `{
                 "created_by": {
                     "type": "integer"
                 },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -1790,9 +3654,22 @@ const docTemplate = `{
                     "description": "this should be converted to a uint",
                     "type": "integer"
                 },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
                 "disposition_note": {
                     "type": "string"
                 },
+                "encoding_scheme": {
+                    "type": "string"
+                },
+                "filesystems": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "hdd_interface": {
                     "type": "string"
                 },
@@ -1886,6 +3763,234 @@ const docTemplate = `{
                 }
             }
         },
+        "models.EntryMove": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "entry_id": {
+                    "type": "string"
+                },
+                "from_accession_id": {
+                    "type": "integer"
+                },
+                "from_media_id": {
+                    "type": "integer"
+                },
+                "from_repository_id": {
+                    "type": "integer"
+                },
+                "from_resource_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "to_accession_id": {
+                    "type": "integer"
+                },
+                "to_media_id": {
+                    "type": "integer"
+                },
+                "to_repository_id": {
+                    "type": "integer"
+                },
+                "to_resource_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.EntryRevision": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.FieldChange"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "entry_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "from": {},
+                "to": {}
+            }
+        },
+        "models.FixityEvent": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "entry_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "image_file_id": {
+                    "type": "integer"
+                },
+                "outcome": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FormatCount": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "puid": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FormatProfile": {
+            "type": "object",
+            "properties": {
+                "entry_id": {
+                    "type": "string"
+                },
+                "file_count": {
+                    "type": "integer"
+                },
+                "filesystems": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "formats": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FormatCount"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reports": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "total_bytes": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImageFile": {
+            "type": "object",
+            "properties": {
+                "entry_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_checked_at": {
+                    "type": "string"
+                },
+                "md5": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "registered_at": {
+                    "type": "string"
+                },
+                "registered_by": {
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImagingAttempt": {
+            "type": "object",
+            "properties": {
+                "attempted_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "encoding_scheme": {
+                    "type": "string"
+                },
+                "entry_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "imaged_by": {
+                    "type": "string"
+                },
+                "imaging_software": {
+                    "type": "string"
+                },
+                "imaging_success": {
+                    "type": "string"
+                },
+                "interface": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
         "models.MedialogInfo": {
             "type": "object",
             "properties": {
@@ -1912,6 +4017,10 @@ const docTemplate = `{
                 "created_by": {
                     "type": "integer"
                 },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -1941,6 +4050,10 @@ const docTemplate = `{
                 "created_by": {
                     "type": "integer"
                 },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -1967,12 +4080,18 @@ const docTemplate = `{
         "models.Token": {
             "type": "object",
             "properties": {
+                "created_at": {
+                    "type": "string"
+                },
                 "expires": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
+                "ip_address": {
+                    "type": "string"
+                },
                 "is_valid": {
                     "type": "boolean"
                 },
@@ -2011,6 +4130,10 @@ const docTemplate = `{
                 "encrypted_password": {
                     "type": "string"
                 },
+                "failed_logins": {
+                    "description": "wrong passwords since the last successful login or unlock",
+                    "type": "integer"
+                },
                 "first_name": {
                     "type": "string"
                 },
@@ -2026,6 +4149,9 @@ const docTemplate = `{
                 "last_name": {
                     "type": "string"
                 },
+                "locked_until": {
+                    "type": "string"
+                },
                 "previous_ip_address": {
                     "type": "string"
                 },
@@ -2035,11 +4161,46 @@ const docTemplate = `{
                 "sign_in_count": {
                     "type": "integer"
                 },
+                "totp_enabled": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.VocabularyTerm": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_retired": {
+                    "type": "boolean"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
                 },
                 "updated_by": {
                     "type": "integer"
+                },
+                "vocabulary": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,118 @@
+package fixity
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checksums are the size and hashes of a file
+type Checksums struct {
+	Size   int64
+	MD5    string
+	SHA256 string
+}
+
+// Matches reports whether two sets of checksums describe the same content
+func (c Checksums) Matches(other Checksums) bool {
+	return c.Size == other.Size && c.MD5 == other.MD5 && c.SHA256 == other.SHA256
+}
+
+// HashFile reads a file once and returns its size, md5 and sha256
+func HashFile(path string) (Checksums, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Checksums{}, err
+	}
+	defer f.Close()
+
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), f)
+	if err != nil {
+		return Checksums{}, err
+	}
+
+	return Checksums{
+		Size:   size,
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
+
+// ListFiles returns the absolute paths of the regular files below a directory, hidden files and directories are skipped
+func ListFiles(dir string) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return []string{}, err
+	}
+
+	files := []string{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+var roots = []string{}
+
+// SetRoots sets the directories files may be registered from over the api
+func SetRoots(dirs []string) error {
+	roots = []string{}
+	for _, dir := range dirs {
+		root, err := resolve(dir)
+		if err != nil {
+			return fmt.Errorf("image root %s: %w", dir, err)
+		}
+		roots = append(roots, root)
+	}
+	return nil
+}
+
+// Roots returns the configured image roots
+func Roots() []string { return roots }
+
+// InRoots reports whether a path is one of the image roots or below one
+func InRoots(path string) bool {
+	path, err := resolve(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve makes a path absolute and follows symlinks, so a link cannot lead out of an image root
+func resolve(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved, nil
+	}
+	return path, nil
+}
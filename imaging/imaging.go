@@ -0,0 +1,214 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	SoftwareFTKImager = "FTK Imager"
+	SoftwareKryoFlux  = "KryoFlux"
+)
+
+// Log is what could be read from an imaging log, empty fields were not found
+type Log struct {
+	Software      string   `json:"software"`
+	Version       string   `json:"version"`
+	ImageFilename string   `json:"image_filename"`
+	Segments      []string `json:"segments"`
+	DriveModel    string   `json:"drive_model"`
+	SourceType    string   `json:"source_type"`
+	ImageSize     int64    `json:"image_size"`
+	MD5           string   `json:"md5"`
+	SHA1          string   `json:"sha1"`
+	SHA256        string   `json:"sha256"`
+	Verified      *bool    `json:"verified"` //nil when the log has no verification result
+	GoodTracks    int      `json:"good_tracks"`
+	BadTracks     int      `json:"bad_tracks"`
+}
+
+// Parse detects the kind of imaging log and parses it
+func Parse(data []byte) (Log, error) {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	switch {
+	case bytes.Contains(head, []byte("FTK")) && bytes.Contains(head, []byte("Imager")):
+		return ParseFTKImager(bytes.NewReader(data))
+	case bytes.Contains(head, []byte("KryoFlux")) || bytes.Contains(head, []byte("DiskTool")):
+		return ParseKryoFlux(bytes.NewReader(data))
+	default:
+		return Log{}, fmt.Errorf("the file is not an FTK Imager report or a KryoFlux log")
+	}
+}
+
+var (
+	ftkVersion      = regexp.MustCompile(`FTK\S*\s+Imager\s+v?(\d+(?:\.\d+)+)`)
+	ftkAcquiredWith = regexp.MustCompile(`Acquired using:\s*ADI\s*(\d+(?:\.\d+)+)`)
+	ftkField        = regexp.MustCompile(`^\s*([A-Za-z0-9 ]+?)\s*:\s*(.*?)\s*$`)
+	hashValue       = regexp.MustCompile(`^([0-9a-fA-F]{32,64})\b`)
+)
+
+// ParseFTKImager reads the .txt report FTK Imager writes next to an image
+func ParseFTKImager(r io.Reader) (Log, error) {
+	log := Log{Software: SoftwareFTKImager, Segments: []string{}}
+
+	var sectorCount, bytesPerSector int64
+	section := ""
+	inSegments := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if log.Version == "" {
+			if m := ftkVersion.FindStringSubmatch(line); m != nil {
+				log.Version = m[1]
+			} else if m := ftkAcquiredWith.FindStringSubmatch(line); m != nil {
+				log.Version = m[1]
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "[Computed Hashes]"):
+			section = "computed"
+			continue
+		case strings.HasPrefix(trimmed, "Image Verification Results"):
+			section = "verification"
+			continue
+		case strings.HasPrefix(trimmed, "Image Information"):
+			section = "image"
+			continue
+		}
+
+		if inSegments {
+			if trimmed != "" && !strings.Contains(trimmed, ": ") && !strings.HasSuffix(trimmed, ":") {
+				log.Segments = append(log.Segments, trimmed)
+				continue
+			}
+			inSegments = false
+		}
+		if strings.HasPrefix(trimmed, "Segment list:") {
+			inSegments = true
+			continue
+		}
+
+		m := ftkField.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key, value := strings.ToLower(m[1]), m[2]
+
+		switch key {
+		case "source type":
+			log.SourceType = value
+		case "drive model":
+			log.DriveModel = value
+		case "sector count":
+			if n, err := strconv.ParseInt(strings.ReplaceAll(value, ",", ""), 10, 64); err == nil && sectorCount == 0 {
+				sectorCount = n
+			}
+		case "bytes per sector":
+			if n, err := strconv.ParseInt(strings.ReplaceAll(value, ",", ""), 10, 64); err == nil {
+				bytesPerSector = n
+			}
+		case "md5 checksum", "sha1 checksum", "sha256 checksum":
+			hash := hashValue.FindString(value)
+			if hash == "" {
+				continue
+			}
+			switch {
+			case key == "md5 checksum" && log.MD5 == "":
+				log.MD5 = strings.ToLower(hash)
+			case key == "sha1 checksum" && log.SHA1 == "":
+				log.SHA1 = strings.ToLower(hash)
+			case key == "sha256 checksum" && log.SHA256 == "":
+				log.SHA256 = strings.ToLower(hash)
+			}
+			if section == "verification" {
+				result := strings.ToLower(value)
+				verified := strings.Contains(result, "verified") && !strings.Contains(result, "not")
+				if log.Verified == nil || !verified {
+					log.Verified = &verified
+				}
+			}
+		case "verify result":
+			verified := strings.Contains(strings.ToLower(value), "match") && !strings.Contains(strings.ToLower(value), "not")
+			log.Verified = &verified
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return log, err
+	}
+
+	if sectorCount > 0 && bytesPerSector > 0 {
+		log.ImageSize = sectorCount * bytesPerSector
+	}
+	if len(log.Segments) > 0 {
+		log.ImageFilename = baseName(log.Segments[0])
+	}
+	if log.Version == "" && log.MD5 == "" && len(log.Segments) == 0 {
+		return log, fmt.Errorf("no imaging details found in the FTK Imager report")
+	}
+	return log, nil
+}
+
+var (
+	kryofluxVersion  = regexp.MustCompile(`DiskTool Console,?\s+v?(\d+(?:\.\d+)+)`)
+	kryofluxTrack    = regexp.MustCompile(`^\s*\d+\.\d+\s*:`)
+	kryofluxFilename = regexp.MustCompile(`(?:^|\s)-f\s*"?([^"\s]+)"?`)
+)
+
+// ParseKryoFlux reads the console log of a KryoFlux DiskTool Console (dtc) run
+func ParseKryoFlux(r io.Reader) (Log, error) {
+	log := Log{Software: SoftwareKryoFlux, Segments: []string{}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if log.Version == "" {
+			if m := kryofluxVersion.FindStringSubmatch(line); m != nil {
+				log.Version = m[1]
+			}
+		}
+
+		if m := kryofluxFilename.FindStringSubmatch(line); m != nil && log.ImageFilename == "" {
+			log.ImageFilename = baseName(m[1])
+			log.Segments = append(log.Segments, m[1])
+		}
+
+		if kryofluxTrack.MatchString(line) {
+			if strings.Contains(line, ": OK") {
+				log.GoodTracks++
+			} else {
+				log.BadTracks++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return log, err
+	}
+
+	if log.GoodTracks+log.BadTracks > 0 {
+		verified := log.BadTracks == 0
+		log.Verified = &verified
+	}
+	if log.Version == "" && log.GoodTracks+log.BadTracks == 0 {
+		return log, fmt.Errorf("no imaging details found in the KryoFlux log")
+	}
+	return log, nil
+}
+
+// baseName returns the file name of a windows or unix path
+func baseName(p string) string {
+	return path.Base(strings.ReplaceAll(p, `\`, "/"))
+}
@@ -0,0 +1,297 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ReportDFXML     = "dfxml"
+	ReportSiegfried = "siegfried"
+	ReportDROID     = "droid"
+)
+
+// unidentified is the format name used for files no format was identified for
+const unidentified = "Unidentified"
+
+// FormatCount is the number and size of the files identified as one format
+type FormatCount struct {
+	PUID    string `json:"puid"`
+	Format  string `json:"format"`
+	Version string `json:"version"`
+	Count   int    `json:"count"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// FormatReport summarizes the files listed in a DFXML, Siegfried or DROID report
+type FormatReport struct {
+	Kind        string        `json:"kind"`
+	FileCount   int           `json:"file_count"`
+	TotalBytes  int64         `json:"total_bytes"`
+	Filesystems []string      `json:"filesystems"`
+	Formats     []FormatCount `json:"formats"` //empty for DFXML, which identifies no formats
+}
+
+// formatTally accumulates files into a FormatReport
+type formatTally struct {
+	report  FormatReport
+	formats map[string]*FormatCount
+}
+
+func newFormatTally(kind string) *formatTally {
+	return &formatTally{
+		report:  FormatReport{Kind: kind, Filesystems: []string{}, Formats: []FormatCount{}},
+		formats: map[string]*FormatCount{},
+	}
+}
+
+func (t *formatTally) addFile(size int64, puid string, format string, version string) {
+	t.report.FileCount++
+	t.report.TotalBytes += size
+
+	if t.report.Kind == ReportDFXML {
+		return
+	}
+	if puid == "" || strings.EqualFold(puid, "UNKNOWN") {
+		puid, format, version = "", unidentified, ""
+	}
+	key := puid + "\x00" + format + "\x00" + version
+	count, ok := t.formats[key]
+	if !ok {
+		count = &FormatCount{PUID: puid, Format: format, Version: version}
+		t.formats[key] = count
+	}
+	count.Count++
+	count.Bytes += size
+}
+
+func (t *formatTally) addFilesystem(filesystem string) {
+	filesystem = strings.TrimSpace(filesystem)
+	if filesystem == "" {
+		return
+	}
+	for _, existing := range t.report.Filesystems {
+		if existing == filesystem {
+			return
+		}
+	}
+	t.report.Filesystems = append(t.report.Filesystems, filesystem)
+}
+
+// finish returns the report with formats ordered by file count, most common first
+func (t *formatTally) finish() (FormatReport, error) {
+	for _, count := range t.formats {
+		t.report.Formats = append(t.report.Formats, *count)
+	}
+	sort.Slice(t.report.Formats, func(i, j int) bool {
+		a, b := t.report.Formats[i], t.report.Formats[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.PUID+a.Format < b.PUID+b.Format
+	})
+	if t.report.FileCount == 0 && len(t.report.Filesystems) == 0 {
+		return t.report, fmt.Errorf("no files found in the %s report", t.report.Kind)
+	}
+	return t.report, nil
+}
+
+// ParseFormatReport detects the kind of report, a DFXML file, a Siegfried json or csv, or a DROID csv, and parses it
+func ParseFormatReport(data []byte) (FormatReport, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return ParseDFXML(bytes.NewReader(trimmed))
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return ParseSiegfriedJSON(bytes.NewReader(trimmed))
+	case len(trimmed) > 0:
+		return ParseFormatCSV(bytes.NewReader(trimmed))
+	default:
+		return FormatReport{}, fmt.Errorf("the report is empty")
+	}
+}
+
+type dfxmlFileObject struct {
+	Filename string `xml:"filename"`
+	Filesize int64  `xml:"filesize"`
+	NameType string `xml:"name_type"`
+	MetaType int    `xml:"meta_type"`
+	Alloc    string `xml:"alloc"`
+	Unalloc  string `xml:"unalloc"`
+}
+
+// isAllocatedFile reports whether a fileobject is a regular file that has not been deleted
+func (f dfxmlFileObject) isAllocatedFile() bool {
+	if f.NameType != "" && f.NameType != "r" {
+		return false
+	}
+	if f.NameType == "" && f.MetaType != 1 {
+		return false
+	}
+	return f.Unalloc != "1" && f.Alloc != "0"
+}
+
+// ParseDFXML reads the DFXML fiwalk writes for a disk image, counting the allocated regular files of every volume
+func ParseDFXML(r io.Reader) (FormatReport, error) {
+	tally := newFormatTally(ReportDFXML)
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	sawDFXML := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return tally.report, fmt.Errorf("invalid DFXML: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "dfxml":
+			sawDFXML = true
+		case "ftype_str":
+			var filesystem string
+			if err := decoder.DecodeElement(&filesystem, &start); err != nil {
+				return tally.report, fmt.Errorf("invalid DFXML: %w", err)
+			}
+			tally.addFilesystem(filesystem)
+		case "fileobject":
+			fileObject := dfxmlFileObject{}
+			if err := decoder.DecodeElement(&fileObject, &start); err != nil {
+				return tally.report, fmt.Errorf("invalid DFXML: %w", err)
+			}
+			if fileObject.isAllocatedFile() {
+				tally.addFile(fileObject.Filesize, "", "", "")
+			}
+		}
+	}
+
+	if !sawDFXML {
+		return tally.report, fmt.Errorf("the xml is not a DFXML file")
+	}
+	return tally.finish()
+}
+
+type siegfriedReport struct {
+	Siegfried string `json:"siegfried"`
+	Files     []struct {
+		Filesize int64 `json:"filesize"`
+		Matches  []struct {
+			Namespace string `json:"ns"`
+			ID        string `json:"id"`
+			Format    string `json:"format"`
+			Version   string `json:"version"`
+		} `json:"matches"`
+	} `json:"files"`
+}
+
+// ParseSiegfriedJSON reads the output of sf -json, using each file's PRONOM match
+func ParseSiegfriedJSON(r io.Reader) (FormatReport, error) {
+	tally := newFormatTally(ReportSiegfried)
+
+	report := siegfriedReport{}
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return tally.report, fmt.Errorf("invalid Siegfried json: %w", err)
+	}
+	if report.Siegfried == "" {
+		return tally.report, fmt.Errorf("the json is not a Siegfried report")
+	}
+
+	for _, file := range report.Files {
+		puid, format, version := "", "", ""
+		for i, match := range file.Matches {
+			if i == 0 || strings.EqualFold(match.Namespace, "pronom") {
+				puid, format, version = match.ID, match.Format, match.Version
+			}
+			if strings.EqualFold(match.Namespace, "pronom") {
+				break
+			}
+		}
+		tally.addFile(file.Filesize, puid, format, version)
+	}
+	return tally.finish()
+}
+
+// ParseFormatCSV reads the csv output of sf -csv or a DROID export, told apart by their headers
+func ParseFormatCSV(r io.Reader) (FormatReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return FormatReport{}, fmt.Errorf("invalid csv: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i //siegfried repeats the match columns for every identifier, the first is used
+		}
+	}
+
+	col := func(name string) int {
+		if i, ok := columns[name]; ok {
+			return i
+		}
+		return -1
+	}
+
+	var tally *formatTally
+	var sizeCol, puidCol, formatCol, versionCol, typeCol int
+	switch {
+	case has(columns, "puid", "size", "format_name"):
+		tally = newFormatTally(ReportDROID)
+		sizeCol, puidCol, formatCol, versionCol, typeCol = col("size"), col("puid"), col("format_name"), col("format_version"), col("type")
+	case has(columns, "filesize", "id", "format"):
+		tally = newFormatTally(ReportSiegfried)
+		sizeCol, puidCol, formatCol, versionCol, typeCol = col("filesize"), col("id"), col("format"), col("version"), -1
+	default:
+		return FormatReport{}, fmt.Errorf("the csv is not a Siegfried or DROID report")
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return tally.report, fmt.Errorf("invalid csv: %w", err)
+		}
+
+		//DROID lists folders, and the files inside containers again, only files and containers are counted
+		if kind := field(record, typeCol); typeCol >= 0 && !strings.EqualFold(kind, "File") && !strings.EqualFold(kind, "Container") {
+			continue
+		}
+		size, _ := strconv.ParseInt(field(record, sizeCol), 10, 64)
+		tally.addFile(size, field(record, puidCol), field(record, formatCol), field(record, versionCol))
+	}
+	return tally.finish()
+}
+
+func has(columns map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
@@ -10,12 +10,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/nyudlts/go-medialog/controllers"
 	"github.com/nyudlts/go-medialog/database"
 	"github.com/nyudlts/go-medialog/models"
@@ -33,7 +41,14 @@ var (
 	rollback      bool
 	automigrate   bool
 	createAdmin   bool
-	createJSON    bool
+	verifyJSON    bool
+	rebuildSearch bool
+	purgeTrash    bool
+	reportDupes   bool
+	checkFixity   bool
+	scanStaging   bool
+	registerDir   string
+	registerEntry string
 )
 
 func init() {
@@ -46,12 +61,22 @@ func init() {
 	flag.BoolVar(&automigrate, "automigrate", false, "")
 	flag.BoolVar(&rollback, "rollback", false, "")
 	flag.BoolVar(&createAdmin, "create-admin", false, "")
-	flag.BoolVar(&createJSON, "create-json", false, "")
+	flag.BoolVar(&verifyJSON, "verify-json", false, "")
+	flag.BoolVar(&rebuildSearch, "rebuild-search", false, "")
+	flag.BoolVar(&purgeTrash, "purge-trash", false, "")
+	flag.BoolVar(&reportDupes, "report-duplicates", false, "")
+	flag.BoolVar(&checkFixity, "check-fixity", false, "")
+	flag.BoolVar(&scanStaging, "scan-staging", false, "")
+	flag.StringVar(&registerDir, "register-images", "", "")
+	flag.StringVar(&registerEntry, "entry", "", "")
 }
 
 var r *gin.Engine
 var env models.Environment
 
+// time allowed for in-flight requests to finish after a shutdown signal
+const shutdownTimeout = 25 * time.Second
+
 func main() {
 	//parse cli flags
 	flag.Parse()
@@ -113,18 +138,233 @@ func main() {
 		os.Exit(0)
 	}
 
-	if createJSON {
-		if err := database.CreateJSON(); err != nil {
+	if rebuildSearch {
+		drift, err := database.RebuildSearchIndex()
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(drift.String())
+		fmt.Println("search index rebuilt")
+		os.Exit(0)
+	}
+
+	if verifyJSON {
+		drift, err := database.VerifyJSON(true)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(drift.String())
+		for _, id := range drift.Missing {
+			fmt.Printf(" missing json for entry %s\n", id)
+		}
+		for _, id := range drift.Stale {
+			fmt.Printf(" stale json for entry %s\n", id)
+		}
+		for _, id := range drift.Duplicates {
+			fmt.Printf(" duplicate json row %d\n", id)
+		}
+		for _, id := range drift.Orphaned {
+			fmt.Printf(" orphaned json row %d\n", id)
+		}
+		if drift.HasDrift() {
+			fmt.Println("drift repaired")
+		}
+		os.Exit(0)
+	}
+
+	if purgeTrash {
+		if env.TrashRetention < 1 {
+			fmt.Println("trash_retention_days is not set, nothing purged")
+			os.Exit(0)
+		}
+
+		purged, err := database.PurgeTrash(trashCutoff())
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("%d objects purged from the trash\n", purged)
+		os.Exit(0)
+	}
+
+	if reportDupes {
+		duplicates, err := database.FindDuplicateMediaIDs()
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("%d duplicate media ids\n", len(duplicates))
+		for _, duplicate := range duplicates {
+			fmt.Printf(" media id %d in resource %d is used by entries %s\n", duplicate.MediaID, duplicate.ResourceID, joinEntryIDs(duplicate.EntryIDs))
+		}
+		os.Exit(0)
+	}
+
+	if registerDir != "" {
+		entryID, err := uuid.Parse(registerEntry)
+		if err != nil {
+			fmt.Println("--register-images needs the uuid of an entry in --entry")
+			os.Exit(1)
+		}
+
+		imageFiles, err := controllers.RegisterImageFiles(entryID, registerDir, 0)
+		for _, imageFile := range imageFiles {
+			fmt.Printf(" registered %s %d bytes sha256 %s\n", imageFile.Path, imageFile.Size, imageFile.SHA256)
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("%d image files registered for entry %s\n", len(imageFiles), entryID)
+		os.Exit(0)
+	}
+
+	if checkFixity {
+		summary, err := controllers.CheckFixity()
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(summary.String())
+		os.Exit(0)
+	}
+
+	if scanStaging {
+		report, err := controllers.ScanStaging(0)
+		if err != nil {
 			panic(err)
 		}
+
+		for _, file := range report.Matched {
+			fmt.Printf(" %s matched %s by %s, updated %s\n", file.Path, file.Label, file.MatchedBy, strings.Join(file.Updated, ", "))
+		}
+		for _, file := range report.Unmatched {
+			fmt.Printf(" %s unmatched: %s\n", file.Path, file.Reason)
+		}
+		for _, e := range report.Errors {
+			fmt.Printf(" error: %s\n", e)
+		}
+		fmt.Println(report.String())
 		os.Exit(0)
 	}
 
 	//start the application
 	log.Printf("[INFO] Running Go-Medialog %s", version.GetAppVersion())
 
-	if err := r.Run(":8080"); err != nil {
+	if !database.HasMediaIDIndex() {
+		duplicates, err := database.FindDuplicateMediaIDs()
+		if err != nil {
+			log.Printf("[ERROR] checking for duplicate media ids: %s", err.Error())
+		}
+		log.Printf("[WARNING] media ids are not unique yet, %d duplicates found; run --report-duplicates and renumber them, then migrate", len(duplicates))
+	}
+
+	if env.TrashRetention > 0 {
+		go schedule("trash purge", 24*time.Hour, func() error {
+			purged, err := database.PurgeTrash(trashCutoff())
+			if purged > 0 {
+				log.Printf("[INFO] %d objects purged from the trash", purged)
+			}
+			return err
+		})
+	}
+
+	if env.FixityInterval > 0 {
+		go schedule("fixity check", time.Duration(env.FixityInterval)*time.Hour, func() error {
+			summary, err := controllers.CheckFixity()
+			log.Printf("[INFO] %s", summary.String())
+			return err
+		})
+	}
+
+	if env.StagingInterval > 0 && len(env.StagingRoots) > 0 {
+		go schedule("staging scan", time.Duration(env.StagingInterval)*time.Minute, func() error {
+			report, err := controllers.ScanStaging(0)
+			if len(report.Unmatched) > 0 || len(report.Errors) > 0 {
+				log.Printf("[WARNING] staging scan: %s", report.String())
+			}
+			return err
+		})
+	}
+
+	go schedule("session cleanup", env.Sessions.CleanupInterval(), func() error {
+		deleted, err := database.DeleteExpiredSessions()
+		if deleted > 0 {
+			log.Printf("[INFO] %d expired sessions deleted", deleted)
+		}
+		return err
+	})
+
+	if err := serve(); err != nil {
 		log.Fatal(err)
 	}
 
 }
+
+func serve() error {
+	srv := &http.Server{
+		Addr:    env.ListenAddress(),
+		Handler: r,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		if env.UseTLS() {
+			log.Printf("[INFO] Listening and serving HTTPS on %s", srv.Addr)
+			serverErrors <- srv.ListenAndServeTLS(env.TLSCert, env.TLSKey)
+		} else {
+			log.Printf("[INFO] Listening and serving HTTP on %s", srv.Addr)
+			serverErrors <- srv.ListenAndServe()
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErrors:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-quit:
+		log.Printf("[INFO] Received %s, draining in-flight requests", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	log.Println("[INFO] Medialog shut down")
+	return nil
+}
+
+func joinEntryIDs(ids []uuid.UUID) string {
+	s := []string{}
+	for _, id := range ids {
+		s = append(s, id.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+// trashCutoff is the time before which deleted records are purged from the trash
+func trashCutoff() time.Time {
+	return time.Now().AddDate(0, 0, -env.TrashRetention)
+}
+
+// schedule runs a maintenance job at startup and then at every interval, errors are logged and the job keeps running
+func schedule(name string, interval time.Duration, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := job(); err != nil {
+			log.Printf("[ERROR] %s: %s", name, err.Error())
+		}
+		<-ticker.C
+	}
+}
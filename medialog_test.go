@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var csrfTokenPattern = regexp.MustCompile(`name="csrf_token" value="([0-9a-f]+)"`)
+
 func TestApplication(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -29,7 +32,7 @@ func TestApplication(t *testing.T) {
 		assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("content-type"))
 	})
 
-	t.Run("test login to application", func(t *testing.T) {
+	t.Run("test login without a csrf token", func(t *testing.T) {
 		recorder := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(recorder)
 		form := url.Values{}
@@ -41,6 +44,39 @@ func TestApplication(t *testing.T) {
 		}
 		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 		r.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("test login to application", func(t *testing.T) {
+		//the login page sets the session cookie and the csrf token the form posts back
+		loginRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(loginRecorder)
+		req, err := http.NewRequestWithContext(c, "GET", "/users/login", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		r.ServeHTTP(loginRecorder, req)
+		assert.Equal(t, http.StatusOK, loginRecorder.Code)
+		token := csrfTokenPattern.FindStringSubmatch(loginRecorder.Body.String())
+		if token == nil {
+			t.Fatal("no csrf token on the login page")
+		}
+
+		recorder := httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(recorder)
+		form := url.Values{}
+		form.Set("email", env.TestCreds.Username)
+		form.Add("password_1", env.TestCreds.Password)
+		form.Add("csrf_token", token[1])
+		req, err = http.NewRequestWithContext(c, "POST", "/users/authenticate", strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Error(err)
+		}
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		for _, cookie := range loginRecorder.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		r.ServeHTTP(recorder, req)
 		assert.Equal(t, http.StatusFound, recorder.Code)
 
 		outFile, _ := os.Create("headers.txt")
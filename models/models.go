@@ -1,88 +1,100 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"reflect"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type Repository struct {
-	ID        uint      `json:"id" gorm:"primaryKey" form:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	CreatedBy int       `json:"created_by"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UpdatedBy int       `json:"updated_by"`
-	Slug      string    `json:"slug" form:"slug"`
-	Title     string    `json:"title" form:"title"`
+	ID        uint           `json:"id" gorm:"primaryKey" form:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	CreatedBy int            `json:"created_by"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	UpdatedBy int            `json:"updated_by"`
+	Slug      string         `json:"slug" form:"slug"`
+	Title     string         `json:"title" form:"title"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
 }
 
 type Resource struct {
-	ID             uint       `json:"id" gorm:"primaryKey" form:"id"`
-	Title          string     `json:"title" form:"title"`
-	CollectionCode string     `json:"collection_code" form:"collection_code"`
-	PartnerCode    string     `json:"partner_code" form:"partner_code"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
-	CreatedBy      int        `json:"created_by"`
-	UpdatedBy      int        `json:"updated_by"`
-	RepositoryID   uint       `json:"repository_id" form:"repository_id"`
-	Repository     Repository `json:"repository"`
+	ID             uint           `json:"id" gorm:"primaryKey" form:"id"`
+	Title          string         `json:"title" form:"title"`
+	CollectionCode string         `json:"collection_code" form:"collection_code"`
+	PartnerCode    string         `json:"partner_code" form:"partner_code"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	CreatedBy      int            `json:"created_by"`
+	UpdatedBy      int            `json:"updated_by"`
+	RepositoryID   uint           `json:"repository_id" form:"repository_id"`
+	Repository     Repository     `json:"repository"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
 }
 
 type Accession struct {
-	ID             uint      `json:"id" gorm:"primaryKey" form:"id"`
-	AccessionNum   string    `json:"accession_num" form:"accession_num"`
-	AccessionNote  string    `json:"accession_note"`  //deprecated
-	AccessionState string    `json:"accession_state"` //deprecated
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	CreatedBy      int       `json:"created_by"`
-	UpdatedBy      int       `json:"updated_by"`
-	ResourceID     uint      `json:"resource_id" form:"resource_id"`
-	Resource       Resource  `json:"resource"`
+	ID             uint           `json:"id" gorm:"primaryKey" form:"id"`
+	AccessionNum   string         `json:"accession_num" form:"accession_num"`
+	AccessionNote  string         `json:"accession_note"`  //deprecated
+	AccessionState string         `json:"accession_state"` //deprecated
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	CreatedBy      int            `json:"created_by"`
+	UpdatedBy      int            `json:"updated_by"`
+	ResourceID     uint           `json:"resource_id" form:"resource_id"`
+	Resource       Resource       `json:"resource"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
 }
 
 type Entry struct {
-	ID                    uuid.UUID  `json:"id" gorm:"primaryKey" form:"id"`
-	CreatedAt             time.Time  `json:"created_at"`
-	UpdatedAt             time.Time  `json:"updated_at"`
-	CreatedBy             int        `json:"created_by"` //this should be converted to a uint
-	UpdatedBy             int        `json:"updated_by"` //this should be converted to a uint
-	MediaID               uint       `json:"media_id" form:"media_id"`
-	Mediatype             string     `json:"mediatype" form:"mediatype"`
-	Manufacturer          string     `json:"manufacturer" form:"manufacturer"`
-	ManufacturerSerial    string     `json:"manufacturer_serial" form:"manufacturer_serial"`
-	LabelText             string     `json:"label_text" form:"label_text"`
-	MediaNote             string     `json:"media_note" form:"media_note"`
-	HDDInterface          string     `json:"hdd_interface" form:"hdd_interface"`
-	ImagingSuccess        string     `json:"imaging_success" form:"imaging_success"`
-	ImageFilename         string     `json:"image_filename" form:"image_filename"`
-	Interface             string     `json:"interface" form:"interface"`
-	ImagingSoftware       string     `json:"imaging_software" form:"imaging_software"`
-	InterpretationSuccess string     `json:"interpretation_success" form:"interpretation_success"`
-	ImagedBy              string     `json:"imaged_by" form:"imaged_by"`
-	ImagingNote           string     `json:"imaging_note" form:"imaging_note"`
-	ImageFormat           string     `json:"image_format" form:"image_format"`
-	BoxNumber             string     `json:"box_number" form:"box_number"`
-	OriginalID            string     `json:"original_id" form:"original_id"`
-	DispositionNote       string     `json:"disposition_note" form:"disposition_note"`
-	Status                string     `json:"status" form:"status"`
-	StockUnit             string     `json:"stock_unit" form:"stock_unit"`
-	StockSizeNum          float32    `json:"stock_size_num" form:"stock_size_num"`
-	RepositoryID          uint       `json:"repository_id" form:"repository_id"`
-	Repository            Repository `json:"repository"`
-	ResourceID            uint       `json:"resource_id" form:"resource_id"`
-	Resource              Resource   `json:"resource"`
-	AccessionID           uint       `json:"accession_id" form:"accession_id"`
-	Accession             Accession  `json:"accession"`
-	IsRefreshed           bool       `json:"is_refreshed" form:"is_refreshed"`
-	IsTransferred         bool       `json:"is_transferred"`
-	ContentType           string     `json:"content_type" form:"content_type"`
-	Structure             string     `json:"structure"`
-	Location              string     `json:"location" form:"location"`
+	ID                    uuid.UUID      `json:"id" gorm:"primaryKey" form:"id"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	CreatedBy             int            `json:"created_by"` //this should be converted to a uint
+	UpdatedBy             int            `json:"updated_by"` //this should be converted to a uint
+	MediaID               uint           `json:"media_id" form:"media_id"`
+	Mediatype             string         `json:"mediatype" form:"mediatype"`
+	Manufacturer          string         `json:"manufacturer" form:"manufacturer"`
+	ManufacturerSerial    string         `json:"manufacturer_serial" form:"manufacturer_serial"`
+	LabelText             string         `json:"label_text" form:"label_text"`
+	MediaNote             string         `json:"media_note" form:"media_note"`
+	HDDInterface          string         `json:"hdd_interface" form:"hdd_interface"`
+	ImagingSuccess        string         `json:"imaging_success" form:"imaging_success"`
+	ImageFilename         string         `json:"image_filename" form:"image_filename"`
+	Interface             string         `json:"interface" form:"interface"`
+	ImagingSoftware       string         `json:"imaging_software" form:"imaging_software"`
+	InterpretationSuccess string         `json:"interpretation_success" form:"interpretation_success"`
+	ImagedBy              string         `json:"imaged_by" form:"imaged_by"`
+	ImagingNote           string         `json:"imaging_note" form:"imaging_note"`
+	ImageFormat           string         `json:"image_format" form:"image_format"`
+	BoxNumber             string         `json:"box_number" form:"box_number"`
+	OriginalID            string         `json:"original_id" form:"original_id"`
+	DispositionNote       string         `json:"disposition_note" form:"disposition_note"`
+	Status                string         `json:"status" form:"status"`
+	StockUnit             string         `json:"stock_unit" form:"stock_unit"`
+	StockSizeNum          float32        `json:"stock_size_num" form:"stock_size_num"`
+	RepositoryID          uint           `json:"repository_id" form:"repository_id"`
+	Repository            Repository     `json:"repository"`
+	ResourceID            uint           `json:"resource_id" form:"resource_id"`
+	Resource              Resource       `json:"resource"`
+	AccessionID           uint           `json:"accession_id" form:"accession_id"`
+	Accession             Accession      `json:"accession"`
+	IsRefreshed           bool           `json:"is_refreshed" form:"is_refreshed"`
+	IsTransferred         bool           `json:"is_transferred"`
+	ContentType           string         `json:"content_type" form:"content_type"`
+	Structure             string         `json:"structure" form:"structure"`
+	Filesystems           []string       `json:"filesystems" form:"filesystems" gorm:"serializer:json;type:text"`
+	EncodingScheme        string         `json:"encoding_scheme" form:"encoding_scheme"`
+	Location              string         `json:"location" form:"location"`
+	DeletedAt             gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
 }
 
 func (e Entry) Minimal() EntryMin {
@@ -101,6 +113,155 @@ func (e Entry) Minimal() EntryMin {
 	return entryMin
 }
 
+type EntryRevision struct {
+	ID        uint                   `json:"id" gorm:"primaryKey"`
+	EntryID   uuid.UUID              `json:"entry_id" gorm:"index"`
+	CreatedAt time.Time              `json:"created_at"`
+	CreatedBy int                    `json:"created_by"`
+	Changes   map[string]FieldChange `json:"changes" gorm:"serializer:json;type:text"`
+}
+
+// EntryMove records an entry being re-filed into another accession and the media id it had before
+type EntryMove struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	EntryID          uuid.UUID `json:"entry_id" gorm:"index"`
+	FromRepositoryID uint      `json:"from_repository_id"`
+	FromResourceID   uint      `json:"from_resource_id"`
+	FromAccessionID  uint      `json:"from_accession_id"`
+	FromMediaID      uint      `json:"from_media_id"`
+	ToRepositoryID   uint      `json:"to_repository_id"`
+	ToResourceID     uint      `json:"to_resource_id"`
+	ToAccessionID    uint      `json:"to_accession_id"`
+	ToMediaID        uint      `json:"to_media_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	CreatedBy        int       `json:"created_by"`
+}
+
+// MediaIDCounter holds the last media id handed out in a resource, its row is locked while new ids are allocated
+type MediaIDCounter struct {
+	ResourceID  uint      `json:"resource_id" gorm:"primaryKey;autoIncrement:false"`
+	LastMediaID uint      `json:"last_media_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const (
+	ImageFileUnchecked = "unchecked"
+	ImageFileOK        = "ok"
+	ImageFileMissing   = "missing"
+	ImageFileChanged   = "changed"
+
+	FixityPass = "pass"
+	FixityFail = "fail"
+)
+
+// ImageFile is a disk image on storage with the size and checksums recorded when it was registered
+type ImageFile struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	EntryID       uuid.UUID  `json:"entry_id" gorm:"index"`
+	Path          string     `json:"path" gorm:"size:768;uniqueIndex"`
+	Size          int64      `json:"size"`
+	MD5           string     `json:"md5" gorm:"size:32"`
+	SHA256        string     `json:"sha256" gorm:"size:64"`
+	Status        string     `json:"status" gorm:"size:16;index"`
+	RegisteredAt  time.Time  `json:"registered_at"`
+	RegisteredBy  int        `json:"registered_by"`
+	LastCheckedAt *time.Time `json:"last_checked_at"`
+}
+
+// HasProblem reports whether the last fixity check found the file missing or changed
+func (f ImageFile) HasProblem() bool {
+	return f.Status == ImageFileMissing || f.Status == ImageFileChanged
+}
+
+// FixityEvent records the outcome of re-hashing an image file
+type FixityEvent struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ImageFileID uint      `json:"image_file_id" gorm:"index"`
+	EntryID     uuid.UUID `json:"entry_id" gorm:"index"`
+	Outcome     string    `json:"outcome" gorm:"size:8"`
+	Detail      string    `json:"detail"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+const (
+	ImageSuccessYes = "image_success_yes"
+	ImageSuccessNo  = "image_success_no"
+)
+
+// ImagingAttempt records one attempt at imaging an entry, the entry's imaging fields follow its latest successful attempt
+type ImagingAttempt struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	EntryID         uuid.UUID `json:"entry_id" gorm:"index"`
+	AttemptedAt     time.Time `json:"attempted_at"`
+	ImagedBy        string    `json:"imaged_by" form:"imaged_by"`
+	Interface       string    `json:"interface" form:"interface"`
+	ImagingSoftware string    `json:"imaging_software" form:"imaging_software"`
+	EncodingScheme  string    `json:"encoding_scheme" form:"encoding_scheme"`
+	ImagingSuccess  string    `json:"imaging_success" form:"imaging_success"`
+	Note            string    `json:"note" form:"note"`
+	CreatedAt       time.Time `json:"created_at"`
+	CreatedBy       int       `json:"created_by"`
+}
+
+func (a ImagingAttempt) Succeeded() bool { return a.ImagingSuccess == ImageSuccessYes }
+
+// FormatProfile summarizes the files in an entry's disk image from the DFXML, Siegfried and DROID reports attached to it
+type FormatProfile struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	EntryID     uuid.UUID     `json:"entry_id" gorm:"uniqueIndex"`
+	FileCount   int           `json:"file_count"`
+	TotalBytes  int64         `json:"total_bytes"`
+	Filesystems []string      `json:"filesystems" gorm:"serializer:json;type:text"`
+	Reports     []string      `json:"reports" gorm:"serializer:json;type:text"`
+	Formats     []FormatCount `json:"formats" gorm:"foreignKey:ProfileID"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	UpdatedBy   int           `json:"updated_by"`
+}
+
+// FormatCount is the number and size of the files in an entry identified as one PRONOM format
+type FormatCount struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	ProfileID uint      `json:"-" gorm:"index"`
+	EntryID   uuid.UUID `json:"-" gorm:"index"`
+	PUID      string    `json:"puid" gorm:"column:puid;size:64"`
+	Format    string    `json:"format"`
+	Version   string    `json:"version"`
+	Count     int       `json:"count"`
+	Bytes     int64     `json:"bytes"`
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+var diffIgnoredFields = map[string]bool{"ID": true, "CreatedAt": true, "CreatedBy": true, "UpdatedAt": true, "UpdatedBy": true}
+
+// Diff returns the fields that differ in the updated entry keyed by json name, associations and timestamps are ignored
+func (e Entry) Diff(updated Entry) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	original := reflect.ValueOf(e)
+	revised := reflect.ValueOf(updated)
+	for i := 0; i < original.NumField(); i++ {
+		field := original.Type().Field(i)
+		if diffIgnoredFields[field.Name] || field.Type.Kind() == reflect.Struct {
+			continue
+		}
+
+		from := original.Field(i).Interface()
+		to := revised.Field(i).Interface()
+		//a list saved empty is read back as nil
+		if field.Type.Kind() == reflect.Slice && original.Field(i).Len() == 0 && revised.Field(i).Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(from, to) {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			changes[name] = FieldChange{From: from, To: to}
+		}
+	}
+	return changes
+}
+
 type EntryMin struct {
 	Mediatype       string
 	LabelText       string
@@ -190,6 +351,9 @@ func (e *Entry) UpdateEntry(updatedEntry Entry) {
 	e.ImagingNote = updatedEntry.ImagingNote
 	e.ImagingSoftware = updatedEntry.ImagingSoftware
 	e.ImageFormat = updatedEntry.ImageFormat
+	e.Structure = updatedEntry.Structure
+	e.Filesystems = updatedEntry.Filesystems
+	e.EncodingScheme = updatedEntry.EncodingScheme
 	e.Location = updatedEntry.Location
 	e.Status = updatedEntry.Status
 }
@@ -220,44 +384,325 @@ func (e *Entry) ValidateEntry() error {
 }
 
 type User struct {
-	ID                uint      `json:"id" gorm:"primaryKey" form:"id"`
-	Email             string    `json:"email" form:"email"`
-	Salt              string    `json:"salt"`
-	EncryptedPassword string    `json:"encrypted_password"`
-	SignInCount       int       `json:"sign_in_count"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
-	CreatedBy         int       `json:"created_by"`
-	UpdatedBy         int       `json:"updated_by"`
-	IsActive          bool      `json:"is_active"`
-	IsAdmin           bool      `json:"is_admin"`
-	CurrentIPAddress  string    `json:"current_ip_address"`
-	PreviousIPAddress string    `json:"previous_ip_address"`
-	FirstName         string    `json:"first_name" form:"first_name"`
-	LastName          string    `json:"last_name" form:"last_name"`
-	CanAccessAPI      bool      `json:"can_access_api" form:"can_access_api"`
+	ID                uint       `json:"id" gorm:"primaryKey" form:"id"`
+	Email             string     `json:"email" form:"email"`
+	Salt              string     `json:"salt"`
+	EncryptedPassword string     `json:"encrypted_password"`
+	SignInCount       int        `json:"sign_in_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	CreatedBy         int        `json:"created_by"`
+	UpdatedBy         int        `json:"updated_by"`
+	IsActive          bool       `json:"is_active"`
+	IsAdmin           bool       `json:"is_admin"`
+	CurrentIPAddress  string     `json:"current_ip_address"`
+	PreviousIPAddress string     `json:"previous_ip_address"`
+	FirstName         string     `json:"first_name" form:"first_name"`
+	LastName          string     `json:"last_name" form:"last_name"`
+	CanAccessAPI      bool       `json:"can_access_api" form:"can_access_api"`
+	FailedLogins      int        `json:"failed_logins" gorm:"not null;default:0"` //wrong passwords since the last successful login or unlock
+	LockedUntil       *time.Time `json:"locked_until"`
+	TOTPSecret        string     `json:"-" gorm:"size:64;not null;default:''"` //base32 secret shared with the user's authenticator app, set before two-factor is enabled
+	TOTPEnabled       bool       `json:"totp_enabled" gorm:"not null;default:false"`
+	TOTPLastStep      int64      `json:"-" gorm:"not null;default:0"` //time step of the last code accepted, so a code only works once
+}
+
+// IsLocked reports whether the account is locked out after too many failed logins
+func (u User) IsLocked() bool { return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil) }
+
+// RecoveryCode lets a user with two-factor authentication log in once without their authenticator app, only the
+// sha256 hash of the code is stored
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-" gorm:"size:64;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginAttempt records a web or api login, failed attempts are counted to throttle password guessing
+type LoginAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Email     string    `json:"email" gorm:"size:255;index"`
+	UserID    uint      `json:"user_id" gorm:"index"` //0 when the email is not a user's
+	IPAddress string    `json:"ip_address" gorm:"size:64;index"`
+	Via       string    `json:"via" gorm:"size:16"` //web or api
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason"` //why a failed login was rejected
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
 }
 
 type Token struct {
-	Token   string    `json:"token"`
-	ID      uint      `json:"id" gorm:"primaryKey"`
-	IsValid bool      `json:"is_valid"`
-	Expires time.Time `json:"expires"`
-	UserID  uint      `json:"user_id"`
-	User    User      `json:"user"`
-	Type    string    `json:"type"`
+	Token     string    `json:"token"`
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	IsValid   bool      `json:"is_valid"`
+	Expires   time.Time `json:"expires"`
+	UserID    uint      `json:"user_id"`
+	User      User      `json:"user"`
+	Type      string    `json:"type"`
+	SessionID string    `json:"-" gorm:"size:64;index"` //the web session the token was issued to, empty for api tokens
+	CreatedAt time.Time `json:"created_at"`
+	IPAddress string    `json:"ip_address"`
+}
+
+const (
+	APIKeyPrefix         = "mlk_"
+	APIKeyScopeRead      = "read"
+	APIKeyScopeReadWrite = "read-write"
+)
+
+// APIKey is a long-lived personal access key, only the sha256 hash of the key is stored
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	Name       string     `json:"name" form:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Scope      string     `json:"scope" form:"scope"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func (k APIKey) IsRevoked() bool { return k.RevokedAt != nil }
+
+func (k APIKey) IsExpired() bool { return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt) }
+
+func (k APIKey) IsActive() bool { return !k.IsRevoked() && !k.IsExpired() }
+
+// Allows reports whether the key's scope permits a request method, read keys may only make safe requests
+func (k APIKey) Allows(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return k.Scope == APIKeyScopeRead || k.Scope == APIKeyScopeReadWrite
+	default:
+		return k.Scope == APIKeyScopeReadWrite
+	}
+}
+
+const (
+	RoleViewer     = "viewer"
+	RoleTechnician = "technician"
+	RoleCurator    = "curator"
+	RoleAdmin      = "admin"
+)
+
+// Roles lists the roles from least to most privileged, each role may do everything the roles before it may
+var Roles = []string{RoleViewer, RoleTechnician, RoleCurator, RoleAdmin}
+
+// RoleRank orders roles by privilege, an empty or unknown role ranks below viewer
+func RoleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// RoleGrant gives a user a role in one repository, or in every repository when RepositoryID is 0
+type RoleGrant struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"uniqueIndex:idx_role_grants_user_repository"`
+	RepositoryID uint      `json:"repository_id" form:"repository_id" gorm:"uniqueIndex:idx_role_grants_user_repository"`
+	Role         string    `json:"role" form:"role" gorm:"size:32"`
+	CreatedAt    time.Time `json:"created_at"`
+	CreatedBy    int       `json:"created_by"`
+}
+
+func (g RoleGrant) IsGlobal() bool { return g.RepositoryID == 0 }
+
+type VocabularyTerm struct {
+	ID         uint      `json:"id" gorm:"primaryKey" form:"id"`
+	Vocabulary string    `json:"vocabulary" form:"vocabulary" gorm:"size:64;uniqueIndex:idx_vocabulary_term"`
+	Key        string    `json:"key" form:"key" gorm:"column:term_key;size:128;uniqueIndex:idx_vocabulary_term"`
+	Label      string    `json:"label" form:"label"`
+	IsRetired  bool      `json:"is_retired"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	CreatedBy  int       `json:"created_by"`
+	UpdatedBy  int       `json:"updated_by"`
 }
 
 // config functions
 type Environment struct {
-	LogLocation    string         `yaml:"log"`
-	DatabaseConfig DatabaseConfig `yaml:"database"`
-	TestCreds      TestCreds      `yaml:"test_creds"`
-	AdminEmail     string         `yaml:"admin_email"`
-	Port           string         `yaml:"port"`
+	LogLocation     string            `yaml:"log"`
+	DatabaseConfig  DatabaseConfig    `yaml:"database"`
+	TestCreds       TestCreds         `yaml:"test_creds"`
+	AdminEmail      string            `yaml:"admin_email"`
+	Host            string            `yaml:"host"`
+	Port            string            `yaml:"port"`
+	TLSCert         string            `yaml:"tls_cert"`
+	TLSKey          string            `yaml:"tls_key"`
+	PasswordHasher  string            `yaml:"password_hasher"`       //argon2id or bcrypt, defaults to argon2id
+	TrashRetention  int               `yaml:"trash_retention_days"`  //days deleted records stay in the trash, 0 keeps them until purged
+	ImageRoots      []string          `yaml:"image_roots"`           //directories image files may be registered from over the api
+	FixityInterval  int               `yaml:"fixity_interval_hours"` //hours between scheduled fixity checks, 0 disables them
+	StagingRoots    map[string]string `yaml:"staging_roots"`         //storage location codes mapped to the staging directories images are dropped in
+	StagingInterval int               `yaml:"staging_scan_minutes"`  //minutes between staging scans, 0 only scans on demand
+	Sessions        SessionConfig     `yaml:"sessions"`
+	Login           LoginConfig       `yaml:"login"`
+	PasswordPolicy  PasswordPolicy    `yaml:"password_policy"`
+	TwoFactor       TwoFactorConfig   `yaml:"two_factor"`
+}
+
+// TwoFactorConfig sets up TOTP two-factor authentication for web logins
+type TwoFactorConfig struct {
+	Issuer   string   `yaml:"issuer"`   //the name authenticator apps show the account under, defaults to Medialog
+	Required []string `yaml:"required"` //roles that must use two-factor authentication, e.g. [admin]
+}
+
+// IssuerName returns the configured issuer
+func (t TwoFactorConfig) IssuerName() string {
+	if t.Issuer != "" {
+		return t.Issuer
+	}
+	return "Medialog"
+}
+
+// LoginConfig throttles password guessing
+type LoginConfig struct {
+	MaxFailures    int `yaml:"max_failures"`    //wrong passwords in a row before an account is locked, defaults to 5
+	LockoutMinutes int `yaml:"lockout_minutes"` //minutes an account stays locked, also the window failures from one address are counted in, defaults to 15
+	IPMaxFailures  int `yaml:"ip_max_failures"` //failed logins from one address within the window before it is refused, defaults to 20
+}
+
+// AccountMaxFailures returns the wrong passwords in a row that lock an account
+func (l LoginConfig) AccountMaxFailures() int {
+	if l.MaxFailures > 0 {
+		return l.MaxFailures
+	}
+	return 5
+}
+
+// Lockout returns how long an account stays locked
+func (l LoginConfig) Lockout() time.Duration {
+	if l.LockoutMinutes > 0 {
+		return time.Duration(l.LockoutMinutes) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// AddressMaxFailures returns the failed logins from one address that refuse further logins from it
+func (l LoginConfig) AddressMaxFailures() int {
+	if l.IPMaxFailures > 0 {
+		return l.IPMaxFailures
+	}
+	return 20
+}
+
+// PasswordPolicy sets what a new password must contain
+type PasswordPolicy struct {
+	MinLength     int  `yaml:"min_length"` //defaults to 12
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+}
+
+// MinimumLength returns the configured minimum password length in characters
+func (p PasswordPolicy) MinimumLength() int {
+	if p.MinLength > 0 {
+		return p.MinLength
+	}
+	return 12
+}
+
+func (p PasswordPolicy) requirements() []string {
+	requirements := []string{fmt.Sprintf("at least %d characters", p.MinimumLength())}
+	if p.RequireUpper {
+		requirements = append(requirements, "an uppercase letter")
+	}
+	if p.RequireLower {
+		requirements = append(requirements, "a lowercase letter")
+	}
+	if p.RequireDigit {
+		requirements = append(requirements, "a digit")
+	}
+	if p.RequireSymbol {
+		requirements = append(requirements, "a symbol")
+	}
+	return requirements
+}
+
+// String describes the policy for password forms
+func (p PasswordPolicy) String() string {
+	return "passwords need " + strings.Join(p.requirements(), ", ")
+}
+
+// Check returns an error naming what a password is missing
+func (p PasswordPolicy) Check(password string) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if utf8.RuneCountInString(password) < p.MinimumLength() ||
+		(p.RequireUpper && !upper) || (p.RequireLower && !lower) ||
+		(p.RequireDigit && !digit) || (p.RequireSymbol && !symbol) {
+		return fmt.Errorf("password does not meet the policy, %s", p)
+	}
+	return nil
+}
+
+// SessionConfig sets up the cookie web sessions are kept in
+type SessionConfig struct {
+	Secret         string `yaml:"secret"`          //signs the session cookie, without one a random secret is used and restarts end every session
+	MaxAge         int    `yaml:"max_age"`         //seconds a session lasts after it was last saved, defaults to 3 hours
+	Secure         bool   `yaml:"secure"`          //only send the cookie over https, always on when tls is configured
+	SameSite       string `yaml:"same_site"`       //lax, strict or none, defaults to lax
+	Domain         string `yaml:"domain"`          //defaults to the host the request was made to
+	CleanupMinutes int    `yaml:"cleanup_minutes"` //minutes between deletes of expired sessions, defaults to 60
+}
+
+// SessionMaxAge returns the configured session lifetime in seconds
+func (s SessionConfig) SessionMaxAge() int {
+	if s.MaxAge > 0 {
+		return s.MaxAge
+	}
+	return 3 * 60 * 60
+}
+
+// CleanupInterval returns the time between deletes of expired sessions
+func (s SessionConfig) CleanupInterval() time.Duration {
+	if s.CleanupMinutes > 0 {
+		return time.Duration(s.CleanupMinutes) * time.Minute
+	}
+	return time.Hour
+}
+
+// ListenAddress returns the host:port the server binds to, port defaults to 8080 and an empty host binds all interfaces
+func (e Environment) ListenAddress() string {
+	port := e.Port
+	if port == "" {
+		port = "8080"
+	}
+	return net.JoinHostPort(e.Host, port)
+}
+
+// UseTLS reports whether both a certificate and a key are configured
+func (e Environment) UseTLS() bool {
+	return e.TLSCert != "" && e.TLSKey != ""
 }
 
 type DatabaseConfig struct {
+	Driver       string `yaml:"driver"` //mysql or sqlite, defaults to mysql
+	Path         string `yaml:"path"`   //sqlite database file or :memory:
 	Username     string `yaml:"username"`
 	Password     string `yaml:"password"`
 	URL          string `yaml:"url"`
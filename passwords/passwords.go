@@ -0,0 +1,238 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher creates and verifies algorithm-tagged password hashes
+type Hasher interface {
+	Algorithm() string
+	Hash(password string) (string, error)
+	Verify(password string, encoded string) (bool, error)
+	NeedsRehash(encoded string) bool //reports whether a hash of this algorithm was made with other parameters
+}
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmSHA512   = "sha512" //legacy salted sha512, verify only
+)
+
+var hashers = map[string]Hasher{
+	AlgorithmArgon2id: Argon2id{Time: 3, Memory: 64 * 1024, Threads: 2, KeyLength: 32},
+	AlgorithmBcrypt:   Bcrypt{Cost: bcrypt.DefaultCost},
+	AlgorithmSHA512:   SHA512{},
+}
+
+var defaultHasher = hashers[AlgorithmArgon2id]
+
+// SetDefault selects the hasher used for new passwords, an empty algorithm keeps argon2id
+func SetDefault(algorithm string) error {
+	if algorithm == "" {
+		return nil
+	}
+	hasher, ok := hashers[algorithm]
+	if !ok || algorithm == AlgorithmSHA512 {
+		return fmt.Errorf("unsupported password hashing algorithm: %s", algorithm)
+	}
+	defaultHasher = hasher
+	return nil
+}
+
+// Default returns the hasher used for new passwords
+func Default() Hasher { return defaultHasher }
+
+// Hash hashes a password with the default hasher
+func Hash(password string) (string, error) { return defaultHasher.Hash(password) }
+
+// Verify checks a password against an algorithm-tagged hash
+func Verify(password string, encoded string) (bool, error) {
+	hasher, err := hasherFor(encoded)
+	if err != nil {
+		return false, err
+	}
+	return hasher.Verify(password, encoded)
+}
+
+// NeedsRehash reports whether a hash was not created by the default hasher with its current parameters
+func NeedsRehash(encoded string) bool {
+	hasher, err := hasherFor(encoded)
+	if err != nil || hasher.Algorithm() != defaultHasher.Algorithm() {
+		return true
+	}
+	return defaultHasher.NeedsRehash(encoded)
+}
+
+func hasherFor(encoded string) (Hasher, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return hashers[AlgorithmArgon2id], nil
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return hashers[AlgorithmBcrypt], nil
+	case strings.HasPrefix(encoded, "$sha512$"):
+		return hashers[AlgorithmSHA512], nil
+	default:
+		return nil, fmt.Errorf("unrecognized password hash format")
+	}
+}
+
+// Argon2id hashes are encoded in the PHC string format
+type Argon2id struct {
+	Time      uint32
+	Memory    uint32
+	Threads   uint8
+	KeyLength uint32
+}
+
+func (a Argon2id) Algorithm() string { return AlgorithmArgon2id }
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Time, a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a Argon2id) Verify(password string, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+func (a Argon2id) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	return err != nil || params != a
+}
+
+// decodeArgon2id returns the parameters, salt and key of a PHC encoded argon2id hash
+func decodeArgon2id(encoded string) (Argon2id, []byte, []byte, error) {
+	params := Argon2id{}
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, err
+	}
+	if params.Time < 1 || params.Threads < 1 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// Bcrypt hashes use the standard modular crypt format
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Algorithm() string { return AlgorithmBcrypt }
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(password string, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
+
+// SHA512 verifies the legacy sha512(password+salt) hashes, tagged as $sha512$<base64 salt>$<hex digest>
+type SHA512 struct{}
+
+func (s SHA512) Algorithm() string { return AlgorithmSHA512 }
+
+func (s SHA512) Hash(password string) (string, error) {
+	return "", fmt.Errorf("sha512 is only supported for verifying legacy passwords")
+}
+
+func (s SHA512) Verify(password string, encoded string) (bool, error) {
+	salt, digest, err := DecodeLegacy(encoded)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(digest), []byte(LegacyDigest(password, salt))) == 1, nil
+}
+
+// NeedsRehash is always true, legacy hashes are replaced on the next login
+func (s SHA512) NeedsRehash(encoded string) bool { return true }
+
+// LegacyDigest computes the untagged hex sha512(password+salt) digest stored by earlier versions of medialog
+func LegacyDigest(password string, salt string) string {
+	hash := sha512.Sum512([]byte(password + salt))
+	return hex.EncodeToString(hash[:])
+}
+
+// EncodeLegacy tags a legacy digest and its salt so it can be verified without the user's salt column
+func EncodeLegacy(salt string, digest string) string {
+	return fmt.Sprintf("$sha512$%s$%s", base64.RawStdEncoding.EncodeToString([]byte(salt)), digest)
+}
+
+// DecodeLegacy returns the salt and digest from a tagged legacy hash
+func DecodeLegacy(encoded string) (string, string, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[1] != AlgorithmSHA512 {
+		return "", "", fmt.Errorf("invalid sha512 hash")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", "", err
+	}
+	return string(salt), parts[3], nil
+}
+
+// IsTagged reports whether a stored hash carries an algorithm tag
+func IsTagged(encoded string) bool { return strings.HasPrefix(encoded, "$") }
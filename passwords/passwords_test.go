@@ -0,0 +1,181 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters keep the tests fast
+var (
+	testArgon2id = Argon2id{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32}
+	testBcrypt   = Bcrypt{Cost: bcrypt.MinCost}
+)
+
+func TestHashAndVerify(t *testing.T) {
+	for _, hasher := range []Hasher{testArgon2id, testBcrypt} {
+		t.Run("Test hash and verify with "+hasher.Algorithm(), func(t *testing.T) {
+			encoded, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsTagged(encoded) {
+				t.Errorf("Wanted a tagged hash, got %s", encoded)
+			}
+
+			again, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again == encoded {
+				t.Error("Wanted a new salt for every hash")
+			}
+
+			for password, want := range map[string]bool{"correct horse": true, "correct horse ": false, "": false} {
+				ok, err := Verify(password, encoded)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != want {
+					t.Errorf("Wanted `%s` verified %t, got %t", password, want, ok)
+				}
+			}
+		})
+	}
+
+	t.Run("Test the default hasher is argon2id", func(t *testing.T) {
+		encoded, err := Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(encoded, "$argon2id$") {
+			t.Errorf("Wanted an argon2id hash, got %s", encoded)
+		}
+	})
+
+	t.Run("Test malformed hashes are refused", func(t *testing.T) {
+		for _, encoded := range []string{
+			"",
+			"plaintext",
+			"$md5$salt$digest",
+			"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+			"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=1024,t=1,p=1$not base64!$a2V5",
+			"$2b$04$tooshort",
+		} {
+			if ok, err := Verify("correct horse", encoded); ok || err == nil {
+				t.Errorf("Wanted `%s` refused with an error, got %t %v", encoded, ok, err)
+			}
+		}
+	})
+}
+
+func TestLegacy(t *testing.T) {
+	t.Run("Test encode and decode a legacy hash", func(t *testing.T) {
+		for _, salt := range []string{"salt", "", "a$salt$with$dollars", "ünïcode"} {
+			digest := LegacyDigest("correct horse", salt)
+			encoded := EncodeLegacy(salt, digest)
+
+			decodedSalt, decodedDigest, err := DecodeLegacy(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decodedSalt != salt || decodedDigest != digest {
+				t.Errorf("Wanted salt `%s` and digest %s, got `%s` and %s", salt, digest, decodedSalt, decodedDigest)
+			}
+
+			if ok, err := Verify("correct horse", encoded); err != nil || !ok {
+				t.Errorf("Wanted the legacy hash with salt `%s` verified, got %t %v", salt, ok, err)
+			}
+			if ok, _ := Verify("wrong horse", encoded); ok {
+				t.Errorf("Wanted a wrong password refused for salt `%s`", salt)
+			}
+		}
+	})
+
+	t.Run("Test malformed legacy hashes are refused", func(t *testing.T) {
+		for _, encoded := range []string{
+			"$sha512$",
+			"$sha512$c2FsdA",
+			"$sha512$c2FsdA$digest$extra",
+			"$sha512$not base64!$digest",
+			"$bcrypt$c2FsdA$digest",
+			"sha512$c2FsdA$digest",
+		} {
+			if _, _, err := DecodeLegacy(encoded); err == nil {
+				t.Errorf("Wanted `%s` refused", encoded)
+			}
+		}
+	})
+
+	t.Run("Test legacy hashes cannot be created", func(t *testing.T) {
+		if _, err := (SHA512{}).Hash("correct horse"); err == nil {
+			t.Error("Wanted an error hashing with sha512")
+		}
+		if err := SetDefault(AlgorithmSHA512); err == nil {
+			t.Error("Wanted sha512 refused as the default")
+		}
+	})
+}
+
+func TestNeedsRehash(t *testing.T) {
+	defer func() { defaultHasher = hashers[AlgorithmArgon2id] }()
+
+	argon2idHash, err := testArgon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := testBcrypt.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyHash := EncodeLegacy("salt", LegacyDigest("correct horse", "salt"))
+
+	strongerArgon2id := testArgon2id
+	strongerArgon2id.Time = 2
+	widerArgon2id := testArgon2id
+	widerArgon2id.KeyLength = 64
+
+	tests := []struct {
+		name    string
+		hasher  Hasher
+		encoded string
+		want    bool
+	}{
+		{"argon2id hash with the default argon2id", testArgon2id, argon2idHash, false},
+		{"argon2id hash after more iterations are configured", strongerArgon2id, argon2idHash, true},
+		{"argon2id hash after a longer key is configured", widerArgon2id, argon2idHash, true},
+		{"argon2id hash with a bcrypt default", testBcrypt, argon2idHash, true},
+		{"bcrypt hash with the default bcrypt", testBcrypt, bcryptHash, false},
+		{"bcrypt hash after a higher cost is configured", Bcrypt{Cost: bcrypt.MinCost + 1}, bcryptHash, true},
+		{"bcrypt hash with an argon2id default", testArgon2id, bcryptHash, true},
+		{"legacy hash with an argon2id default", testArgon2id, legacyHash, true},
+		{"legacy hash with a bcrypt default", testBcrypt, legacyHash, true},
+		{"unrecognized hash", testArgon2id, "plaintext", true},
+	}
+	for _, test := range tests {
+		t.Run("Test "+test.name, func(t *testing.T) {
+			defaultHasher = test.hasher
+			if got := NeedsRehash(test.encoded); got != test.want {
+				t.Errorf("Wanted needs rehash %t, got %t", test.want, got)
+			}
+		})
+	}
+
+	t.Run("Test set the default hasher", func(t *testing.T) {
+		if err := SetDefault(AlgorithmBcrypt); err != nil {
+			t.Fatal(err)
+		}
+		if Default().Algorithm() != AlgorithmBcrypt {
+			t.Errorf("Wanted bcrypt, got %s", Default().Algorithm())
+		}
+		if err := SetDefault("md5"); err == nil {
+			t.Error("Wanted an unknown algorithm refused")
+		}
+		if err := SetDefault(""); err != nil || Default().Algorithm() != AlgorithmBcrypt {
+			t.Error("Wanted an empty algorithm to keep the default")
+		}
+	})
+}
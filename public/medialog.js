@@ -75,6 +75,25 @@ function togglePassword() {
 	}
 }
 
+// show only the entry form rows that apply to the selected mediatype, rows list their media categories in data-media
+function toggleMediaFields() {
+	var select = document.getElementById("mediatype");
+	if (!select || select.selectedIndex < 0) {
+		return;
+	}
+	var category = select.options[select.selectedIndex].getAttribute("data-category");
+	var rows = document.querySelectorAll("[data-media]");
+	for (var i = 0; i < rows.length; i++) {
+		if (!category || rows[i].getAttribute("data-media").split(" ").indexOf(category) > -1) {
+			rows[i].style.display = "";
+		} else {
+			rows[i].style.display = "none";
+		}
+	}
+}
+
+document.addEventListener("DOMContentLoaded", toggleMediaFields);
+
 function jumpToAccessionsPage() {
 	var page = document.getElementById("page").value;
 	window.location.href = "/accessions/{{ .accession.ID }}/show?page=" + page;
@@ -6,16 +6,22 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/gin-contrib/sessions"
 	gormsessions "github.com/gin-contrib/sessions/gorm"
 	"github.com/gin-gonic/gin"
+	"github.com/nyudlts/bytemath"
 	"github.com/nyudlts/go-medialog/controllers"
 	"github.com/nyudlts/go-medialog/database"
+	"github.com/nyudlts/go-medialog/fixity"
 	"github.com/nyudlts/go-medialog/models"
+	"github.com/nyudlts/go-medialog/passwords"
 	"github.com/nyudlts/go-medialog/version"
 	"gopkg.in/yaml.v2"
 )
@@ -24,6 +30,10 @@ func SetupRouter(env models.Environment, gormDebug bool, prod bool) (*gin.Engine
 
 	log.Println("Medialog starting up")
 
+	if err := checkTLS(env); err != nil {
+		return nil, err
+	}
+
 	if prod {
 		gin.SetMode(gin.ReleaseMode)
 		log.Println("[INFO] Gin logger")
@@ -49,11 +59,27 @@ func SetupRouter(env models.Environment, gormDebug bool, prod bool) (*gin.Engine
 
 	log.Println("[INFO] Connecting to database")
 	//connect the database
-	if err := database.ConnectMySQL(env.DatabaseConfig, gormDebug); err != nil {
+	if err := database.Connect(env.DatabaseConfig, gormDebug); err != nil {
+		log.Printf("[ERROR] %s", err.Error())
 		os.Exit(2)
 	}
 
-	if prod {
+	if err := passwords.SetDefault(env.PasswordHasher); err != nil {
+		return nil, err
+	}
+
+	if err := fixity.SetRoots(env.ImageRoots); err != nil {
+		return nil, err
+	}
+	controllers.SetStagingRoots(env.StagingRoots)
+	controllers.SetLoginConfig(env.Login)
+	controllers.SetPasswordPolicy(env.PasswordPolicy)
+	if err := controllers.SetTwoFactorConfig(env.TwoFactor); err != nil {
+		return nil, err
+	}
+
+	//sessions signed with a random secret do not survive a restart, so neither do their tokens
+	if prod && env.Sessions.Secret == "" {
 		log.Println("[INFO] Expiring session tokens")
 		if err := database.ExpireAllTokens(); err != nil {
 			os.Exit(3)
@@ -62,15 +88,21 @@ func SetupRouter(env models.Environment, gormDebug bool, prod bool) (*gin.Engine
 
 	//configure session parameters
 	log.Println("[INFO] Configuring sessions")
-	runes := controllers.GenerateStringRunes(24)
-	hash := sha512.Sum512([]byte(runes))
-	secret := hex.EncodeToString(hash[:])
-	store := gormsessions.NewStore(database.GetDB(), true, []byte(secret))
-	options := sessions.Options{}
-	options.HttpOnly = true
-	options.Domain = "127.0.0.1"
-	options.MaxAge = 3600
+	secret, err := sessionSecret(env)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha512.Sum512([]byte(secret))
+	options, err := sessionOptions(env)
+	if err != nil {
+		return nil, err
+	}
+	//expired sessions are deleted by the scheduled session cleanup rather than by the store
+	store := gormsessions.NewStore(database.GetDB(), false, []byte(hex.EncodeToString(hash[:])))
+	store.Options(options)
+	controllers.SetSessionOptions(options)
 	r.Use(sessions.Sessions("medialog-sessions", store))
+	r.Use(controllers.VerifyCSRF)
 
 	//load application routes
 	log.Println("[INFO] Loading routes")
@@ -83,6 +115,59 @@ func SetupRouter(env models.Environment, gormDebug bool, prod bool) (*gin.Engine
 	return r, nil
 }
 
+// checkTLS refuses a configuration with only one of the tls certificate and key, rather than quietly serving http
+func checkTLS(env models.Environment) error {
+	if env.TLSCert == "" && env.TLSKey != "" {
+		return fmt.Errorf("tls_key is set without tls_cert, set both to serve https or neither to serve http")
+	}
+	if env.TLSCert != "" && env.TLSKey == "" {
+		return fmt.Errorf("tls_cert is set without tls_key, set both to serve https or neither to serve http")
+	}
+	return nil
+}
+
+const minSessionSecretLength = 32
+
+// sessionSecret returns the configured secret that signs session cookies, or a random one when none is configured
+func sessionSecret(env models.Environment) (string, error) {
+	secret := env.Sessions.Secret
+	if secret == "" {
+		log.Println("[WARNING] no session secret is configured, sessions will end when medialog restarts")
+		return controllers.GenerateStringRunes(24), nil
+	}
+	if len(secret) < minSessionSecretLength {
+		return "", fmt.Errorf("the session secret must be at least %d characters", minSessionSecretLength)
+	}
+	return secret, nil
+}
+
+// sessionOptions builds the session cookie's options from the environment
+func sessionOptions(env models.Environment) (sessions.Options, error) {
+	options := sessions.Options{
+		Path:     "/",
+		Domain:   env.Sessions.Domain,
+		MaxAge:   env.Sessions.SessionMaxAge(),
+		Secure:   env.Sessions.Secure || env.UseTLS(),
+		HttpOnly: true,
+	}
+
+	switch strings.ToLower(env.Sessions.SameSite) {
+	case "", "lax":
+		options.SameSite = http.SameSiteLaxMode
+	case "strict":
+		options.SameSite = http.SameSiteStrictMode
+	case "none":
+		if !options.Secure {
+			return options, fmt.Errorf("same_site none needs a secure session cookie")
+		}
+		options.SameSite = http.SameSiteNoneMode
+	default:
+		return options, fmt.Errorf("`%s` is not a same_site mode, use lax, strict or none", env.Sessions.SameSite)
+	}
+
+	return options, nil
+}
+
 // Global Functions
 func Add(a int, b int) int { return a + b }
 
@@ -96,6 +181,12 @@ func MultAndAdd(page int, mult int, add int) int {
 
 func FormatAsDate(t time.Time) string { return t.Format("2006-01-02") }
 
+func FormatAsDateTime(t time.Time) string { return t.Format("2006-01-02 15:04") }
+
+func HumanBytes(n int64) string { return bytemath.ConvertBytesToHumanReadable(n) }
+
+func InList(list []string, s string) bool { return slices.Contains(list, s) }
+
 func Iterate(count int) []int {
 	var i int
 	var Items []int
@@ -109,6 +200,7 @@ func SetGlobalFuncs(router *gin.Engine) {
 	router.SetFuncMap(template.FuncMap{
 		"AppVersion":            version.GetAppVersion,
 		"formatAsDate":          FormatAsDate,
+		"formatAsDateTime":      FormatAsDateTime,
 		"add":                   Add,
 		"subtract":              Subtract,
 		"multiply":              Multiply,
@@ -121,6 +213,9 @@ func SetGlobalFuncs(router *gin.Engine) {
 		"getEntryStatus":        controllers.GetEntryStatus,
 		"getOpticalContentType": controllers.GetOpticalContentType,
 		"iterate":               Iterate,
+		"humanBytes":            HumanBytes,
+		"inList":                InList,
+		"mediatypeCategory":     controllers.MediatypeCategory,
 	})
 }
 
@@ -0,0 +1,101 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nyudlts/go-medialog/models"
+)
+
+func TestSessionOptions(t *testing.T) {
+	t.Run("Test same_site modes", func(t *testing.T) {
+		for sameSite, want := range map[string]http.SameSite{"": http.SameSiteLaxMode, "Lax": http.SameSiteLaxMode, "strict": http.SameSiteStrictMode} {
+			options, err := sessionOptions(models.Environment{Sessions: models.SessionConfig{SameSite: sameSite}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if options.SameSite != want {
+				t.Errorf("Wanted same_site `%s` to be %d, got %d", sameSite, want, options.SameSite)
+			}
+		}
+	})
+
+	t.Run("Test an unknown same_site mode is refused", func(t *testing.T) {
+		if _, err := sessionOptions(models.Environment{Sessions: models.SessionConfig{SameSite: "sometimes"}}); err == nil {
+			t.Error("Wanted an error for an unknown same_site mode")
+		}
+	})
+
+	t.Run("Test same_site none needs a secure cookie", func(t *testing.T) {
+		if _, err := sessionOptions(models.Environment{Sessions: models.SessionConfig{SameSite: "none"}}); err == nil {
+			t.Error("Wanted an error for same_site none without a secure cookie")
+		}
+
+		options, err := sessionOptions(models.Environment{Sessions: models.SessionConfig{SameSite: "none", Secure: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if options.SameSite != http.SameSiteNoneMode {
+			t.Errorf("Wanted same_site none, got %d", options.SameSite)
+		}
+	})
+
+	t.Run("Test tls makes the cookie secure", func(t *testing.T) {
+		options, err := sessionOptions(models.Environment{TLSCert: "cert.pem", TLSKey: "key.pem"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !options.Secure || !options.HttpOnly {
+			t.Errorf("Wanted a secure http only cookie, got secure %t http only %t", options.Secure, options.HttpOnly)
+		}
+	})
+}
+
+func TestSessionSecret(t *testing.T) {
+	t.Run("Test a short secret is refused", func(t *testing.T) {
+		short := strings.Repeat("s", minSessionSecretLength-1)
+		if _, err := sessionSecret(models.Environment{Sessions: models.SessionConfig{Secret: short}}); err == nil {
+			t.Errorf("Wanted an error for a %d character secret", len(short))
+		}
+	})
+
+	t.Run("Test a long enough secret is used", func(t *testing.T) {
+		long := strings.Repeat("s", minSessionSecretLength)
+		secret, err := sessionSecret(models.Environment{Sessions: models.SessionConfig{Secret: long}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret != long {
+			t.Error("Wanted the configured secret")
+		}
+	})
+
+	t.Run("Test a random secret is used when none is configured", func(t *testing.T) {
+		secret, err := sessionSecret(models.Environment{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == "" {
+			t.Error("Wanted a random secret")
+		}
+	})
+}
+
+func TestCheckTLS(t *testing.T) {
+	t.Run("Test a certificate and a key or neither are accepted", func(t *testing.T) {
+		for _, env := range []models.Environment{{}, {TLSCert: "cert.pem", TLSKey: "key.pem"}} {
+			if err := checkTLS(env); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("Test only one of a certificate and a key is refused", func(t *testing.T) {
+		for _, env := range []models.Environment{{TLSCert: "cert.pem"}, {TLSKey: "key.pem"}} {
+			if err := checkTLS(env); err == nil {
+				t.Errorf("Wanted an error for tls_cert `%s` and tls_key `%s`", env.TLSCert, env.TLSKey)
+			}
+		}
+	})
+}
@@ -20,6 +20,8 @@ func LoadRoutes(router *gin.Engine) {
 	router.GET("/test", func(c *gin.Context) { Test(c) })
 	router.GET("/users/login", func(c *gin.Context) { controllers.LoginUser(c) })
 	router.POST("/users/authenticate", func(c *gin.Context) { controllers.AuthenticateUser(c) })
+	router.GET("/users/two_factor", func(c *gin.Context) { controllers.TwoFactorLogin(c) })
+	router.POST("/users/two_factor", func(c *gin.Context) { controllers.VerifyTwoFactor(c) })
 	router.GET("/errors/test", func(c *gin.Context) { controllers.TestError(c) })
 	router.NoRoute(func(c *gin.Context) { controllers.NoRoute(c) })
 	router.NoMethod(func(c *gin.Context) { c.JSON(http.StatusMethodNotAllowed, "NO METHOD") })
@@ -39,10 +41,13 @@ func LoadRoutes(router *gin.Engine) {
 	accessionsRoutes.GET(":id/show", func(c *gin.Context) { controllers.GetAccession(c) })
 	accessionsRoutes.GET(":id/edit", func(c *gin.Context) { controllers.EditAccession(c) })
 	accessionsRoutes.POST(":id/update", func(c *gin.Context) { controllers.UpdateAccession(c) })
-	accessionsRoutes.GET(":id/delete", func(c *gin.Context) { controllers.DeleteAccession(c) })
+	accessionsRoutes.GET(":id/delete", func(c *gin.Context) { controllers.ConfirmDeleteAccession(c) })
+	accessionsRoutes.POST(":id/delete", func(c *gin.Context) { controllers.DeleteAccession(c) })
 	accessionsRoutes.GET(":id/slew", func(c *gin.Context) { controllers.SlewAccession(c) })
 	accessionsRoutes.POST("slew", func(c *gin.Context) { controllers.CreateAccessionSlew(c) })
 	accessionsRoutes.GET(":id/csv", func(c *gin.Context) { controllers.AccessionGenCSV(c) })
+	accessionsRoutes.GET(":id/import", func(c *gin.Context) { controllers.ImportAccessionEntries(c) })
+	accessionsRoutes.POST(":id/import", func(c *gin.Context) { controllers.CreateAccessionImport(c) })
 
 	//Repository Group
 	repositoryRoutes := authorized.Group("/repositories")
@@ -52,7 +57,8 @@ func LoadRoutes(router *gin.Engine) {
 	repositoryRoutes.POST("", func(c *gin.Context) { controllers.CreateRepository(c) })
 	repositoryRoutes.GET(":id/edit", func(c *gin.Context) { controllers.EditRepository(c) })
 	repositoryRoutes.POST(":id/update", func(c *gin.Context) { controllers.UpdateRepository(c) })
-	repositoryRoutes.GET(":id/delete", func(c *gin.Context) { controllers.DeleteRepository(c) })
+	repositoryRoutes.GET(":id/delete", func(c *gin.Context) { controllers.ConfirmDeleteRepository(c) })
+	repositoryRoutes.POST(":id/delete", func(c *gin.Context) { controllers.DeleteRepository(c) })
 
 	//Resources Group
 	resourceRoutes := authorized.Group("/resources")
@@ -62,7 +68,8 @@ func LoadRoutes(router *gin.Engine) {
 	resourceRoutes.POST("", func(c *gin.Context) { controllers.CreateResource(c) })
 	resourceRoutes.GET(":id/edit", func(c *gin.Context) { controllers.EditResource(c) })
 	resourceRoutes.POST(":id/update", func(c *gin.Context) { controllers.UpdateResource(c) })
-	resourceRoutes.GET(":id/delete", func(c *gin.Context) { controllers.DeleteResource(c) })
+	resourceRoutes.GET(":id/delete", func(c *gin.Context) { controllers.ConfirmDeleteResource(c) })
+	resourceRoutes.POST(":id/delete", func(c *gin.Context) { controllers.DeleteResource(c) })
 	resourceRoutes.GET(":id/csv", func(c *gin.Context) { controllers.ResourceGenCSV(c) })
 
 	//Entries Group
@@ -72,12 +79,21 @@ func LoadRoutes(router *gin.Engine) {
 	entryRoutes.POST("", func(c *gin.Context) { controllers.CreateEntry(c) })
 	entryRoutes.GET(":id/edit", func(c *gin.Context) { controllers.EditEntry(c) })
 	entryRoutes.POST(":id/update", func(c *gin.Context) { controllers.UpdateEntry(c) })
-	entryRoutes.GET(":id/delete", func(c *gin.Context) { controllers.DeleteEntry(c) })
+	entryRoutes.GET(":id/delete", func(c *gin.Context) { controllers.ConfirmDeleteEntry(c) })
+	entryRoutes.POST(":id/delete", func(c *gin.Context) { controllers.DeleteEntry(c) })
 	entryRoutes.GET(":id/show", func(c *gin.Context) { controllers.GetEntry(c) })
 	entryRoutes.GET(":id/previous", func(c *gin.Context) { controllers.GetPreviousEntry(c) })
 	entryRoutes.GET(":id/next", func(c *gin.Context) { controllers.GetNextEntry(c) })
-	entryRoutes.GET(":id/clone", func(c *gin.Context) { controllers.CloneEntry(c) })
+	entryRoutes.POST(":id/clone", func(c *gin.Context) { controllers.CloneEntry(c) })
 	entryRoutes.POST("find", func(c *gin.Context) { controllers.FindEntry(c) })
+	entryRoutes.GET("move", func(c *gin.Context) { controllers.MoveEntriesForm(c) })
+	entryRoutes.POST("move", func(c *gin.Context) { controllers.MoveEntries(c) })
+	entryRoutes.POST(":id/image_files", func(c *gin.Context) { controllers.RegisterEntryImageFiles(c) })
+	entryRoutes.POST(":id/fixity", func(c *gin.Context) { controllers.CheckEntryFixity(c) })
+	entryRoutes.POST(":id/imaging_log", func(c *gin.Context) { controllers.UploadImagingLog(c) })
+	entryRoutes.POST(":id/format_report", func(c *gin.Context) { controllers.UploadFormatReport(c) })
+	entryRoutes.POST(":id/imaging_attempts", func(c *gin.Context) { controllers.CreateImagingAttempt(c) })
+	entryRoutes.POST(":id/imaging_attempts/:attempt_id/delete", func(c *gin.Context) { controllers.DeleteImagingAttempt(c) })
 	entryRoutes.GET("/csv", func(c *gin.Context) { controllers.EntriesGenCSV(c) })
 
 	//Users Group (protected routes only — login and authenticate are unprotected above)
@@ -88,21 +104,49 @@ func LoadRoutes(router *gin.Engine) {
 	userRoutes.GET("logout", func(c *gin.Context) { controllers.LogoutUser(c) })
 	userRoutes.GET(":id/reset_password", func(c *gin.Context) { controllers.ResetUserPassword(c) })
 	userRoutes.POST(":id/reset_password", func(c *gin.Context) { controllers.ResetPassword(c) })
-	userRoutes.GET(":id/deactivate", func(c *gin.Context) { controllers.DeactivateUser(c) })
-	userRoutes.GET(":id/reactivate", func(c *gin.Context) { controllers.ReactivateUser(c) })
-	userRoutes.GET(":id/make_admin", func(c *gin.Context) { controllers.MakeUserAdmin(c) })
-	userRoutes.GET(":id/remove_admin", func(c *gin.Context) { controllers.RemoveUserAdmin(c) })
+	userRoutes.POST(":id/deactivate", func(c *gin.Context) { controllers.DeactivateUser(c) })
+	userRoutes.POST(":id/reactivate", func(c *gin.Context) { controllers.ReactivateUser(c) })
+	userRoutes.POST(":id/make_admin", func(c *gin.Context) { controllers.MakeUserAdmin(c) })
+	userRoutes.POST(":id/remove_admin", func(c *gin.Context) { controllers.RemoveUserAdmin(c) })
 	userRoutes.GET(":id/show", func(c *gin.Context) { controllers.GetUser(c) })
 	userRoutes.GET(":id/edit", func(c *gin.Context) { controllers.EditUser(c) })
 	userRoutes.POST("update", func(c *gin.Context) { controllers.UpdateUser(c) })
-	userRoutes.GET(":id/allow_api", func(c *gin.Context) { controllers.AllowAPI(c) })
-	userRoutes.GET(":id/revoke_api", func(c *gin.Context) { controllers.RevokeAPI(c) })
+	userRoutes.POST(":id/allow_api", func(c *gin.Context) { controllers.AllowAPI(c) })
+	userRoutes.POST(":id/revoke_api", func(c *gin.Context) { controllers.RevokeAPI(c) })
+	userRoutes.POST(":id/api_keys", func(c *gin.Context) { controllers.CreateAPIKey(c) })
+	userRoutes.POST(":id/api_keys/:key_id/revoke", func(c *gin.Context) { controllers.RevokeAPIKey(c) })
+	userRoutes.POST(":id/roles", func(c *gin.Context) { controllers.CreateRoleGrant(c) })
+	userRoutes.POST(":id/roles/:grant_id/delete", func(c *gin.Context) { controllers.DeleteRoleGrant(c) })
+	userRoutes.POST(":id/unlock", func(c *gin.Context) { controllers.UnlockUser(c) })
+	userRoutes.POST(":id/two_factor/setup", func(c *gin.Context) { controllers.SetupTwoFactor(c) })
+	userRoutes.POST(":id/two_factor/enable", func(c *gin.Context) { controllers.EnableTwoFactor(c) })
+	userRoutes.POST(":id/two_factor/recovery_codes", func(c *gin.Context) { controllers.RegenerateRecoveryCodes(c) })
+	userRoutes.POST(":id/two_factor/reset", func(c *gin.Context) { controllers.ResetTwoFactor(c) })
+
+	//Vocabularies Group
+	vocabularyRoutes := authorized.Group("/vocabularies")
+	vocabularyRoutes.GET("", func(c *gin.Context) { controllers.GetVocabularies(c) })
+	vocabularyRoutes.GET("new", func(c *gin.Context) { controllers.NewVocabularyTerm(c) })
+	vocabularyRoutes.POST("", func(c *gin.Context) { controllers.CreateVocabularyTerm(c) })
+	vocabularyRoutes.GET(":id/edit", func(c *gin.Context) { controllers.EditVocabularyTerm(c) })
+	vocabularyRoutes.POST(":id/update", func(c *gin.Context) { controllers.UpdateVocabularyTerm(c) })
+	vocabularyRoutes.POST(":id/retire", func(c *gin.Context) { controllers.RetireVocabularyTerm(c) })
+	vocabularyRoutes.POST(":id/restore", func(c *gin.Context) { controllers.RestoreVocabularyTerm(c) })
+
+	//Trash Group
+	trashRoutes := authorized.Group("/trash")
+	trashRoutes.GET("", func(c *gin.Context) { controllers.GetTrash(c) })
+	trashRoutes.POST(":type/:id/restore", func(c *gin.Context) { controllers.RestoreTrashItem(c) })
+	trashRoutes.POST(":type/:id/purge", func(c *gin.Context) { controllers.PurgeTrashItem(c) })
 
 	//Report Group
 	reportsRoutes := authorized.Group("/reports")
 	reportsRoutes.GET("", func(c *gin.Context) { controllers.ReportsIndex(c) })
 	reportsRoutes.POST("/range", func(c *gin.Context) { controllers.ReportsRange(c) })
 	reportsRoutes.POST("/csv", func(c *gin.Context) { controllers.ReportsCSV(c) })
+	reportsRoutes.GET("/fixity", func(c *gin.Context) { controllers.ReportsFixity(c) })
+	reportsRoutes.GET("/staging", func(c *gin.Context) { controllers.ReportsStaging(c) })
+	reportsRoutes.POST("/staging/scan", func(c *gin.Context) { controllers.ScanStagingNow(c) })
 
 	//Search Group
 	searchRoutes := authorized.Group("/search")
@@ -110,6 +154,8 @@ func LoadRoutes(router *gin.Engine) {
 
 	//Session Group
 	sessionRoutes := authorized.Group("/sessions")
+	sessionRoutes.GET("", func(c *gin.Context) { controllers.GetSessions(c) })
+	sessionRoutes.POST(":id/logout", func(c *gin.Context) { controllers.ForceLogout(c) })
 	sessionRoutes.GET("/dump", func(c *gin.Context) { controllers.DumpSession(c) })
 
 }
@@ -148,20 +194,47 @@ func LoadAPI(router *gin.Engine) {
 	apiV0Routes.GET("accessions/:id", func(c *gin.Context) { api.GetAccessionV0(c) })
 	apiV0Routes.GET("accessions/:id/entries", func(c *gin.Context) { api.GetAccessionEntriesV0(c) })
 	apiV0Routes.GET("accessions/:id/summary", func(c *gin.Context) { api.GetAccessionSummaryV0(c) })
+	apiV0Routes.POST("accessions/:id/import", func(c *gin.Context) { api.ImportAccessionEntriesV0(c) })
 
 	//entries
 	apiV0Routes.POST("entries", func(c *gin.Context) { api.CreateEntryV0(c) })
 	apiV0Routes.DELETE("entries/:id", func(c *gin.Context) { api.DeleteEntryV0(c) })
+	apiV0Routes.POST("entries/:id/move", func(c *gin.Context) { api.MoveEntryV0(c) })
+	apiV0Routes.POST("entries/move", func(c *gin.Context) { api.MoveEntriesV0(c) })
 	apiV0Routes.GET("entries", func(c *gin.Context) { api.GetEntriesV0(c) })
 	apiV0Routes.GET("entries/:id", func(c *gin.Context) { api.GetEntryV0(c) })
+	apiV0Routes.GET("entries/:id/history", func(c *gin.Context) { api.GetEntryHistoryV0(c) })
 	apiV0Routes.PATCH("entries/:id/update_location", func(c *gin.Context) { api.UpdateEntryLocationV0(c) })
 	apiV0Routes.POST("entries/:id/update", func(c *gin.Context) { api.UpdateEntryV0(c) })
+	apiV0Routes.GET("entries/:id/image_files", func(c *gin.Context) { api.GetEntryImageFilesV0(c) })
+	apiV0Routes.POST("entries/:id/image_files", func(c *gin.Context) { api.RegisterEntryImageFilesV0(c) })
+	apiV0Routes.POST("entries/:id/imaging_log", func(c *gin.Context) { api.ReadImagingLogV0(c) })
+	apiV0Routes.GET("entries/:id/format_profile", func(c *gin.Context) { api.GetEntryFormatProfileV0(c) })
+	apiV0Routes.POST("entries/:id/format_profile", func(c *gin.Context) { api.AttachFormatReportV0(c) })
+	apiV0Routes.GET("entries/:id/imaging_attempts", func(c *gin.Context) { api.GetEntryImagingAttemptsV0(c) })
+	apiV0Routes.POST("entries/:id/imaging_attempts", func(c *gin.Context) { api.CreateImagingAttemptV0(c) })
+	apiV0Routes.DELETE("entries/:id/imaging_attempts/:attempt_id", func(c *gin.Context) { api.DeleteImagingAttemptV0(c) })
+
+	//search
+	apiV0Routes.GET("search/entries", func(c *gin.Context) { api.SearchEntriesV0(c) })
+
+	//vocabularies
+	apiV0Routes.GET("vocabularies", func(c *gin.Context) { api.GetVocabulariesV0(c) })
+	apiV0Routes.GET("vocabularies/:vocabulary", func(c *gin.Context) { api.GetVocabularyV0(c) })
+
+	//trash
+	apiV0Routes.GET("trash", func(c *gin.Context) { api.GetTrashV0(c) })
+	apiV0Routes.POST("trash/:type/:id/restore", func(c *gin.Context) { api.RestoreTrashItemV0(c) })
+	apiV0Routes.DELETE("trash/:type/:id", func(c *gin.Context) { api.PurgeTrashItemV0(c) })
 
 	//sessions
 	apiV0Routes.DELETE("delete_sessions", func(c *gin.Context) { api.DeleteSessionsV0(c) })
 
 	//reports
 	apiV0Routes.GET("reports/range", func(c *gin.Context) { api.SummaryDateRange(c) })
+	apiV0Routes.GET("reports/fixity", func(c *gin.Context) { api.FixityReportV0(c) })
+	apiV0Routes.GET("reports/staging", func(c *gin.Context) { api.GetStagingReportV0(c) })
+	apiV0Routes.POST("staging/scan", func(c *gin.Context) { api.ScanStagingV0(c) })
 }
 
 func Test(c *gin.Context) {